@@ -1,6 +1,9 @@
 package main
 
 import (
+	"encoding/binary"
+	"fmt"
+	"math"
 	"os"
 
 	"github.com/hajimehoshi/ebiten/v2/audio"
@@ -38,6 +41,46 @@ func (am *AudioManager) Play(key string) {
 	}
 }
 
+// Length of a voice blip in seconds
+const blipDuration = 0.04
+
+// Makes a fading square wave of the pitch as 16-bit stereo samples
+func synthesizeBlip(pitch float64) []byte {
+	const sampleRate = 44100
+
+	samples := int(sampleRate * blipDuration)
+	data := make([]byte, samples*4)
+
+	for i := 0; i < samples; i++ {
+		value := 0.2 * (1 - float64(i)/float64(samples))
+		if math.Mod(float64(i)*pitch/sampleRate, 1) >= 0.5 {
+			value = -value
+		}
+
+		sample := uint16(int16(value * math.MaxInt16))
+		binary.LittleEndian.PutUint16(data[i*4:], sample)
+		binary.LittleEndian.PutUint16(data[i*4+2:], sample)
+	}
+
+	return data
+}
+
+// Plays a short synthesized blip, the voice of a speaking character.
+// Blips of each pitch are made once and kept with the other sounds.
+func (am *AudioManager) PlayBlip(pitch float64) {
+	if am.game.volumeMusic <= 0 {
+		return
+	}
+
+	key := fmt.Sprintf("blip/%g", pitch)
+
+	if _, has := am.assets[key]; !has {
+		am.assets[key] = audio.NewPlayerFromBytes(GetAudioContext(), synthesizeBlip(pitch))
+	}
+
+	am.Play(key)
+}
+
 func (am *AudioManager) PlayBackgroundMusic(key string) {
 	/*
 		if am.currentBGM != nil {
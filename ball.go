@@ -0,0 +1,312 @@
+package main
+
+import (
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Collision radius of a ball in pixels
+const ballRadius = 6.0
+
+// Distance at which a walking player kicks a ball
+const ballKickDistance = tileSize * 0.75
+
+// Balls slower than this stop, in pixels per tick
+const ballRestSpeed = 0.05
+
+// Physics of a ball class. Friction is the velocity kept per tick, bounce
+// the part kept when hitting a wall or an entity.
+type BallKind struct {
+	tile      Tile
+	friction  float64
+	bounce    float64
+	kickSpeed float64
+}
+
+// Honey balls are slow and stick to whatever they hit
+var ballKinds = map[string]BallKind{
+	"ball":       {tileIDBall, 0.97, 0.8, 4.0},
+	"honey_ball": {tileIDHoneyBall, 0.85, 0.0, 2.0},
+}
+
+func init() {
+	for class := range ballKinds {
+		class := class
+		RegisterEntityClass(class, func(g *Game) ILivingEntity { return CreateBall(g, class) })
+	}
+}
+
+type BallEventKind int
+
+const (
+	ballKicked BallEventKind = iota
+	ballBounced
+	ballScored
+	ballLeftTarget
+	ballFell
+)
+
+type BallEvent struct {
+	kind    BallEventKind
+	ball    *Ball
+	tilePos int
+}
+
+type BallListener func(g *Game, event BallEvent)
+
+// Adds a function called on every ball event
+func (g *Game) AddBallListener(listener BallListener) {
+	g.ballListeners = append(g.ballListeners, listener)
+}
+
+func (g *Game) emitBallEvent(kind BallEventKind, ball *Ball, tilePos int) {
+	event := BallEvent{kind, ball, tilePos}
+
+	for _, listener := range g.ballListeners {
+		listener(g, event)
+	}
+}
+
+// Counts balls landing on targets
+func ballScoreListener(g *Game, event BallEvent) {
+	switch event.kind {
+	case ballScored:
+		g.ballScore++
+		log.Printf("[Ball] Scored on tile %d, score %d", event.tilePos, g.ballScore)
+
+	case ballLeftTarget:
+		g.ballScore--
+	}
+}
+
+// Rolling ball kicked by the player
+type Ball struct {
+	LivingEntity
+	class    string
+	kind     BallKind
+	velocity Vec2f
+	layer    int
+	scored   bool
+}
+
+func CreateBall(g *Game, class string) *Ball {
+	e := new(Ball)
+	e.etype = e
+	e._ConstructLivingEntity(g)
+	e.class = class
+	e.classKey = class
+	e.kind = ballKinds[class]
+	e.entityClass = I18n("tile_id_"+class, class)
+	e.layer = -1
+	return e
+}
+
+func (e *Ball) GetVelocity() Vec2f {
+	return e.velocity
+}
+
+func (e *Ball) IsResting() bool {
+	return e.velocity.X == 0 && e.velocity.Y == 0
+}
+
+func (e *Ball) IsScored() bool {
+	return e.scored
+}
+
+func (e *Ball) Kick(velocity Vec2f) {
+	e.velocity = velocity
+
+	tilePos, _ := e.GetTilePos()
+	e.game.emitBallEvent(ballKicked, e, tilePos)
+}
+
+// Kicks the ball away from a player walking into it
+func (e *Ball) processKick() {
+	g := e.game
+	if g.char == nil {
+		return
+	}
+
+	player := g.char.GetLivingEntity()
+	if !player.walking {
+		return
+	}
+
+	offset := e.worldPos.Subtract(player.worldPos)
+	if offset.Distance() > ballKickDistance {
+		return
+	}
+
+	look := lookDirectionVector(player.look)
+	ahead := offset.X*look.X + offset.Y*look.Y
+	speed := e.velocity.X*look.X + e.velocity.Y*look.Y
+
+	if ahead > 0 && speed < e.kind.kickSpeed/2 {
+		e.Kick(look.Scale(e.kind.kickSpeed))
+		player.PlayClip(clipAttack + "_" + lookDirectionClipNames[player.look])
+	}
+}
+
+// Reflects the velocity off entities the ball runs into
+func (e *Ball) processEntityBounce() {
+	for _, entity := range e.game.entities {
+		other := entity.GetLivingEntity()
+		if other == &e.LivingEntity || entity == e.game.char {
+			continue
+		}
+
+		offset := e.worldPos.Subtract(other.worldPos)
+		dist := offset.Distance()
+		if dist == 0 || dist > ballRadius*2 {
+			continue
+		}
+
+		normal := offset.Scale(1 / dist)
+		approach := e.velocity.X*normal.X + e.velocity.Y*normal.Y
+		if approach >= 0 {
+			continue
+		}
+
+		e.velocity = e.velocity.Subtract(normal.Scale(2 * approach)).Scale(e.kind.bounce)
+
+		tilePos, _ := e.GetTilePos()
+		e.game.emitBallEvent(ballBounced, e, tilePos)
+	}
+}
+
+// Moves the ball along one axis, bouncing off solid tiles
+func (e *Ball) moveAxis(delta Vec2f) bool {
+	if delta.X == 0 && delta.Y == 0 {
+		return false
+	}
+
+	edge := e.worldPos.Add(delta).Add(delta.Normalize().Scale(ballRadius))
+	if e.game.IsTileSolidAt(edge.X, edge.Y) {
+		return true
+	}
+
+	e.worldPos = e.worldPos.Add(delta)
+	return false
+}
+
+func (e *Ball) Update() {
+	g := e.game
+
+	e.processKick()
+	e.processEntityBounce()
+
+	bounced := false
+	if e.moveAxis(Vec2f{e.velocity.X, 0}) {
+		e.velocity.X *= -e.kind.bounce
+		bounced = true
+	}
+	if e.moveAxis(Vec2f{0, e.velocity.Y}) {
+		e.velocity.Y *= -e.kind.bounce
+		bounced = true
+	}
+
+	tilePos, err := e.GetTilePos()
+	if err != nil {
+		return
+	}
+
+	if bounced {
+		g.emitBallEvent(ballBounced, e, tilePos)
+	}
+
+	e.velocity = e.velocity.Scale(e.kind.friction)
+	if e.velocity.Distance() < ballRestSpeed {
+		e.velocity = Vec2f{}
+	}
+
+	if g.hasTileAt(tilePos, tileIDPit) >= 0 {
+		e.health = 0
+		g.emitBallEvent(ballFell, e, tilePos)
+		return
+	}
+
+	if e.scored && tilePos != e.prevTilePos {
+		e.scored = false
+		g.emitBallEvent(ballLeftTarget, e, e.prevTilePos)
+	}
+	e.prevTilePos = tilePos
+
+	if !e.scored && e.IsResting() && g.hasTileAt(tilePos, tileIDTarget) >= 0 {
+		e.scored = true
+		g.emitBallEvent(ballScored, e, tilePos)
+	}
+}
+
+func (e *Ball) Draw(screen *ebiten.Image) {
+	cameraZoom := e.game.camera.GetZoom()
+	pos := e.game.camera.WorldToScreen2(e.worldPos)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(-tileSize/2, -tileSize/2)
+	op.GeoM.Scale(cameraZoom, cameraZoom)
+	op.GeoM.Translate(pos.X, pos.Y)
+
+	DrawImage(screen, GetTileSprite(tilesImage, tileXNum, tileSize, e.kind.tile), op)
+}
+
+// Returns whether a ball rests on the tile after landing on a target
+func (g *Game) isScoredBallAt(tilePos int) bool {
+	for _, entity := range g.entities {
+		if ball, ok := entity.(*Ball); ok && ball.scored && ball.prevTilePos == tilePos {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Replaces ball tiles of a chunk with ball entities
+func (g *Game) createTileBalls(level *Level, chunk *LevelChunk) []ILivingEntity {
+	var balls []ILivingEntity
+
+	for y := chunk.y * levelChunkSize; y < (chunk.y+1)*levelChunkSize && y < level.height; y++ {
+		for x := chunk.x * levelChunkSize; x < (chunk.x+1)*levelChunkSize && x < level.width; x++ {
+			tilePos := y*level.width + x
+
+			for layer := 0; layer < level.GetLayerCount(); layer++ {
+				for class, kind := range ballKinds {
+					if level.GetTile(layer, tilePos) != kind.tile {
+						continue
+					}
+
+					ball := CreateBall(g, class)
+					ball.worldPos = Vec2f{float64(x*tileSize + tileSize/2), float64(y*tileSize + tileSize/2)}
+					ball.prevTilePos = tilePos
+					ball.layer = layer
+					balls = append(balls, ball)
+
+					level.SetTile(layer, tilePos, tileIDEmpty)
+				}
+			}
+		}
+	}
+
+	return balls
+}
+
+// Writes balls back as tiles into a dense level buffer, so legacy level
+// files keep them
+func (g *Game) stampBallTiles(buffer []byte, size int) {
+	for _, entity := range g.entities {
+		ball, ok := entity.(*Ball)
+		if !ok {
+			continue
+		}
+
+		tilePos, err := ball.GetTilePos()
+		layer := ball.layer
+		if layer < 0 {
+			layer = g.level.GetLayerCount() - 1
+		}
+
+		if err == nil && buffer[layer*size+tilePos] == byte(tileIDEmpty) {
+			buffer[layer*size+tilePos] = byte(ball.kind.tile)
+		}
+	}
+}
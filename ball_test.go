@@ -0,0 +1,136 @@
+package main
+
+import "testing"
+
+// Turns the ball tiles of the level into balls and records their events
+func newBallTest(t *testing.T, level *Level) (*HeadlessSimulation, *ScriptedInputSource, *[]BallEventKind) {
+	t.Helper()
+
+	sim, input := newTestSimulation(t, level)
+	g := sim.GetGame()
+
+	var events []BallEventKind
+	g.AddBallListener(func(g *Game, event BallEvent) {
+		events = append(events, event.kind)
+	})
+
+	g.entities = append(g.entities, g.CreateLevelEntities(level)...)
+	return sim, input, &events
+}
+
+func findBall(g *Game) *Ball {
+	for _, entity := range g.entities {
+		if ball, ok := entity.(*Ball); ok {
+			return ball
+		}
+	}
+
+	return nil
+}
+
+func hasBallEvent(events []BallEventKind, kind BallEventKind) bool {
+	for _, event := range events {
+		if event == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestPlayerKicksBallOffWalls(t *testing.T) {
+	level := newTestLevel()
+	level.SetTile(testObjectLayer, tileIndex(level, 4, 3), tileIDBall)
+	level.SetTile(testObjectLayer, tileIndex(level, 7, 3), tileIDBricks)
+
+	sim, input, events := newBallTest(t, level)
+	g := sim.GetGame()
+
+	if level.GetTile(testObjectLayer, tileIndex(level, 4, 3)) != tileIDEmpty {
+		t.Fatal("ball tile was not turned into an entity")
+	}
+
+	sim.Spawn(g.char, 2, 3)
+	input.Hold(0, 24, kbPlayerMoveRight)
+	sim.Step(24)
+
+	ball := findBall(g)
+	if !hasBallEvent(*events, ballKicked) || ball.GetVelocity().X <= 0 {
+		t.Fatalf("ball was not kicked, velocity %v", ball.GetVelocity())
+	}
+
+	if clip := g.char.GetLivingEntity().GetClipName(); clip != "attack_right" {
+		t.Fatalf("player plays %s after kicking the ball", clip)
+	}
+
+	sim.Step(300)
+
+	if !hasBallEvent(*events, ballBounced) {
+		t.Fatal("ball did not bounce off the wall")
+	}
+
+	if !ball.IsResting() || ball.GetWorldPos().X+ballRadius > 7*tileSize {
+		t.Fatalf("ball at %v with velocity %v, want resting before the wall", ball.GetWorldPos(), ball.GetVelocity())
+	}
+}
+
+func TestHoneyBallSticksToWall(t *testing.T) {
+	level := newTestLevel()
+	level.SetTile(testObjectLayer, tileIndex(level, 4, 3), tileIDHoneyBall)
+	level.SetTile(testObjectLayer, tileIndex(level, 5, 3), tileIDBricks)
+
+	sim, _, _ := newBallTest(t, level)
+	ball := findBall(sim.GetGame())
+	ball.Kick(Vec2f{2, 0})
+	sim.Step(1)
+
+	if !ball.IsResting() {
+		t.Fatalf("honey ball velocity = %v after hitting the wall, want stuck", ball.GetVelocity())
+	}
+}
+
+func TestBallScoresOnWiredTarget(t *testing.T) {
+	level := newTestLevel()
+	level.SetTile(testObjectLayer, tileIndex(level, 4, 3), tileIDBall)
+	level.SetTile(testFloorLayer, tileIndex(level, 4, 3), tileIDTarget)
+	level.SetTile(testObjectLayer, tileIndex(level, 4, 6), tileIDPoppingBarrierActive)
+
+	level.SetSignals(&JSONLevelSignals{
+		Emitters:  []JSONSignalWire{{X: 4, Y: 3, Channel: 1}},
+		Receivers: []JSONSignalWire{{X: 4, Y: 6, Channel: 1}},
+	})
+
+	sim, _, events := newBallTest(t, level)
+	g := sim.GetGame()
+	sim.Step(2)
+
+	if !hasBallEvent(*events, ballScored) || g.ballScore != 1 {
+		t.Fatalf("score = %d after the ball rested on the target", g.ballScore)
+	}
+
+	if got := level.GetTile(testObjectLayer, tileIndex(level, 4, 6)); got != tileIDPoppingBarrierPushed {
+		t.Fatalf("barrier tile = %d, want opened by the target", got)
+	}
+
+	findBall(g).Kick(Vec2f{0, 4})
+	sim.Step(10)
+
+	if !hasBallEvent(*events, ballLeftTarget) || g.ballScore != 0 {
+		t.Fatalf("score = %d after the ball left the target", g.ballScore)
+	}
+}
+
+func TestBallFallsIntoPit(t *testing.T) {
+	level := newTestLevel()
+	level.SetTile(testObjectLayer, tileIndex(level, 4, 3), tileIDBall)
+	level.SetTile(testFloorLayer, tileIndex(level, 6, 3), tileIDPit)
+
+	sim, _, events := newBallTest(t, level)
+	g := sim.GetGame()
+	findBall(g).Kick(Vec2f{3, 0})
+	sim.Step(20)
+
+	if !hasBallEvent(*events, ballFell) || findBall(g) != nil {
+		t.Fatal("ball did not fall into the pit")
+	}
+}
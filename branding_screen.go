@@ -45,7 +45,7 @@ func (s *BrandingScreen) Draw(screen *ebiten.Image) {
 	op.GeoM.Translate(screenWidth/2, screenHeight/2)
 	op.ColorM.Scale(1.0, 1.0, 1.0, fadeFactor)
 
-	screen.DrawImage(s.aragajagaImage, op)
+	DrawImage(screen, s.aragajagaImage, op)
 }
 
 func (s *BrandingScreen) ProcessKeyEvents() bool {
@@ -128,6 +128,13 @@ func (c *Camera) WorldToScreen2(pos Vec2f) Vec2f {
 	return screenPos
 }
 
+// Inverse of WorldToScreen2, e.g. to find what the mouse cursor points at
+func (c *Camera) ScreenToWorld(pos Vec2f) Vec2f {
+	screenCenter := Vec2f{screenWidth / 2, screenHeight / 2}
+
+	return c.currentWorldPos.Subtract(screenCenter.Subtract(pos).Scale(1 / c.zoom))
+}
+
 func (c *Camera) SetZoom(zoom float64) {
 	c.zoom = math.Max(1.0, zoom)
 }
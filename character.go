@@ -2,10 +2,19 @@ package main
 
 type ICharacter interface {
 	ILivingEntity
+	AddItem(id string, count int) int
+	TakeItem(id string, count int) bool
+	GetItemCount(id string) int
+	GetInventory() *Inventory
+	GetMoney() int
+	AddMoney(amount int)
+	SpendMoney(amount int) bool
 }
 
 type Character struct {
 	LivingEntity
+	inventory *Inventory
+	money     int
 }
 
 const (
@@ -27,6 +36,7 @@ func (e *Character) Update() {
 		e.game.ProcessTileLeaving(e, e.prevTilePos)
 
 		e.prevTilePos = curTilePos
+		e.game.ProcessTileEntering(e, curTilePos)
 	}
 
 	underlyingTiles, _ := e.game.GetUnderlyingTilesAt(e.worldPos.X, e.worldPos.Y)
@@ -40,6 +50,44 @@ func CreateCharacter(g *Game) *Character {
 	e.etype = e
 	e._ConstructLivingEntity(g)
 	e.entityClass = langData["entity_player"]
-	e.sprite = charSprite
+	e.classKey = "player"
+	e.SetSprite(charSprite, "char2")
+	e.inventory = NewInventory(inventoryColumns * inventoryRows)
 	return e
 }
+
+// Returns how many items did not fit into the inventory
+func (e *Character) AddItem(id string, count int) int {
+	return e.inventory.Add(id, count)
+}
+
+// Removes items if the character carries enough of them
+func (e *Character) TakeItem(id string, count int) bool {
+	return e.inventory.Take(id, count)
+}
+
+func (e *Character) GetItemCount(id string) int {
+	return e.inventory.Count(id)
+}
+
+func (e *Character) GetInventory() *Inventory {
+	return e.inventory
+}
+
+func (e *Character) GetMoney() int {
+	return e.money
+}
+
+func (e *Character) AddMoney(amount int) {
+	e.money += amount
+}
+
+// Takes money if the character has enough of it
+func (e *Character) SpendMoney(amount int) bool {
+	if e.money < amount {
+		return false
+	}
+
+	e.money -= amount
+	return true
+}
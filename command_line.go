@@ -0,0 +1,346 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var g_currentProcessCmdLine *CommandLine
+
+type SwitchMap map[string]string
+
+type CommandLine struct {
+	switches   SwitchMap
+	argv       []string
+	begin_args uint
+}
+
+// Parses the current process arguments once. Returns false if already done.
+func (cmdLine *CommandLine) Init() bool {
+	if g_currentProcessCmdLine != nil {
+		return false
+	}
+
+	cmdLine.FromOsArgs(os.Args)
+	g_currentProcessCmdLine = cmdLine
+	return true
+}
+
+func CommandLine_ForCurrentProcess() *CommandLine {
+	if g_currentProcessCmdLine == nil {
+		new(CommandLine).Init()
+	}
+
+	return g_currentProcessCmdLine
+}
+
+func NewCommandLine(argv []string) *CommandLine {
+	cmdLine := new(CommandLine)
+	cmdLine.FromOsArgs(argv)
+	return cmdLine
+}
+
+type AnyMap map[interface{}]interface{}
+
+func (cmdLine *CommandLine) FromOsArgs(argv []string) {
+	cmdLine.argv = []string{""}
+	cmdLine.switches = make(SwitchMap)
+	cmdLine.begin_args = 1
+
+	if len(argv) == 0 {
+		cmdLine.SetProgram("")
+		return
+	}
+
+	cmdLine.SetProgram(argv[0])
+
+	parseSwitches := true
+	for _, arg := range argv[1:] {
+		arg = strings.TrimSpace(arg)
+
+		// A lone "--" terminates switch parsing, the rest are plain arguments
+		if parseSwitches && arg == "--" {
+			parseSwitches = false
+			continue
+		}
+
+		if parseSwitches {
+			if isSwitch, switchString, switchValue := IsSwitch(arg); isSwitch {
+				key := strings.ToLower(switchString[GetSwitchPrefixLen(switchString):])
+				cmdLine.switches[key] = switchValue
+				continue
+			}
+		}
+
+		cmdLine.argv = append(cmdLine.argv, arg)
+	}
+}
+
+func (cmdLine *CommandLine) GetSwitches() *SwitchMap {
+	return &cmdLine.switches
+}
+
+func (cmdLine *CommandLine) HasSwitch(key string) bool {
+	_, has := cmdLine.switches[key]
+	return has
+}
+
+// Returns the switch value, or an empty string if the switch has none
+func (cmdLine *CommandLine) GetSwitchValue(key string) string {
+	return cmdLine.switches[key]
+}
+
+// Returns the arguments which are not switches
+func (cmdLine *CommandLine) GetArgs() []string {
+	return cmdLine.argv[cmdLine.begin_args:]
+}
+
+func (cmdLine *CommandLine) GetProgram() string {
+	return cmdLine.argv[0]
+}
+
+func (cmdLine *CommandLine) SetProgram(program string) {
+	cmdLine.argv[0] = strings.TrimSpace(program)
+}
+
+// Longer prefixes go first, so "--" is not mistaken for "-"
+var switchPrefixes []string = []string{"--", "-", "/"}
+
+func GetSwitchPrefixLen(arg string) int {
+	for _, prefix := range switchPrefixes {
+		if strings.HasPrefix(arg, prefix) {
+			return strlen(prefix)
+		}
+	}
+
+	return 0
+}
+
+var switchValueSeparator string = "="
+
+/*
+ *  Fill in |switch_string| and |switch_value| if |string| is a switch.
+ *	This will preserve the input switch prefix in the output |switch_string|.
+ */
+func IsSwitch(arg string) (bool, string, string) {
+	prefixLen := GetSwitchPrefixLen(arg)
+	if prefixLen == 0 || prefixLen == strlen(arg) {
+		return false, "", ""
+	}
+
+	pair := strings.SplitN(arg, switchValueSeparator, 2)
+	if len(pair) == 1 {
+		return true, pair[0], ""
+	}
+
+	return true, pair[0], pair[1]
+}
+
+func IsSwitchWithKey(arg string, key string) bool {
+	prefixLen := GetSwitchPrefixLen(arg)
+
+	if prefixLen == 0 || prefixLen == strlen(arg) {
+		return false
+	}
+
+	equalsPos := strings.Index(arg, switchValueSeparator)
+	if equalsPos == -1 {
+		equalsPos = strlen(arg)
+	}
+
+	return strings.ToLower(substr(arg, prefixLen, equalsPos-prefixLen)) == key
+}
+
+const (
+	switchScreen      = "screen"
+	switchLevel       = "level"
+	switchLang        = "lang"
+	switchSeed        = "seed"
+	switchWindowScale = "window-scale"
+	switchFullscreen  = "fullscreen"
+	switchMute        = "mute"
+	switchRecord      = "record"
+	switchReplay      = "replay"
+	switchHelp        = "help"
+)
+
+type SwitchDesc struct {
+	name        string
+	valueName   string
+	description string
+}
+
+var knownSwitches = []SwitchDesc{
+	{switchScreen, "NAME", "Open a registered screen directly, skipping the intro"},
+	{switchLevel, "PATH", "Level file to load (default " + defaultLevelPath + ")"},
+	{switchLang, "CODE", "Interface language, e.g. en_us (default " + defaultLang + ")"},
+	{switchSeed, "N", "Seed for the game random generator"},
+	{switchWindowScale, "K", "Multiply the window size by K"},
+	{switchFullscreen, "", "Start in fullscreen mode"},
+	{switchMute, "", "Disable music and sounds"},
+	{switchRecord, "PATH", "Record gameplay input to a replay file"},
+	{switchReplay, "PATH", "Play back a replay file recorded with --record"},
+	{switchHelp, "", "Show this help and exit"},
+}
+
+// Writes the usage text listing every known switch
+func PrintHelp(w io.Writer, program string) {
+	fmt.Fprintf(w, "Usage: %s [switches]\n\n", program)
+	fmt.Fprintln(w, "Switches may start with --, - or /. Values follow '=', e.g. --seed=42")
+	fmt.Fprintln(w)
+
+	for _, desc := range knownSwitches {
+		name := "--" + desc.name
+		if desc.valueName != "" {
+			name += "=" + desc.valueName
+		}
+
+		fmt.Fprintf(w, "  %-22s %s\n", name, desc.description)
+	}
+
+	var names []string
+	for name := range screenNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Screens: "+strings.Join(names, ", "))
+	}
+}
+
+const defaultLang = "ru_ru"
+
+// Start-up options of a game instance
+type GameConfig struct {
+	screenName  string
+	levelPath   string
+	lang        string
+	seed        int64
+	hasSeed     bool
+	windowScale float64
+	fullscreen  bool
+	mute        bool
+	recordPath  string
+	replayPath  string
+	showHelp    bool
+}
+
+func DefaultGameConfig() GameConfig {
+	return GameConfig{
+		levelPath:   defaultLevelPath,
+		lang:        defaultLang,
+		windowScale: 1.0,
+	}
+}
+
+func requireSwitchValue(cmdLine *CommandLine, key string) (string, error) {
+	value := cmdLine.GetSwitchValue(key)
+	if value == "" {
+		return "", errors.New("switch --" + key + " requires a value")
+	}
+
+	return value, nil
+}
+
+// Builds a game config from parsed switches
+//
+// Unknown switches are reported through the warnings list rather than failing,
+// while malformed values of known switches are an error.
+func ParseGameConfig(cmdLine *CommandLine) (GameConfig, []string, error) {
+	config := DefaultGameConfig()
+	var warnings []string
+	var err error
+
+	for key := range *cmdLine.GetSwitches() {
+		known := false
+		for _, desc := range knownSwitches {
+			if desc.name == key {
+				known = true
+				break
+			}
+		}
+
+		if !known {
+			warnings = append(warnings, "Unknown switch --"+key)
+		}
+	}
+	sort.Strings(warnings)
+
+	config.showHelp = cmdLine.HasSwitch(switchHelp)
+
+	if cmdLine.HasSwitch(switchScreen) {
+		if config.screenName, err = requireSwitchValue(cmdLine, switchScreen); err != nil {
+			return config, warnings, err
+		}
+
+		if _, has := screenNames[config.screenName]; !has {
+			return config, warnings, errors.New("unknown screen " + config.screenName)
+		}
+	}
+
+	if cmdLine.HasSwitch(switchLevel) {
+		if config.levelPath, err = requireSwitchValue(cmdLine, switchLevel); err != nil {
+			return config, warnings, err
+		}
+	}
+
+	if cmdLine.HasSwitch(switchLang) {
+		if config.lang, err = requireSwitchValue(cmdLine, switchLang); err != nil {
+			return config, warnings, err
+		}
+	}
+
+	if cmdLine.HasSwitch(switchSeed) {
+		value, err := requireSwitchValue(cmdLine, switchSeed)
+		if err != nil {
+			return config, warnings, err
+		}
+
+		config.seed, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return config, warnings, errors.New("switch --seed expects an integer, got " + value)
+		}
+
+		config.hasSeed = true
+	}
+
+	if cmdLine.HasSwitch(switchWindowScale) {
+		value, err := requireSwitchValue(cmdLine, switchWindowScale)
+		if err != nil {
+			return config, warnings, err
+		}
+
+		config.windowScale, err = strconv.ParseFloat(value, 64)
+		if err != nil || config.windowScale <= 0 {
+			return config, warnings, errors.New("switch --window-scale expects a positive number, got " + value)
+		}
+	}
+
+	config.fullscreen = cmdLine.HasSwitch(switchFullscreen)
+	config.mute = cmdLine.HasSwitch(switchMute)
+
+	if cmdLine.HasSwitch(switchRecord) {
+		if config.recordPath, err = requireSwitchValue(cmdLine, switchRecord); err != nil {
+			return config, warnings, err
+		}
+	}
+
+	if cmdLine.HasSwitch(switchReplay) {
+		if config.replayPath, err = requireSwitchValue(cmdLine, switchReplay); err != nil {
+			return config, warnings, err
+		}
+	}
+
+	if config.recordPath != "" && config.replayPath != "" {
+		return config, warnings, errors.New("switches --record and --replay can not be used together")
+	}
+
+	return config, warnings, nil
+}
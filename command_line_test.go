@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestGetSwitchPrefixLen(t *testing.T) {
+	cases := map[string]int{
+		"--seed=1": 2,
+		"-seed=1":  1,
+		"/seed=1":  1,
+		"seed":     0,
+		"--":       2,
+	}
+
+	for arg, want := range cases {
+		if got := GetSwitchPrefixLen(arg); got != want {
+			t.Errorf("GetSwitchPrefixLen(%q) = %d, want %d", arg, got, want)
+		}
+	}
+}
+
+func TestIsSwitch(t *testing.T) {
+	isSwitch, key, value := IsSwitch("--level=level/a=b.lvl")
+	if !isSwitch || key != "--level" || value != "level/a=b.lvl" {
+		t.Errorf("IsSwitch = %v %q %q", isSwitch, key, value)
+	}
+
+	isSwitch, key, value = IsSwitch("-mute")
+	if !isSwitch || key != "-mute" || value != "" {
+		t.Errorf("IsSwitch without value = %v %q %q", isSwitch, key, value)
+	}
+
+	if isSwitch, _, _ := IsSwitch("level0.lvl"); isSwitch {
+		t.Error("plain argument reported as switch")
+	}
+
+	if !IsSwitchWithKey("/Fullscreen", "fullscreen") || IsSwitchWithKey("--seed=1", "se") {
+		t.Error("IsSwitchWithKey matched wrong key")
+	}
+}
+
+func TestCommandLineParsesAllPrefixes(t *testing.T) {
+	cmdLine := NewCommandLine([]string{"alpa", "--seed=42", "-mute", "/lang=en_us", "extra", "--", "--level=x"})
+
+	if cmdLine.GetProgram() != "alpa" {
+		t.Errorf("program = %q", cmdLine.GetProgram())
+	}
+
+	if cmdLine.GetSwitchValue("seed") != "42" || !cmdLine.HasSwitch("mute") || cmdLine.GetSwitchValue("lang") != "en_us" {
+		t.Errorf("switches = %v", *cmdLine.GetSwitches())
+	}
+
+	if cmdLine.HasSwitch("level") {
+		t.Error("switch after -- terminator was parsed")
+	}
+
+	args := cmdLine.GetArgs()
+	if len(args) != 2 || args[0] != "extra" || args[1] != "--level=x" {
+		t.Errorf("args = %v", args)
+	}
+}
+
+func TestParseGameConfig(t *testing.T) {
+	if screenNames == nil {
+		screenNames = make(map[string]ScreenBuilder)
+	}
+	RegisterScreenName("game", func(g *Game) IScreen {
+		return NewGameplayScreen(g)
+	})
+
+	cmdLine := NewCommandLine([]string{"alpa", "--screen=game", "--level=level/test.lvl", "--lang=en_us",
+		"--seed=-7", "--window-scale=1.5", "--fullscreen", "--mute", "--bogus"})
+
+	config, warnings, err := ParseGameConfig(cmdLine)
+	if err != nil {
+		t.Fatalf("ParseGameConfig: %v", err)
+	}
+
+	if len(warnings) != 1 {
+		t.Errorf("warnings = %v, want one for --bogus", warnings)
+	}
+
+	if config.screenName != "game" || config.levelPath != "level/test.lvl" || config.lang != "en_us" {
+		t.Errorf("config = %+v", config)
+	}
+
+	if !config.hasSeed || config.seed != -7 || config.windowScale != 1.5 || !config.fullscreen || !config.mute {
+		t.Errorf("config = %+v", config)
+	}
+
+	defaults, _, err := ParseGameConfig(NewCommandLine([]string{"alpa"}))
+	if err != nil || defaults != DefaultGameConfig() {
+		t.Errorf("empty command line config = %+v, %v", defaults, err)
+	}
+}
+
+func TestParseGameConfigRejectsBadValues(t *testing.T) {
+	bad := [][]string{
+		{"alpa", "--seed=abc"},
+		{"alpa", "--seed"},
+		{"alpa", "--window-scale=0"},
+		{"alpa", "--screen=nonexistent"},
+		{"alpa", "--record=a", "--replay=b"},
+	}
+
+	for _, argv := range bad {
+		if _, _, err := ParseGameConfig(NewCommandLine(argv)); err == nil {
+			t.Errorf("ParseGameConfig(%v) succeeded, want error", argv[1:])
+		}
+	}
+}
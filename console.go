@@ -0,0 +1,1115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	consoleMaxOutputLines = 256
+	consoleMaxHistory     = 64
+	consoleHeight         = screenHeight / 2
+	consolePrompt         = "> "
+)
+
+type ConsoleCommandHandler func(c *Console, args []string) error
+
+// Returns candidates for the argument at the given index
+type ConsoleCommandCompleter func(c *Console, argIndex int) []string
+
+type ConsoleCommand struct {
+	name        string
+	usage       string
+	description string
+	handler     ConsoleCommandHandler
+	completer   ConsoleCommandCompleter
+}
+
+var consoleCommands map[string]*ConsoleCommand
+
+func RegisterConsoleCommand(cmd *ConsoleCommand) {
+	if consoleCommands == nil {
+		consoleCommands = make(map[string]*ConsoleCommand)
+	}
+
+	consoleCommands[cmd.name] = cmd
+}
+
+// Variable printable with the "print" command
+type ConsoleVarPrinter func(*Game) string
+
+var consoleVars map[string]ConsoleVarPrinter
+
+func RegisterConsoleVar(name string, printer ConsoleVarPrinter) {
+	if consoleVars == nil {
+		consoleVars = make(map[string]ConsoleVarPrinter)
+	}
+
+	consoleVars[name] = printer
+}
+
+// Drop-down developer console
+//
+// While open it takes over the keyboard and the current screen is paused.
+type Console struct {
+	game       *Game
+	open       bool
+	input      string
+	output     []string
+	history    []string
+	historyPos int
+	scroll     int
+}
+
+func (c *Console) IsOpen() bool {
+	return c != nil && c.open
+}
+
+func (c *Console) Toggle() {
+	c.open = !c.open
+	c.historyPos = len(c.history)
+}
+
+func (c *Console) Print(line string) {
+	c.output = append(c.output, strings.Split(line, "\n")...)
+
+	if len(c.output) > consoleMaxOutputLines {
+		c.output = c.output[len(c.output)-consoleMaxOutputLines:]
+	}
+
+	c.scroll = 0
+}
+
+func (c *Console) Printf(format string, a ...interface{}) {
+	c.Print(fmt.Sprintf(format, a...))
+}
+
+func (c *Console) GetOutput() []string {
+	return c.output
+}
+
+func (c *Console) GetInput() string {
+	return c.input
+}
+
+func (c *Console) SetInput(input string) {
+	c.input = input
+}
+
+// Runs a command line and stores it in the history
+func (c *Console) Execute(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+
+	if len(c.history) == 0 || c.history[len(c.history)-1] != line {
+		c.history = append(c.history, line)
+
+		if len(c.history) > consoleMaxHistory {
+			c.history = c.history[1:]
+		}
+	}
+	c.historyPos = len(c.history)
+
+	c.Print(consolePrompt + line)
+	c.Run(line)
+}
+
+// Runs a command line without echoing it or storing it in the history,
+// printing only what the command does
+func (c *Console) Run(line string) {
+	log.Println("[Console] " + line)
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return
+	}
+
+	cmd, has := consoleCommands[strings.ToLower(fields[0])]
+	if !has {
+		c.Print("Unknown command: " + fields[0] + ". Type \"help\" for a list of commands")
+		return
+	}
+
+	if err := cmd.handler(c, fields[1:]); err != nil {
+		c.Print("Error: " + err.Error())
+		c.Print("Usage: " + cmd.name + " " + cmd.usage)
+	}
+}
+
+// Runs a command at the start of the next gameplay tick, recording it with
+// the input so a replay runs it at the same tick
+func (g *Game) QueueCommand(line string) {
+	g.input.RecordCommand(line)
+	g.commandQueue = append(g.commandQueue, line)
+}
+
+func (g *Game) RunQueuedCommands() {
+	queue := g.commandQueue
+	g.commandQueue = nil
+
+	for _, line := range queue {
+		g.console.Run(line)
+	}
+}
+
+func (c *Console) HistoryPrev() {
+	if c.historyPos > 0 {
+		c.historyPos--
+		c.input = c.history[c.historyPos]
+	}
+}
+
+func (c *Console) HistoryNext() {
+	if c.historyPos < len(c.history) {
+		c.historyPos++
+	}
+
+	if c.historyPos == len(c.history) {
+		c.input = ""
+	} else {
+		c.input = c.history[c.historyPos]
+	}
+}
+
+// Completes the last word of the input line
+//
+// Extends the word up to the longest common prefix of the candidates and
+// prints them all if the choice is still ambiguous.
+func (c *Console) Complete() {
+	fields := strings.Fields(c.input)
+	if len(fields) == 0 || strings.HasSuffix(c.input, " ") {
+		fields = append(fields, "")
+	}
+
+	word := fields[len(fields)-1]
+
+	var candidates []string
+	if len(fields) == 1 {
+		for name := range consoleCommands {
+			candidates = append(candidates, name)
+		}
+	} else if cmd, has := consoleCommands[strings.ToLower(fields[0])]; has && cmd.completer != nil {
+		candidates = cmd.completer(c, len(fields)-2)
+	}
+
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) {
+			matches = append(matches, candidate)
+		}
+	}
+	sort.Strings(matches)
+
+	if len(matches) == 0 {
+		return
+	}
+
+	prefix := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	fields[len(fields)-1] = prefix
+	c.input = strings.Join(fields, " ")
+
+	if len(matches) == 1 {
+		c.input += " "
+	} else {
+		c.Print(strings.Join(matches, "  "))
+	}
+}
+
+// Returns true on the first tick of a key press and then periodically while held
+func repeatingKeyPressed(key ebiten.Key) bool {
+	const (
+		delay    = 30
+		interval = 3
+	)
+
+	d := inpututil.KeyPressDuration(key)
+	if d == 1 {
+		return true
+	}
+
+	return d >= delay && (d-delay)%interval == 0
+}
+
+func (c *Console) Update() {
+	for _, ch := range ebiten.InputChars() {
+		if ch >= ' ' && ch != '`' {
+			c.input += string(ch)
+		}
+	}
+
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		c.open = false
+
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadEnter):
+		line := c.input
+		c.input = ""
+		c.Execute(line)
+		c.game.input.RecordCommand(line)
+
+	case repeatingKeyPressed(ebiten.KeyBackspace):
+		c.input = trimLastChar(c.input)
+
+	case inpututil.IsKeyJustPressed(ebiten.KeyTab):
+		c.Complete()
+
+	case repeatingKeyPressed(ebiten.KeyUp):
+		c.HistoryPrev()
+
+	case repeatingKeyPressed(ebiten.KeyDown):
+		c.HistoryNext()
+
+	case repeatingKeyPressed(ebiten.KeyPageUp):
+		if c.scroll < len(c.output)-1 {
+			c.scroll++
+		}
+
+	case repeatingKeyPressed(ebiten.KeyPageDown):
+		if c.scroll > 0 {
+			c.scroll--
+		}
+	}
+}
+
+func (c *Console) Draw(screen *ebiten.Image) {
+	ebitenutil.DrawRect(screen, 0, 0, screenWidth, consoleHeight, color.RGBA{0, 0, 0, 192})
+	ebitenutil.DrawRect(screen, 0, consoleHeight, screenWidth, 2, color.RGBA{128, 128, 128, 255})
+
+	fontRenderer := c.game.systemFontRenderer
+	if fontRenderer == nil {
+		fontRenderer = c.game.fontRenderer
+	}
+
+	fontRenderer.PushState()
+
+	fontRenderer.Reset()
+	fontRenderer.SetTextColor(color.White)
+
+	lineHeight := fontRenderer.GetGlyphSize().Y + 2
+	y := float64(consoleHeight) - lineHeight - 2
+
+	prompt := consolePrompt + c.input
+	if c.game.appTicker/30%2 == 0 {
+		prompt += "_"
+	}
+	fontRenderer.DrawTextAt(screen, prompt, Vec2f{4, y})
+
+	fontRenderer.SetTextColor(color.RGBA{192, 192, 192, 255})
+
+	for i := len(c.output) - 1 - c.scroll; i >= 0; i-- {
+		y -= lineHeight
+		if y < 0 {
+			break
+		}
+
+		fontRenderer.DrawTextAt(screen, c.output[i], Vec2f{4, y})
+	}
+
+	fontRenderer.PopState()
+}
+
+func NewConsole(g *Game) *Console {
+	c := new(Console)
+	c.game = g
+	return c
+}
+
+func consoleFindEntity(g *Game, id int) ILivingEntity {
+	g.entityListMutex.RLock()
+	defer g.entityListMutex.RUnlock()
+
+	for _, entity := range g.entities {
+		if entity.GetLivingEntity().id == id {
+			return entity
+		}
+	}
+
+	return nil
+}
+
+// Returns the entity selected by an optional trailing id argument,
+// the player character otherwise
+func consoleTargetEntity(g *Game, args []string, idIndex int) (ILivingEntity, error) {
+	if len(args) <= idIndex {
+		if g.char == nil {
+			return nil, errors.New("no player character")
+		}
+
+		return g.char, nil
+	}
+
+	id, err := strconv.Atoi(args[idIndex])
+	if err != nil {
+		return nil, errors.New("entity id must be an integer")
+	}
+
+	entity := consoleFindEntity(g, id)
+	if entity == nil {
+		return nil, fmt.Errorf("no entity with id %d", id)
+	}
+
+	return entity, nil
+}
+
+func consoleParseTilePos(g *Game, xArg, yArg string) (int, int, error) {
+	if g.level == nil {
+		return 0, 0, errors.New("no level loaded")
+	}
+
+	x, errX := strconv.Atoi(xArg)
+	y, errY := strconv.Atoi(yArg)
+	if errX != nil || errY != nil {
+		return 0, 0, errors.New("tile coordinates must be integers")
+	}
+
+	if x < 0 || y < 0 || x >= g.level.width || y >= g.level.height {
+		return 0, 0, fmt.Errorf("tile %d,%d is outside of the %dx%d level", x, y, g.level.width, g.level.height)
+	}
+
+	return x, y, nil
+}
+
+func tileCenter(x, y int) Vec2f {
+	return Vec2f{float64(x*tileSize + tileSize/2), float64(y*tileSize + tileSize/2)}
+}
+
+func mapKeys(names interface{}) []string {
+	var keys []string
+
+	switch m := names.(type) {
+	case map[string]*ConsoleCommand:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]ConsoleVarPrinter:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]ScreenBuilder:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]func(*Game) ILivingEntity:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func _CC_Help(c *Console, args []string) error {
+	if len(args) > 0 {
+		cmd, has := consoleCommands[strings.ToLower(args[0])]
+		if !has {
+			return errors.New("unknown command " + args[0])
+		}
+
+		c.Print(cmd.name + " " + cmd.usage)
+		c.Print("  " + cmd.description)
+		return nil
+	}
+
+	for _, name := range mapKeys(consoleCommands) {
+		c.Printf("%-10s %s", name, consoleCommands[name].description)
+	}
+
+	return nil
+}
+
+func _CC_Clear(c *Console, args []string) error {
+	c.output = nil
+	return nil
+}
+
+func _CC_Teleport(c *Console, args []string) error {
+	if len(args) < 2 {
+		return errors.New("not enough arguments")
+	}
+
+	x, y, err := consoleParseTilePos(c.game, args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	entity, err := consoleTargetEntity(c.game, args, 2)
+	if err != nil {
+		return err
+	}
+
+	e := entity.GetLivingEntity()
+	e.worldPos = tileCenter(x, y)
+	e.prevTilePos, _ = e.GetTilePos()
+
+	c.Printf("Teleported entity %d to %d,%d", e.id, x, y)
+	return nil
+}
+
+func _CC_Spawn(c *Console, args []string) error {
+	if len(args) < 1 {
+		return errors.New("not enough arguments")
+	}
+
+	entity, err := CreateEntity(c.game, strings.ToLower(args[0]))
+	if err != nil {
+		return err
+	}
+
+	g := c.game
+	var pos Vec2f
+
+	if len(args) >= 3 {
+		x, y, err := consoleParseTilePos(g, args[1], args[2])
+		if err != nil {
+			return err
+		}
+
+		pos = tileCenter(x, y)
+	} else if g.char != nil {
+		pos = g.char.GetWorldPos()
+	} else {
+		return errors.New("no player character to spawn at, pass tile coordinates")
+	}
+
+	e := entity.GetLivingEntity()
+	e.worldPos = pos
+	e.prevTilePos, _ = e.GetTilePos()
+
+	g.entityListMutex.Lock()
+	g.entities = append(g.entities, entity)
+	g.entityListMutex.Unlock()
+
+	c.Printf("Spawned %s with id %d", args[0], e.id)
+	return nil
+}
+
+func _CC_Health(c *Console, args []string) error {
+	if len(args) < 1 {
+		return errors.New("not enough arguments")
+	}
+
+	health, err := strconv.ParseFloat(args[0], 64)
+	if err != nil {
+		return errors.New("health must be a number")
+	}
+
+	entity, err := consoleTargetEntity(c.game, args, 1)
+	if err != nil {
+		return err
+	}
+
+	entity.GetLivingEntity().health = health
+	c.Printf("Entity %d health set to %.1f", entity.GetLivingEntity().id, health)
+	return nil
+}
+
+func _CC_Inspect(c *Console, args []string) error {
+	if len(args) < 1 {
+		return errors.New("not enough arguments")
+	}
+
+	ins := c.game.GetEntityInspector()
+	if ins == nil {
+		return errors.New("the entity inspector is not open")
+	}
+
+	switch args[0] {
+	case "select":
+		entity, err := consoleTargetEntity(c.game, args, 1)
+		if err != nil {
+			return err
+		}
+		ins.Select(entity)
+
+	case "adjust":
+		if len(args) < 3 {
+			return errors.New("not enough arguments")
+		}
+
+		delta, err := strconv.ParseFloat(args[2], 64)
+		if err != nil {
+			return errors.New("delta must be a number")
+		}
+
+		if !ins.AdjustNamedField(args[1], delta) {
+			return errors.New("no editable field " + args[1])
+		}
+
+	case "teleport":
+		if len(args) < 3 {
+			return errors.New("not enough arguments")
+		}
+
+		x, errX := strconv.ParseFloat(args[1], 64)
+		y, errY := strconv.ParseFloat(args[2], 64)
+		if errX != nil || errY != nil {
+			return errors.New("world coordinates must be numbers")
+		}
+		ins.TeleportTo(Vec2f{x, y})
+
+	case "pin":
+		ins.TogglePin()
+
+	case "kill":
+		ins.Kill()
+
+	case "clone":
+		ins.Clone()
+
+	default:
+		return errors.New("unknown inspector edit " + args[0])
+	}
+
+	return nil
+}
+
+func _CC_SetTile(c *Console, args []string) error {
+	if len(args) < 4 {
+		return errors.New("not enough arguments")
+	}
+
+	g := c.game
+
+	x, y, err := consoleParseTilePos(g, args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	layer, err := strconv.Atoi(args[2])
+	if err != nil || layer < 0 || layer >= g.level.GetLayerCount() {
+		return fmt.Errorf("layer must be between 0 and %d", g.level.GetLayerCount()-1)
+	}
+
+	tile, err := strconv.Atoi(args[3])
+	if err != nil || tile < 0 || tile > 255 {
+		return errors.New("tile id must be between 0 and 255")
+	}
+
+	g.level.SetTile(layer, y*g.level.width+x, Tile(tile))
+	c.Printf("Tile %d,%d on layer %d set to %d", x, y, layer, tile)
+	return nil
+}
+
+func _CC_LoadLevel(c *Console, args []string) error {
+	if len(args) < 1 {
+		return errors.New("not enough arguments")
+	}
+
+	if err := c.game.LoadLevel(args[0]); err != nil {
+		return err
+	}
+
+	c.Print("Loaded level " + args[0])
+	return nil
+}
+
+func _CC_NewWorld(c *Console, args []string) error {
+	if len(args) < 3 {
+		return errors.New("not enough arguments")
+	}
+
+	width, errW := strconv.Atoi(args[1])
+	height, errH := strconv.Atoi(args[2])
+	if errW != nil || errH != nil || width <= 0 || height <= 0 {
+		return errors.New("size must be positive numbers of tiles")
+	}
+
+	if err := CreateWorld(args[0], width, height, 2); err != nil {
+		return err
+	}
+
+	if err := c.game.LoadLevel(args[0]); err != nil {
+		return err
+	}
+
+	c.Printf("Created world %s (%dx%d)", args[0], width, height)
+	return nil
+}
+
+func _CC_Travel(c *Console, args []string) error {
+	if len(args) < 3 {
+		return errors.New("not enough arguments")
+	}
+
+	x, errX := strconv.Atoi(args[1])
+	y, errY := strconv.Atoi(args[2])
+	if errX != nil || errY != nil {
+		return errors.New("spawn position must be tile numbers")
+	}
+
+	if err := c.game.TravelTo(args[0], x, y); err != nil {
+		return err
+	}
+
+	c.Print("Entered level " + args[0])
+	return nil
+}
+
+// Lists the level circuit or adds and removes its gates
+func _CC_Gate(c *Console, args []string) error {
+	if len(args) < 1 {
+		return errors.New("not enough arguments")
+	}
+
+	signals := c.game.level.signals
+
+	switch args[0] {
+	case "list":
+		for _, line := range signals.Describe() {
+			c.Print(line)
+		}
+
+	case "add":
+		if len(args) < 4 {
+			return errors.New("usage: gate add <type> <output> <input,...> [ticks]")
+		}
+
+		if !IsGateType(args[1]) {
+			return errors.New("unknown gate type " + args[1] + ", known: and, or, toggle, timer")
+		}
+
+		gate := JSONSignalGate{Type: args[1]}
+
+		var err error
+		if gate.Output, err = strconv.Atoi(args[2]); err != nil {
+			return errors.New("output must be a channel number")
+		}
+
+		for _, input := range strings.Split(args[3], ",") {
+			channel, err := strconv.Atoi(input)
+			if err != nil {
+				return errors.New("inputs must be channel numbers")
+			}
+			gate.Inputs = append(gate.Inputs, channel)
+		}
+
+		if len(args) > 4 {
+			if gate.Ticks, err = strconv.Atoi(args[4]); err != nil {
+				return errors.New("ticks must be a number")
+			}
+		}
+
+		signals.Gates = append(signals.Gates, gate)
+		c.Printf("Added gate %d", len(signals.Gates)-1)
+
+	case "remove":
+		if len(args) < 2 {
+			return errors.New("not enough arguments")
+		}
+
+		index, err := strconv.Atoi(args[1])
+		if err != nil || index < 0 || index >= len(signals.Gates) {
+			return errors.New("no gate " + args[1])
+		}
+
+		signals.Gates = append(signals.Gates[:index], signals.Gates[index+1:]...)
+		// Gate state is kept by index, so it starts over
+		c.game.level.SetSignals(signals)
+		c.Print("Removed gate " + args[1])
+
+	default:
+		return errors.New("unknown action " + args[0])
+	}
+
+	return nil
+}
+
+func _CC_Screen(c *Console, args []string) error {
+	if len(args) < 1 {
+		return errors.New("not enough arguments")
+	}
+
+	builder, has := screenNames[args[0]]
+	if !has {
+		return errors.New("unknown screen " + args[0] + ", known: " + strings.Join(mapKeys(screenNames), ", "))
+	}
+
+	c.game.SetScreen(builder(c.game))
+	c.Print("Switched to screen " + args[0])
+	return nil
+}
+
+func _CC_Shop(c *Console, args []string) error {
+	keeper := defaultShopkeeper
+	if len(args) >= 1 {
+		keeper = args[0]
+	}
+
+	if _, err := os.Stat(ShopkeeperPath(keeper)); err != nil {
+		return errors.New("unknown shopkeeper " + keeper)
+	}
+
+	c.game.SetScreen(NewShopkeeperScreen(c.game, keeper))
+	c.Print("Opened the shop of " + keeper)
+	return nil
+}
+
+func _CC_Money(c *Console, args []string) error {
+	g := c.game
+	if g.char == nil {
+		return errors.New("no player character")
+	}
+
+	if len(args) < 1 {
+		c.Printf("Money: %d", g.char.GetMoney())
+		return nil
+	}
+
+	money, err := strconv.Atoi(args[0])
+	if err != nil || money < 0 {
+		return errors.New("money must be a positive number")
+	}
+
+	g.char.AddMoney(money - g.char.GetMoney())
+	c.Printf("Money set to %d", money)
+	return nil
+}
+
+func _CC_Flag(c *Console, args []string) error {
+	if len(args) < 1 {
+		return errors.New("not enough arguments")
+	}
+
+	g := c.game
+	value := !g.HasFlag(args[0])
+	if len(args) >= 2 {
+		switch args[1] {
+		case "on":
+			value = true
+		case "off":
+			value = false
+		default:
+			return errors.New("flag value must be on or off")
+		}
+	}
+
+	g.SetFlag(args[0], value)
+	c.Printf("Story flag %s is %t", args[0], value)
+	return nil
+}
+
+func _CC_God(c *Console, args []string) error {
+	c.game.godMode = !c.game.godMode
+	c.Printf("God mode: %t", c.game.godMode)
+	return nil
+}
+
+func _CC_TimeScale(c *Console, args []string) error {
+	if len(args) < 1 {
+		c.Printf("Time scale: %g", c.game.timeScale)
+		return nil
+	}
+
+	scale, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || scale < 0 || scale > 16 {
+		return errors.New("time scale must be a number between 0 and 16")
+	}
+
+	c.game.timeScale = scale
+	c.Printf("Time scale set to %g", scale)
+	return nil
+}
+
+func _CC_Conveyor(c *Console, args []string) error {
+	level := c.game.level
+
+	if len(args) < 1 {
+		c.Printf("Conveyor speed: %g", level.GetConveyorSpeed())
+		return nil
+	}
+
+	speed, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || speed <= 0 || speed > tileSize {
+		return fmt.Errorf("conveyor speed must be a number between 0 and %d", tileSize)
+	}
+
+	level.signals.ConveyorSpeed = speed
+	c.Printf("Conveyor speed set to %g", speed)
+	return nil
+}
+
+func _CC_Give(c *Console, args []string) error {
+	if len(args) < 1 {
+		return errors.New("not enough arguments")
+	}
+
+	g := c.game
+	if g.char == nil {
+		return errors.New("no player character")
+	}
+
+	def := GetItemDef(args[0])
+	if def == nil {
+		return fmt.Errorf("unknown item %s", args[0])
+	}
+
+	count := 1
+	if len(args) >= 2 {
+		var err error
+		if count, err = strconv.Atoi(args[1]); err != nil || count <= 0 {
+			return errors.New("count must be a positive number")
+		}
+	}
+
+	if left := g.char.AddItem(def.ID, count); left > 0 {
+		c.Printf("Gave %d %s, %d did not fit", count-left, def.ID, left)
+	} else {
+		c.Printf("Gave %d %s", count, def.ID)
+	}
+
+	return nil
+}
+
+func _CC_Print(c *Console, args []string) error {
+	names := args
+	if len(names) == 0 {
+		names = mapKeys(consoleVars)
+	}
+
+	for _, name := range names {
+		printer, has := consoleVars[name]
+		if !has {
+			return errors.New("unknown variable " + name)
+		}
+
+		c.Printf("%s = %s", name, printer(c.game))
+	}
+
+	return nil
+}
+
+func _CC_Profiler(c *Console, args []string) error {
+	prof := Profiler_GetInstance()
+
+	if len(args) == 0 {
+		c.Printf("Profiler enabled: %t, %d frames captured", prof.IsEnabled(), len(prof.GetFrames()))
+		return nil
+	}
+
+	switch args[0] {
+	case "on":
+		prof.SetEnabled(true)
+	case "off":
+		prof.SetEnabled(false)
+	case "export":
+		if len(args) < 2 {
+			return errors.New("export needs a file path")
+		}
+
+		if err := prof.Export(args[1]); err != nil {
+			return err
+		}
+
+		c.Printf("Exported %d frames to %s", len(prof.GetFrames()), args[1])
+		return nil
+	default:
+		return errors.New("unknown action " + args[0])
+	}
+
+	c.Printf("Profiler enabled: %t", prof.IsEnabled())
+	return nil
+}
+
+func _CCC_EntityClasses(c *Console, argIndex int) []string {
+	if argIndex == 0 {
+		return GetEntityClassNames()
+	}
+
+	return nil
+}
+
+func _CCC_Screens(c *Console, argIndex int) []string {
+	if argIndex == 0 {
+		return mapKeys(screenNames)
+	}
+
+	return nil
+}
+
+func _CCC_Commands(c *Console, argIndex int) []string {
+	if argIndex == 0 {
+		return mapKeys(consoleCommands)
+	}
+
+	return nil
+}
+
+func _CCC_Profiler(c *Console, argIndex int) []string {
+	if argIndex == 0 {
+		return []string{"on", "off", "export"}
+	}
+
+	return nil
+}
+
+func _CCC_Gate(c *Console, argIndex int) []string {
+	switch argIndex {
+	case 0:
+		return []string{"list", "add", "remove"}
+	case 1:
+		return []string{gateAnd, gateOr, gateToggle, gateTimer}
+	}
+
+	return nil
+}
+
+func _CCC_Items(c *Console, argIndex int) []string {
+	if argIndex == 0 {
+		return GetItemIDs()
+	}
+
+	return nil
+}
+
+func _CCC_Vars(c *Console, argIndex int) []string {
+	return mapKeys(consoleVars)
+}
+
+func init() {
+	commands := []*ConsoleCommand{
+		{"help", "[command]", "List commands or describe one", _CC_Help, _CCC_Commands},
+		{"clear", "", "Clear the console output", _CC_Clear, nil},
+		{"teleport", "<x> <y> [entity id]", "Move the player or an entity to a tile", _CC_Teleport, nil},
+		{"spawn", "<class> [x y]", "Spawn an entity at the player or on a tile", _CC_Spawn, _CCC_EntityClasses},
+		{"health", "<value> [entity id]", "Set health of the player or an entity", _CC_Health, nil},
+		{"settile", "<x> <y> <layer> <tile id>", "Replace a level tile", _CC_SetTile, nil},
+		{"loadlevel", "<path>", "Load a level file or a world directory", _CC_LoadLevel, nil},
+		{"newworld", "<dir> <width> <height>", "Create and load an empty streamed world", _CC_NewWorld, nil},
+		{"travel", "<level> <x> <y>", "Move the player to another level, keeping this one", _CC_Travel, nil},
+		{"gate", "<list|add|remove> [type output inputs ticks|index]", "Edit logic gates of the level circuit, saved with the level", _CC_Gate, _CCC_Gate},
+		{"conveyor", "[speed]", "Show or set the level's conveyor speed in pixels per tick", _CC_Conveyor, nil},
+		{"screen", "<name>", "Switch to a registered screen", _CC_Screen, _CCC_Screens},
+		{"give", "<item> [count]", "Put items into the player inventory", _CC_Give, _CCC_Items},
+		{"money", "[amount]", "Show or set the player's money", _CC_Money, nil},
+		{"shop", "[shopkeeper]", "Open the shop of a shopkeeper from assets/shop", _CC_Shop, nil},
+		{"flag", "<name> [on|off]", "Toggle or set a story flag", _CC_Flag, nil},
+		{"god", "", "Toggle player invulnerability", _CC_God, nil},
+		{"timescale", "[scale]", "Show or set the world time scale", _CC_TimeScale, nil},
+		{"print", "[variable...]", "Print variables, all of them by default", _CC_Print, _CCC_Vars},
+		{"profiler", "[on|off|export <file.csv|file.json>]", "Control the profiler overlay", _CC_Profiler, _CCC_Profiler},
+		{"inspect", "<select id|adjust field delta|teleport x y|pin|kill|clone>", "Edit the selection of the open entity inspector", _CC_Inspect, nil},
+	}
+
+	for _, cmd := range commands {
+		RegisterConsoleCommand(cmd)
+	}
+
+	RegisterConsoleVar("seed", func(g *Game) string {
+		return strconv.FormatInt(g.seed, 10)
+	})
+
+	RegisterConsoleVar("tick", func(g *Game) string {
+		return strconv.Itoa(tickCounter)
+	})
+
+	RegisterConsoleVar("timescale", func(g *Game) string {
+		return strconv.FormatFloat(g.timeScale, 'g', -1, 64)
+	})
+
+	RegisterConsoleVar("god", func(g *Game) string {
+		return strconv.FormatBool(g.godMode)
+	})
+
+	RegisterConsoleVar("screen", func(g *Game) string {
+		return fmt.Sprintf("%T", g.currentScreen)
+	})
+
+	RegisterConsoleVar("level", func(g *Game) string {
+		if g.level == nil {
+			return "none"
+		}
+
+		return fmt.Sprintf("%s (%dx%d, %d layers)", g.level.fileName, g.level.width, g.level.height, g.level.GetLayerCount())
+	})
+
+	RegisterConsoleVar("score", func(g *Game) string {
+		return strconv.Itoa(g.ballScore)
+	})
+
+	RegisterConsoleVar("inventory", func(g *Game) string {
+		if g.char == nil {
+			return "none"
+		}
+
+		ids, totals := g.char.GetInventory().GetTotals()
+		if len(ids) == 0 {
+			return "empty"
+		}
+
+		var items []string
+		for _, id := range ids {
+			items = append(items, fmt.Sprintf("%s x%d", id, totals[id]))
+		}
+
+		return strings.Join(items, ", ")
+	})
+
+	RegisterConsoleVar("flags", func(g *Game) string {
+		flags := g.GetFlags()
+		if len(flags) == 0 {
+			return "none"
+		}
+
+		return strings.Join(flags, ", ")
+	})
+
+	RegisterConsoleVar("minefields", func(g *Game) string {
+		if g.level == nil || len(g.level.minefields) == 0 {
+			return "none"
+		}
+
+		return g.level.DescribeMinefields()
+	})
+
+	RegisterConsoleVar("entities", func(g *Game) string {
+		g.entityListMutex.RLock()
+		defer g.entityListMutex.RUnlock()
+
+		return strconv.Itoa(len(g.entities))
+	})
+
+	RegisterConsoleVar("player", func(g *Game) string {
+		if g.char == nil {
+			return "none"
+		}
+
+		e := g.char.GetLivingEntity()
+		tilePos, _ := e.GetTilePos()
+		return fmt.Sprintf("id %d, x %.1f, y %.1f, tile %d, health %.1f", e.id, e.worldPos.X, e.worldPos.Y, tilePos, e.health)
+	})
+
+	RegisterConsoleVar("zoom", func(g *Game) string {
+		return strconv.FormatFloat(g.camera.GetZoom(), 'g', -1, 64)
+	})
+
+	RegisterConsoleVar("tilemap", func(g *Game) string {
+		if g.tilemapRenderer == nil {
+			return "not drawn yet"
+		}
+
+		return fmt.Sprintf("%d cached chunks, %d chunk renders", g.tilemapRenderer.GetCachedChunkCount(), g.tilemapRenderer.GetRedrawCount())
+	})
+
+	RegisterConsoleVar("tps", func(g *Game) string {
+		return fmt.Sprintf("%.1f", ebiten.CurrentTPS())
+	})
+}
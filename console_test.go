@@ -0,0 +1,122 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func lastLine(c *Console) string {
+	output := c.GetOutput()
+	if len(output) == 0 {
+		return ""
+	}
+
+	return output[len(output)-1]
+}
+
+func TestConsoleTeleportAndHealth(t *testing.T) {
+	sim, _ := newTestSimulation(t, newTestLevel())
+	g := sim.GetGame()
+	sim.Spawn(g.char, 1, 1)
+	c := g.console
+
+	c.Execute("teleport 4 6")
+	if tilePos, _ := g.char.GetTilePos(); tilePos != tileIndex(g.level, 4, 6) {
+		t.Fatalf("player tile = %d after teleport, want %d", tilePos, tileIndex(g.level, 4, 6))
+	}
+
+	c.Execute("teleport 40 6")
+	if !strings.HasPrefix(lastLine(c), "Usage:") {
+		t.Fatalf("out of bounds teleport output = %q, want usage", lastLine(c))
+	}
+
+	c.Execute("health 25")
+	if g.char.GetHealth() != 25 {
+		t.Fatalf("health = %f, want 25", g.char.GetHealth())
+	}
+}
+
+func TestConsoleSpawnAndSetTile(t *testing.T) {
+	sim, _ := newTestSimulation(t, newTestLevel())
+	g := sim.GetGame()
+	sim.Spawn(g.char, 1, 1)
+	c := g.console
+
+	c.Execute("spawn michael 5 5")
+	if len(g.entities) != 2 {
+		t.Fatalf("entity count = %d after spawn, want 2", len(g.entities))
+	}
+
+	c.Execute("spawn dragon")
+	if len(g.entities) != 2 {
+		t.Fatal("unknown entity class was spawned")
+	}
+
+	c.Execute("settile 2 3 1 7")
+	if got := g.level.GetTile(testObjectLayer, tileIndex(g.level, 2, 3)); got != 7 {
+		t.Fatalf("tile = %d, want 7", got)
+	}
+}
+
+func TestConsoleGodModeBlocksDamage(t *testing.T) {
+	level := newTestLevel()
+	level.SetTile(testObjectLayer, tileIndex(level, 3, 3), tileIDThornsActive)
+
+	sim, _ := newTestSimulation(t, level)
+	g := sim.GetGame()
+	sim.Spawn(g.char, 3, 3)
+
+	g.console.Execute("god")
+	sim.Step(5)
+
+	if g.char.GetHealth() != 100 {
+		t.Fatalf("health = %f in god mode, want 100", g.char.GetHealth())
+	}
+}
+
+func TestConsoleTimeScale(t *testing.T) {
+	sim, _ := newTestSimulation(t, newTestLevel())
+	g := sim.GetGame()
+
+	g.console.Execute("timescale 0.5")
+	sim.Step(10)
+
+	if tickCounter != 5 {
+		t.Fatalf("tick counter = %d after 10 frames at half speed, want 5", tickCounter)
+	}
+}
+
+func TestConsoleCompletionAndHistory(t *testing.T) {
+	sim, _ := newTestSimulation(t, newTestLevel())
+	c := sim.GetGame().console
+
+	c.SetInput("tele")
+	c.Complete()
+	if c.GetInput() != "teleport " {
+		t.Fatalf("completed input = %q, want %q", c.GetInput(), "teleport ")
+	}
+
+	c.SetInput("spawn mo")
+	c.Complete()
+	if c.GetInput() != "spawn mo" || !strings.Contains(lastLine(c), "monobear") || !strings.Contains(lastLine(c), "morgen") {
+		t.Fatalf("ambiguous completion input = %q, output = %q", c.GetInput(), lastLine(c))
+	}
+
+	c.Execute("god")
+	c.Execute("print god")
+	if lastLine(c) != "god = true" {
+		t.Fatalf("print output = %q", lastLine(c))
+	}
+
+	c.HistoryPrev()
+	c.HistoryPrev()
+	if c.GetInput() != "god" {
+		t.Fatalf("history input = %q, want %q", c.GetInput(), "god")
+	}
+
+	c.HistoryNext()
+	c.HistoryNext()
+	if c.GetInput() != "" {
+		t.Fatalf("input = %q after walking history forward, want empty", c.GetInput())
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// Conveyor speed in pixels per tick, used when the level does not set one
+const defaultConveyorSpeed = 0.5
+
+// Returns the direction an arrow tile carries things in
+func conveyorDirection(tile Tile) (LookDirection, bool) {
+	switch tile {
+	case tileIDArrowRight:
+		return LooksRight, true
+	case tileIDArrowLeft:
+		return LooksLeft, true
+	case tileIDArrowUp:
+		return LooksUp, true
+	case tileIDArrowDown:
+		return LooksDown, true
+	}
+
+	return LooksRight, false
+}
+
+func lookDirectionVector(look LookDirection) Vec2f {
+	switch look {
+	case LooksLeft:
+		return Vec2f{-1, 0}
+	case LooksUp:
+		return Vec2f{0, -1}
+	case LooksDown:
+		return Vec2f{0, 1}
+	}
+
+	return Vec2f{1, 0}
+}
+
+func (lv *Level) GetConveyorSpeed() float64 {
+	if lv.signals.ConveyorSpeed > 0 {
+		return lv.signals.ConveyorSpeed
+	}
+
+	return defaultConveyorSpeed
+}
+
+// Returns the direction of a running conveyor at the tile. Wired conveyors
+// stop while their channel is low.
+func (g *Game) GetConveyorAt(tilePos int) (LookDirection, bool) {
+	for i := 0; i < g.level.GetLayerCount(); i++ {
+		if look, ok := conveyorDirection(g.level.GetTile(i, tilePos)); ok {
+			return look, g.IsReceiverPowered(tilePos)
+		}
+	}
+
+	return LooksRight, false
+}
+
+// Carries entities and boxes standing on running conveyors
+//
+// Entities drift on top of their own walking and stop at solid tiles.
+// Boxes move a whole tile once the conveyor carried them tileSize pixels.
+func (g *Game) UpdateConveyors() {
+	speed := g.level.GetConveyorSpeed()
+
+	for _, entity := range g.entities {
+		e := entity.GetLivingEntity()
+
+		tilePos, err := e.GetTilePos()
+		if err != nil {
+			continue
+		}
+
+		look, ok := g.GetConveyorAt(tilePos)
+		if !ok {
+			continue
+		}
+
+		pos := e.worldPos.Add(lookDirectionVector(look).Scale(speed))
+		if !g.IsTileSolidAt(pos.X, pos.Y) {
+			e.worldPos = pos
+		}
+	}
+
+	g.updateConveyorBoxes(speed)
+}
+
+func (g *Game) updateConveyorBoxes(speed float64) {
+	level := g.level
+	progress := make(map[int]float64)
+
+	var boxes []int
+	for _, chunk := range level.GetLoadedChunks() {
+		for y := chunk.y * levelChunkSize; y < (chunk.y+1)*levelChunkSize && y < level.height; y++ {
+			for x := chunk.x * levelChunkSize; x < (chunk.x+1)*levelChunkSize && x < level.width; x++ {
+				tilePos := y*level.width + x
+				if g.findBoxLayer(tilePos) < 0 {
+					continue
+				}
+
+				if _, ok := g.GetConveyorAt(tilePos); ok {
+					boxes = append(boxes, tilePos)
+				}
+			}
+		}
+	}
+	sort.Ints(boxes)
+
+	moved := false
+
+	for _, boxPos := range boxes {
+		look, _ := g.GetConveyorAt(boxPos)
+
+		carried := math.Min(level.conveyorMoves[boxPos]+speed, tileSize)
+		if carried < tileSize {
+			progress[boxPos] = carried
+			continue
+		}
+
+		var move sokobanMove
+		if g.moveBox(g.findBoxLayer(boxPos), boxPos, look, &move) {
+			moved = true
+		} else {
+			// Blocked boxes move as soon as the way is free
+			progress[boxPos] = carried
+		}
+	}
+
+	level.conveyorMoves = progress
+
+	// Undo entries would restore tiles the conveyor has changed since
+	if moved && g.sokoban != nil {
+		g.sokoban.undo = nil
+		g.UpdateSokobanState()
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func TestConveyorCarriesPlayer(t *testing.T) {
+	level := newTestLevel()
+	for x := 3; x <= 5; x++ {
+		level.SetTile(testFloorLayer, tileIndex(level, x, 3), tileIDArrowDown)
+	}
+
+	sim, _ := newTestSimulation(t, level)
+	player := sim.GetGame().char
+	sim.Spawn(player, 4, 3)
+
+	start := player.GetWorldPos()
+	sim.Step(10)
+
+	if got, want := player.GetWorldPos().Y-start.Y, 10*defaultConveyorSpeed; got != want {
+		t.Fatalf("player moved %g pixels down, want %g", got, want)
+	}
+}
+
+func TestConveyorMovesBox(t *testing.T) {
+	level := newTestLevel()
+	level.SetTile(testFloorLayer, tileIndex(level, 4, 5), tileIDArrowLeft)
+	level.SetTile(testObjectLayer, tileIndex(level, 4, 5), tileIDSokobanBox)
+
+	sim, _ := newTestSimulation(t, level)
+	sim.GetGame().ResetSokoban()
+
+	ticks := int(tileSize / defaultConveyorSpeed)
+	sim.Step(ticks - 1)
+
+	if level.GetTile(testObjectLayer, tileIndex(level, 4, 5)) != tileIDSokobanBox {
+		t.Fatal("box left the conveyor too early")
+	}
+
+	sim.Step(1)
+
+	if level.GetTile(testObjectLayer, tileIndex(level, 3, 5)) != tileIDSokobanBox ||
+		level.GetTile(testObjectLayer, tileIndex(level, 4, 5)) != tileIDEmpty {
+		t.Fatal("box was not carried one tile left")
+	}
+
+	// Off the conveyor the box stays put
+	sim.Step(ticks * 2)
+
+	if level.GetTile(testObjectLayer, tileIndex(level, 3, 5)) != tileIDSokobanBox {
+		t.Fatal("box kept moving off the conveyor")
+	}
+}
+
+func TestSwitchStopsWiredConveyor(t *testing.T) {
+	level := newTestLevel()
+	level.SetTile(testObjectLayer, tileIndex(level, 3, 3), tileIDSwitch)
+	level.SetTile(testFloorLayer, tileIndex(level, 6, 6), tileIDArrowUp)
+	level.SetTile(testObjectLayer, tileIndex(level, 6, 6), tileIDSokobanBox)
+
+	level.SetSignals(&JSONLevelSignals{
+		Emitters:  []JSONSignalWire{{X: 3, Y: 3, Channel: 2}},
+		Receivers: []JSONSignalWire{{X: 6, Y: 6, Channel: 2, Invert: true}},
+	})
+
+	sim, input := newTestSimulation(t, level)
+	g := sim.GetGame()
+	g.ResetSokoban()
+	sim.Spawn(g.char, 2, 3)
+
+	// Turning the switch on stops the conveyor before it moves the box
+	input.Hold(0, tileSize, kbPlayerMoveRight)
+	sim.Step(tileSize + 1)
+
+	if _, running := g.GetConveyorAt(tileIndex(level, 6, 6)); running {
+		t.Fatal("conveyor still runs with the switch on")
+	}
+
+	sim.Step(int(tileSize/defaultConveyorSpeed) * 2)
+
+	if level.GetTile(testObjectLayer, tileIndex(level, 6, 6)) != tileIDSokobanBox {
+		t.Fatal("stopped conveyor moved the box")
+	}
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Node ID ending a dialogue, same as an empty one
+const dialogueEnd = "end"
+
+const (
+	dialogueActionGiveItem   = "give_item"
+	dialogueActionTakeItem   = "take_item"
+	dialogueActionSetFlag    = "set_flag"
+	dialogueActionClearFlag  = "clear_flag"
+	dialogueActionExpression = "expression"
+	dialogueActionAnimate    = "animate"
+	dialogueActionEvent      = "event"
+)
+
+// Effect of a dialogue line or choice
+//
+// give_item and take_item move Count of Item (1 by default) to or from the
+// player, set_flag and clear_flag change a story flag, expression shows the
+// Preset of a VN Character or the Sprite of one of its Groups ("facial" by
+// default), animate plays a Tween such as "jump" on it, event passes Event
+// to the screen running the dialogue, e.g. "buy" to the shop.
+type JSONDialogueAction struct {
+	Type      string `json:"type"`
+	Item      string `json:"item,omitempty"`
+	Count     int    `json:"count,omitempty"`
+	Flag      string `json:"flag,omitempty"`
+	Character string `json:"character,omitempty"`
+	Group     string `json:"group,omitempty"`
+	Sprite    string `json:"sprite,omitempty"`
+	Preset    string `json:"preset,omitempty"`
+	Tween     string `json:"tween,omitempty"`
+	Event     string `json:"event,omitempty"`
+}
+
+// Line of a dialogue node. Speaker and Text are language keys, shown as
+// they are when there is no such key; a line without a speaker keeps the
+// previous one and its voice. Voice is the blip pitch in Hz, Speed the
+// characters typed per tick. If lists the story flags that must be set,
+// "!flag" ones that must not.
+type JSONDialogueLine struct {
+	Speaker string               `json:"speaker,omitempty"`
+	Text    string               `json:"text"`
+	Voice   float64              `json:"voice,omitempty"`
+	Speed   float64              `json:"speed,omitempty"`
+	If      []string             `json:"if,omitempty"`
+	Actions []JSONDialogueAction `json:"actions,omitempty"`
+}
+
+type JSONDialogueChoice struct {
+	Text    string               `json:"text"`
+	Next    string               `json:"next,omitempty"`
+	If      []string             `json:"if,omitempty"`
+	Actions []JSONDialogueAction `json:"actions,omitempty"`
+}
+
+// Step of a dialogue. Its lines are said in order, the choices are offered
+// with the last one. Without choices the dialogue goes on to Next.
+type JSONDialogueNode struct {
+	Lines   []JSONDialogueLine   `json:"lines"`
+	Choices []JSONDialogueChoice `json:"choices,omitempty"`
+	Next    string               `json:"next,omitempty"`
+}
+
+type JSONDialogue struct {
+	Start string                       `json:"start"`
+	Nodes map[string]*JSONDialogueNode `json:"nodes"`
+}
+
+func LoadDialogue(path string) (*JSONDialogue, error) {
+	dialogue := new(JSONDialogue)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return dialogue, err
+	}
+
+	if err = json.Unmarshal(data, dialogue); err != nil {
+		return dialogue, err
+	}
+
+	return dialogue, dialogue.Validate()
+}
+
+func (d *JSONDialogue) hasNode(id string) bool {
+	_, has := d.Nodes[id]
+	return has || id == "" || id == dialogueEnd
+}
+
+func validateDialogueActions(actions []JSONDialogueAction) error {
+	for _, action := range actions {
+		switch action.Type {
+		case dialogueActionGiveItem, dialogueActionTakeItem:
+			if GetItemDef(action.Item) == nil {
+				return fmt.Errorf("unknown item %q", action.Item)
+			}
+		case dialogueActionAnimate:
+			if GetVNTween(action.Tween) == nil {
+				return fmt.Errorf("unknown tween %q", action.Tween)
+			}
+		case dialogueActionSetFlag, dialogueActionClearFlag, dialogueActionExpression, dialogueActionEvent:
+		default:
+			return fmt.Errorf("unknown action %q", action.Type)
+		}
+	}
+
+	return nil
+}
+
+// Checks that every jump leads to a node and every action is known
+func (d *JSONDialogue) Validate() error {
+	if _, has := d.Nodes[d.Start]; !has {
+		return fmt.Errorf("start node %q does not exist", d.Start)
+	}
+
+	for id, node := range d.Nodes {
+		if !d.hasNode(node.Next) {
+			return fmt.Errorf("node %s: next node %q does not exist", id, node.Next)
+		}
+
+		for _, line := range node.Lines {
+			if err := validateDialogueActions(line.Actions); err != nil {
+				return fmt.Errorf("node %s: %s", id, err)
+			}
+		}
+
+		for _, choice := range node.Choices {
+			if !d.hasNode(choice.Next) {
+				return fmt.Errorf("node %s: choice leads to missing node %q", id, choice.Next)
+			}
+
+			if err := validateDialogueActions(choice.Actions); err != nil {
+				return fmt.Errorf("node %s: %s", id, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (g *Game) SetFlag(name string, value bool) {
+	if g.storyFlags == nil {
+		g.storyFlags = make(map[string]bool)
+	}
+
+	if value {
+		g.storyFlags[name] = true
+	} else {
+		delete(g.storyFlags, name)
+	}
+}
+
+func (g *Game) HasFlag(name string) bool {
+	return g.storyFlags[name]
+}
+
+// Returns the set story flags in alphabetical order
+func (g *Game) GetFlags() []string {
+	var flags []string
+	for name := range g.storyFlags {
+		flags = append(flags, name)
+	}
+	sort.Strings(flags)
+
+	return flags
+}
+
+// Checks story flag conditions, "!flag" meaning the flag must not be set
+func (g *Game) CheckFlags(conditions []string) bool {
+	for _, condition := range conditions {
+		if strings.HasPrefix(condition, "!") {
+			if g.HasFlag(condition[1:]) {
+				return false
+			}
+		} else if !g.HasFlag(condition) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type DialogueEventHandler func(event string)
+
+// Plays a dialogue script in a dialogue box
+//
+// The host screen calls Update every tick and Advance when the player
+// continues, after picking an answer with the box's MoveChoice, and closes
+// the box once IsFinished.
+type DialogueRunner struct {
+	game       *Game
+	script     *JSONDialogue
+	box        IDialogueBox
+	characters map[string]*VNCharacter
+	onEvent    DialogueEventHandler
+	nodeID     string
+	node       *JSONDialogueNode
+	line       int
+	choices    []*JSONDialogueChoice
+	finished   bool
+}
+
+func NewDialogueRunner(g *Game, script *JSONDialogue, box IDialogueBox) *DialogueRunner {
+	r := new(DialogueRunner)
+	r.game = g
+	r.script = script
+	r.box = box
+	r.characters = make(map[string]*VNCharacter)
+	return r
+}
+
+// Lets expression actions change the VN character
+func (r *DialogueRunner) AddCharacter(name string, char *VNCharacter) {
+	if char != nil {
+		r.characters[name] = char
+	}
+}
+
+func (r *DialogueRunner) SetEventHandler(handler DialogueEventHandler) {
+	r.onEvent = handler
+}
+
+func (r *DialogueRunner) GetNodeID() string {
+	return r.nodeID
+}
+
+func (r *DialogueRunner) IsFinished() bool {
+	return r.finished
+}
+
+func (r *DialogueRunner) IsChoosing() bool {
+	return len(r.choices) > 0
+}
+
+func (r *DialogueRunner) Start() {
+	r.finished = false
+	r.Goto(r.script.Start)
+}
+
+// Jumps to a node and shows its first line, going on through nodes with
+// nothing to show. Ends the dialogue if that leads back to a node already
+// passed, as it would never show anything.
+func (r *DialogueRunner) Goto(id string) {
+	r.choices = nil
+	r.box.SetChoices(nil)
+
+	passed := make(map[string]bool)
+	for {
+		node, has := r.script.Nodes[id]
+		if id == "" || id == dialogueEnd || !has {
+			if !has && id != "" && id != dialogueEnd {
+				log.Println("[Dialogue] Unknown node " + id)
+			}
+
+			r.finished = true
+			return
+		}
+
+		if passed[id] {
+			log.Println("[Dialogue] Node " + id + " leads back to itself without showing anything")
+			r.finished = true
+			return
+		}
+		passed[id] = true
+
+		r.nodeID = id
+		r.node = node
+		r.line = -1
+		if r.showNextLine() {
+			return
+		}
+
+		id = node.Next
+	}
+}
+
+// Types the text, and goes on by itself while fast-forwarding until
+// there is something to choose
+func (r *DialogueRunner) Update() {
+	r.box.Update()
+
+	if r.box.IsFastForward() && !r.finished && !r.IsChoosing() && !r.box.IsTyping() &&
+		r.box.GetIdleTicks() >= dialogueFastForwardDelay {
+		r.Advance()
+	}
+}
+
+// Completes the typed line, shows the next one or picks the selected answer
+func (r *DialogueRunner) Advance() {
+	if r.finished {
+		return
+	}
+
+	if r.box.IsTyping() {
+		r.box.Skip()
+		return
+	}
+
+	if !r.IsChoosing() {
+		r.nextLine()
+		return
+	}
+
+	choice := r.choices[r.box.GetChoice()]
+	r.runActions(choice.Actions)
+	r.Goto(choice.Next)
+}
+
+func (r *DialogueRunner) findLine(from int) int {
+	for i := from; i < len(r.node.Lines); i++ {
+		if r.game.CheckFlags(r.node.Lines[i].If) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (r *DialogueRunner) nextLine() {
+	if !r.showNextLine() {
+		r.Goto(r.node.Next)
+	}
+}
+
+// Shows the next line whose conditions hold, or the choices after the last
+// one. Returns false if the node has nothing more to show.
+func (r *DialogueRunner) showNextLine() bool {
+	next := r.findLine(r.line + 1)
+	if next < 0 {
+		return r.offerChoices()
+	}
+
+	r.line = next
+
+	line := r.node.Lines[r.line]
+	if line.Speaker != "" {
+		r.box.SetSpeaker(I18n(line.Speaker, line.Speaker))
+		r.box.SetVoice(line.Voice)
+	} else if line.Voice > 0 {
+		r.box.SetVoice(line.Voice)
+	}
+	r.box.SetTextSpeed(line.Speed)
+	r.box.SetText(I18n(line.Text, line.Text))
+
+	r.runActions(line.Actions)
+
+	if r.findLine(r.line+1) < 0 {
+		r.offerChoices()
+	}
+
+	return true
+}
+
+// Shows the answers whose conditions hold, if there are any
+func (r *DialogueRunner) offerChoices() bool {
+	if r.IsChoosing() {
+		return true
+	}
+
+	var texts []string
+	for i := range r.node.Choices {
+		if choice := &r.node.Choices[i]; r.game.CheckFlags(choice.If) {
+			r.choices = append(r.choices, choice)
+			texts = append(texts, I18n(choice.Text, choice.Text))
+		}
+	}
+
+	r.box.SetChoices(texts)
+	return r.IsChoosing()
+}
+
+func (r *DialogueRunner) runActions(actions []JSONDialogueAction) {
+	g := r.game
+
+	for _, action := range actions {
+		count := action.Count
+		if count <= 0 {
+			count = 1
+		}
+
+		switch action.Type {
+		case dialogueActionGiveItem:
+			if g.char != nil {
+				if left := g.char.AddItem(action.Item, count); left > 0 {
+					log.Printf("[Dialogue] %d %s did not fit into the inventory", left, action.Item)
+				}
+			}
+
+		case dialogueActionTakeItem:
+			if g.char != nil && !g.char.TakeItem(action.Item, count) {
+				log.Println("[Dialogue] The player has not enough " + action.Item)
+			}
+
+		case dialogueActionSetFlag:
+			g.SetFlag(action.Flag, true)
+
+		case dialogueActionClearFlag:
+			g.SetFlag(action.Flag, false)
+
+		case dialogueActionExpression:
+			group := action.Group
+			if group == "" {
+				group = "facial"
+			}
+
+			char, has := r.characters[action.Character]
+			if action.Preset != "" {
+				if !has || !char.ApplyPreset(action.Preset) {
+					log.Printf("[Dialogue] Can not show preset %s of %s", action.Preset, action.Character)
+				}
+			} else if !has || !char.SetGroupSprite(group, action.Sprite) {
+				log.Printf("[Dialogue] Can not show %s/%s of %s", group, action.Sprite, action.Character)
+			}
+
+		case dialogueActionAnimate:
+			char, has := r.characters[action.Character]
+			if !has || !char.GetAnimator().Play(action.Tween) {
+				log.Printf("[Dialogue] Can not play %s on %s", action.Tween, action.Character)
+			}
+
+		case dialogueActionEvent:
+			if r.onEvent != nil {
+				r.onEvent(action.Event)
+			}
+
+		default:
+			log.Println("[Dialogue] Unknown action " + action.Type)
+		}
+	}
+}
+
+// Distance from the spot in front of the player within which an entity
+// can be talked to
+const talkDistance = tileSize * 0.75
+
+// Returns the entity with a dialogue the player faces, or nil
+func (g *Game) FindTalkTarget() ILivingEntity {
+	if g.char == nil {
+		return nil
+	}
+
+	player := g.char.GetLivingEntity()
+	spot := player.worldPos.Add(lookDirectionVector(player.look).Scale(tileSize))
+
+	g.entityListMutex.RLock()
+	defer g.entityListMutex.RUnlock()
+
+	for _, entity := range g.entities {
+		e := entity.GetLivingEntity()
+		if entity != g.char && e.dialogue != "" && e.worldPos.Subtract(spot).Distance() <= talkDistance {
+			return entity
+		}
+	}
+
+	return nil
+}
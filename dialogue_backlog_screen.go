@@ -0,0 +1,118 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// Row of the backlog as drawn, either a speaker name or a wrapped text line
+type dialogueBacklogRow struct {
+	text    string
+	speaker bool
+}
+
+// Screen to re-read the lines a dialogue box has shown, newest at the bottom
+//
+// Up/Down and Page Up/Page Down scroll, Escape or Tab close it.
+type DialogueBacklogScreen struct {
+	Screen
+	box     IDialogueBox
+	font    *Font
+	scroll  int
+	rows    int
+	onClose func()
+}
+
+func (s *DialogueBacklogScreen) Scroll(rows int) {
+	s.scroll += rows
+	if s.scroll > s.rows-1 {
+		s.scroll = s.rows - 1
+	}
+	if s.scroll < 0 {
+		s.scroll = 0
+	}
+}
+
+func (s *DialogueBacklogScreen) ProcessKeyEvents() bool {
+	switch {
+	case s.game.input.IsActionJustPressed(kbGameMenu) || s.game.input.IsActionJustPressed(kbBacklog):
+		s.onClose()
+
+	case repeatingKeyPressed(ebiten.KeyUp):
+		s.Scroll(1)
+
+	case repeatingKeyPressed(ebiten.KeyDown):
+		s.Scroll(-1)
+
+	case repeatingKeyPressed(ebiten.KeyPageUp):
+		s.Scroll(8)
+
+	case repeatingKeyPressed(ebiten.KeyPageDown):
+		s.Scroll(-8)
+	}
+
+	return false
+}
+
+func (s *DialogueBacklogScreen) Draw(screen *ebiten.Image) {
+	ebitenutil.DrawRect(screen, 0, 0, screenWidth, screenHeight, color.RGBA{0, 0, 0, 208})
+
+	fontRenderer := s.game.fontRenderer
+	fontRenderer.PushState()
+
+	fontRenderer.SetFont(s.font)
+	fontRenderer.SetScale(2.0)
+	fontRenderer.EnableShadow(true)
+
+	lineHeight := fontRenderer.GetGlyphSize().Y + 4
+
+	fontRenderer.SetTextColor(color.RGBA{192, 192, 192, 255})
+	fontRenderer.DrawTextAt(screen, I18n("string_dialogue_backlog", "Backlog"), Vec2f{24, 16})
+
+	var rows []dialogueBacklogRow
+	speaker := ""
+	for _, entry := range s.box.GetBacklog() {
+		if entry.speaker != speaker && entry.speaker != "" {
+			rows = append(rows, dialogueBacklogRow{entry.speaker, true})
+		}
+		speaker = entry.speaker
+
+		for _, line := range fontRenderer.WrapText(entry.text, screenWidth-64) {
+			rows = append(rows, dialogueBacklogRow{line, false})
+		}
+	}
+	s.rows = len(rows)
+
+	visible := int((screenHeight - 32 - lineHeight*2) / lineHeight)
+	end := len(rows) - s.scroll
+	start := end - visible
+	if start < 0 {
+		start = 0
+	}
+
+	pos := Vec2f{32, 16 + lineHeight*2}
+	for _, row := range rows[start:end] {
+		if row.speaker {
+			fontRenderer.SetTextColor(color.RGBA{255, 224, 64, 255})
+		} else {
+			fontRenderer.SetTextColor(color.White)
+		}
+
+		fontRenderer.DrawTextAt(screen, row.text, pos)
+		pos.Y += lineHeight
+	}
+
+	fontRenderer.PopState()
+}
+
+func NewDialogueBacklogScreen(g *Game, box IDialogueBox, onClose func()) *DialogueBacklogScreen {
+	s := new(DialogueBacklogScreen)
+	s.IScreen = s
+	s.game = g
+	s.box = box
+	s.font = ResourceManager_GetInstance().LoadFontJSON("font/font_fantasy.json")
+	s.onClose = onClose
+	return s
+}
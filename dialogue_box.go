@@ -0,0 +1,333 @@
+package main
+
+import (
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+type IDialogueBox interface {
+	Draw(*ebiten.Image)
+	Update()
+	SetSpeaker(string)
+	SetText(string)
+	SetTextSpeed(float64)
+	SetVoice(float64)
+	SetChoices([]string)
+	GetChoice() int
+	MoveChoice(int)
+	IsTyping() bool
+	Skip()
+	SetFastForward(bool)
+	IsFastForward() bool
+	GetIdleTicks() int
+	GetBacklog() []DialogueBacklogEntry
+}
+
+// Height of the dialogue box at the bottom of the screen
+const dialogueBoxHeight = 128.0
+
+const (
+	// Characters revealed per tick at normal speed
+	dialogueTextSpeed = 0.5
+
+	// Speed multiplier while fast-forwarding
+	dialogueFastForward = 8.0
+
+	// Ticks the text stops for after a sentence and after a comma
+	dialogueSentencePause = 12
+	dialogueCommaPause    = 6
+
+	// Voice pitch in Hz of speakers without their own
+	dialogueDefaultVoice = 440.0
+
+	// Lines kept for the backlog
+	dialogueBacklogSize = 200
+
+	// Ticks a complete line stays while fast-forwarding
+	dialogueFastForwardDelay = 4
+)
+
+// Line shown in a dialogue box, kept to be read again in the backlog
+type DialogueBacklogEntry struct {
+	speaker string
+	text    string
+}
+
+// Box at the bottom of the screen showing who speaks, what they say and
+// the answers to pick from
+//
+// Text is typed out character by character with a voice blip each, stopping
+// shortly at punctuation. The answers and the continue indicator appear
+// once the text is complete.
+type DialogueBox struct {
+	IDialogueBox
+	game        *Game
+	name        string
+	text        []rune
+	revealed    int
+	progress    float64
+	pause       int
+	speed       float64
+	voice       float64
+	fastForward bool
+	idleTicks   int
+	choices     []string
+	choice      int
+	backlog     []DialogueBacklogEntry
+	font        *Font
+}
+
+func (dialogueBox *DialogueBox) SetSpeaker(name string) {
+	dialogueBox.name = name
+}
+
+// Starts typing out a new text and records it in the backlog
+func (dialogueBox *DialogueBox) SetText(text string) {
+	dialogueBox.text = []rune(text)
+	dialogueBox.revealed = 0
+	dialogueBox.progress = 0
+	dialogueBox.pause = 0
+	dialogueBox.idleTicks = 0
+
+	if text == "" {
+		return
+	}
+
+	dialogueBox.backlog = append(dialogueBox.backlog, DialogueBacklogEntry{dialogueBox.name, text})
+	if len(dialogueBox.backlog) > dialogueBacklogSize {
+		dialogueBox.backlog = dialogueBox.backlog[len(dialogueBox.backlog)-dialogueBacklogSize:]
+	}
+}
+
+func (dialogueBox *DialogueBox) GetText() string {
+	return string(dialogueBox.text)
+}
+
+// Returns the part of the text typed out so far
+func (dialogueBox *DialogueBox) GetRevealedText() string {
+	return string(dialogueBox.text[:dialogueBox.revealed])
+}
+
+// Sets the characters typed per tick, the default speed for 0
+func (dialogueBox *DialogueBox) SetTextSpeed(speed float64) {
+	dialogueBox.speed = speed
+}
+
+// Sets the blip pitch in Hz, the default voice for 0
+func (dialogueBox *DialogueBox) SetVoice(pitch float64) {
+	dialogueBox.voice = pitch
+}
+
+// Replaces the answers and selects the first one
+func (dialogueBox *DialogueBox) SetChoices(choices []string) {
+	dialogueBox.choices = choices
+	dialogueBox.choice = 0
+}
+
+func (dialogueBox *DialogueBox) GetChoice() int {
+	return dialogueBox.choice
+}
+
+// Selects another answer, wrapping around
+func (dialogueBox *DialogueBox) MoveChoice(step int) {
+	if count := len(dialogueBox.choices); count > 0 && !dialogueBox.IsTyping() {
+		dialogueBox.choice = (dialogueBox.choice + step%count + count) % count
+	}
+}
+
+func (dialogueBox *DialogueBox) IsTyping() bool {
+	return dialogueBox.revealed < len(dialogueBox.text)
+}
+
+// Shows the whole text at once
+func (dialogueBox *DialogueBox) Skip() {
+	dialogueBox.revealed = len(dialogueBox.text)
+	dialogueBox.pause = 0
+}
+
+func (dialogueBox *DialogueBox) SetFastForward(fastForward bool) {
+	dialogueBox.fastForward = fastForward
+}
+
+func (dialogueBox *DialogueBox) IsFastForward() bool {
+	return dialogueBox.fastForward
+}
+
+// Returns for how many ticks the text has been complete
+func (dialogueBox *DialogueBox) GetIdleTicks() int {
+	return dialogueBox.idleTicks
+}
+
+func (dialogueBox *DialogueBox) GetBacklog() []DialogueBacklogEntry {
+	return dialogueBox.backlog
+}
+
+// Ticks to wait after typing the character
+func dialoguePauseAfter(ch rune) int {
+	switch ch {
+	case '.', '!', '?':
+		return dialogueSentencePause
+	case ',', ';', ':':
+		return dialogueCommaPause
+	}
+
+	return 0
+}
+
+func (dialogueBox *DialogueBox) playBlip() {
+	game := dialogueBox.game
+	if game == nil || game.audioManager == nil {
+		return
+	}
+
+	pitch := dialogueBox.voice
+	if pitch <= 0 {
+		pitch = dialogueDefaultVoice
+	}
+
+	game.audioManager.PlayBlip(pitch)
+}
+
+// Types out the text, called once per tick
+func (dialogueBox *DialogueBox) Update() {
+	if !dialogueBox.IsTyping() {
+		dialogueBox.idleTicks++
+		return
+	}
+
+	speed := dialogueBox.speed
+	if speed <= 0 {
+		speed = dialogueTextSpeed
+	}
+
+	if dialogueBox.fastForward {
+		speed *= dialogueFastForward
+		dialogueBox.pause = 0
+	}
+
+	if dialogueBox.pause > 0 {
+		dialogueBox.pause--
+		return
+	}
+
+	dialogueBox.progress += speed
+	blip := false
+
+	for dialogueBox.progress >= 1 && dialogueBox.IsTyping() {
+		dialogueBox.progress--
+
+		ch := dialogueBox.text[dialogueBox.revealed]
+		dialogueBox.revealed++
+
+		if ch != ' ' {
+			blip = true
+		}
+
+		if pause := dialoguePauseAfter(ch); pause > 0 && dialogueBox.IsTyping() && !dialogueBox.fastForward {
+			dialogueBox.pause = pause
+			dialogueBox.progress = 0
+			break
+		}
+	}
+
+	// One blip per tick, however many characters were typed
+	if blip {
+		dialogueBox.playBlip()
+	}
+}
+
+// Draws a small arrow that blinks while the box waits for the player
+func (dialogueBox *DialogueBox) drawContinueIndicator(screen *ebiten.Image) {
+	if (dialogueBox.idleTicks/20)%2 != 0 {
+		return
+	}
+
+	x := screenWidth - 32.0
+	y := screenHeight - 28.0
+	for row := 0.0; row < 4; row++ {
+		ebitenutil.DrawRect(screen, x+row*2, y+row*2, 14-row*4, 2, color.White)
+	}
+}
+
+func (dialogueBox *DialogueBox) Draw(screen *ebiten.Image) {
+	game := dialogueBox.game
+	top := screenHeight - dialogueBoxHeight
+	game.DrawHerbGUIFrame(screen, 0, top, screenWidth, dialogueBoxHeight)
+
+	fontRenderer := game.fontRenderer
+	fontRenderer.PushState()
+
+	fontRenderer.SetFont(dialogueBox.font)
+	fontRenderer.SetScale(2.0)
+	fontRenderer.EnableShadow(true)
+
+	lineHeight := fontRenderer.GetGlyphSize().Y + 4
+	pos := Vec2f{24.0, top + 16.0}
+
+	fontRenderer.SetTextColor(color.RGBA{255, 224, 64, 255})
+	fontRenderer.DrawTextAt(screen, dialogueBox.name, pos)
+	pos.Y += lineHeight
+
+	// Wrapping the whole text keeps words from jumping to the next line
+	// while they are typed
+	remaining := dialogueBox.revealed
+	fontRenderer.SetTextColor(color.White)
+	for _, line := range fontRenderer.WrapText(string(dialogueBox.text), screenWidth-48) {
+		if remaining <= 0 {
+			break
+		}
+
+		runes := []rune(line)
+		if len(runes) > remaining {
+			runes = runes[:remaining]
+		}
+
+		fontRenderer.DrawTextAt(screen, strings.TrimRight(string(runes), " "), pos)
+		pos.Y += lineHeight
+
+		// The space or line feed the line was broken at
+		remaining -= len([]rune(line)) + 1
+	}
+
+	if dialogueBox.IsTyping() {
+		fontRenderer.PopState()
+		return
+	}
+
+	choiceY := screenHeight - 16.0 - lineHeight
+	for i, choice := range dialogueBox.choices {
+		if i == dialogueBox.choice {
+			fontRenderer.SetTextColor(color.RGBA{0, 255, 0, 255})
+			choice = "> " + choice
+		} else {
+			fontRenderer.SetTextColor(color.RGBA{192, 192, 192, 255})
+			choice = "  " + choice
+		}
+
+		fontRenderer.DrawTextAt(screen, choice, Vec2f{pos.X, choiceY})
+		pos.X += fontRenderer.GetStringDimensions(choice).X + 24
+	}
+
+	fontRenderer.PopState()
+
+	if len(dialogueBox.choices) == 0 {
+		dialogueBox.drawContinueIndicator(screen)
+	}
+}
+
+func InitDialogueBox(dialogueBox *DialogueBox, game *Game) {
+	rm := ResourceManager_GetInstance()
+
+	dialogueBox.game = game
+	dialogueBox.font = rm.LoadFontJSON("font/font_fantasy.json")
+}
+
+func NewDialogueBox(game *Game) *DialogueBox {
+	dialogueBox := new(DialogueBox)
+	InitDialogueBox(dialogueBox, game)
+
+	return dialogueBox
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Overlay playing a dialogue script over the world, e.g. when the player
+// talks to an NPC
+//
+// Arrows pick an answer, Enter or the interaction key completes the text or
+// continues, holding Control fast-forwards, Tab opens the backlog and
+// Escape closes the dialogue. The keys are read from the game's input
+// source, since the dialogue actions change the world.
+type DialogueScreen struct {
+	Screen
+	gameplayScreen *GameplayScreen
+	dialogueBox    *DialogueBox
+	runner         *DialogueRunner
+	backlogOpen    bool
+}
+
+func (s *DialogueScreen) GetRunner() *DialogueRunner {
+	return s.runner
+}
+
+func (s *DialogueScreen) ProcessKeyEvents() bool {
+	input := s.game.input
+
+	switch {
+	case input.IsActionJustPressed(kbGameMenu):
+		s.gameplayScreen.overlayStack.Pop()
+		return false
+
+	case input.IsActionJustPressed(kbPlayerMoveLeft) || input.IsActionJustPressed(kbPlayerMoveUp):
+		s.dialogueBox.MoveChoice(-1)
+
+	case input.IsActionJustPressed(kbPlayerMoveRight) || input.IsActionJustPressed(kbPlayerMoveDown):
+		s.dialogueBox.MoveChoice(1)
+
+	case input.IsActionJustPressed(kbBacklog):
+		s.OpenBacklog()
+
+	case input.IsActionJustPressed(kbConfirm) || input.IsActionJustPressed(kbInteract):
+		s.runner.Advance()
+	}
+
+	if s.runner.IsFinished() {
+		s.gameplayScreen.overlayStack.Pop()
+	}
+
+	return false
+}
+
+// Shows the lines said so far over the dialogue, which waits meanwhile
+func (s *DialogueScreen) OpenBacklog() {
+	s.backlogOpen = true
+	s.gameplayScreen.overlayStack.Push(NewDialogueBacklogScreen(s.game, s.dialogueBox, func() {
+		s.gameplayScreen.overlayStack.Pop()
+		s.backlogOpen = false
+	}))
+}
+
+func (s *DialogueScreen) Update() {
+	if s.backlogOpen || s.runner.IsFinished() {
+		return
+	}
+
+	s.dialogueBox.SetFastForward(s.game.input.IsActionPressed(kbFastForward))
+	s.runner.Update()
+
+	if s.runner.IsFinished() {
+		s.gameplayScreen.overlayStack.Pop()
+	}
+}
+
+func (s *DialogueScreen) Draw(screen *ebiten.Image) {
+	s.dialogueBox.Draw(screen)
+}
+
+func NewDialogueScreen(s *GameplayScreen, script *JSONDialogue) *DialogueScreen {
+	ds := new(DialogueScreen)
+	ds.IScreen = ds
+	ds.game = s.game
+	ds.gameplayScreen = s
+	ds.dialogueBox = NewDialogueBox(s.game)
+	ds.runner = NewDialogueRunner(s.game, script, ds.dialogueBox)
+	ds.runner.Start()
+	return ds
+}
+
+// Loads a dialogue script and plays it over the world
+func (s *GameplayScreen) OpenDialogue(path string) bool {
+	script, err := LoadDialogue(path)
+	if err != nil {
+		log.Println("[Dialogue] Failed to load " + path + ": " + err.Error())
+		return false
+	}
+
+	ds := NewDialogueScreen(s, script)
+	if !ds.runner.IsFinished() {
+		s.overlayStack.Push(ds)
+	}
+
+	return true
+}
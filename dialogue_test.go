@@ -0,0 +1,265 @@
+package main
+
+import "testing"
+
+var testDialogue = &JSONDialogue{
+	Start: "hello",
+	Nodes: map[string]*JSONDialogueNode{
+		"hello": {
+			Lines: []JSONDialogueLine{
+				{Speaker: "Morshu", Text: "First time?", If: []string{"!met"}, Actions: []JSONDialogueAction{
+					{Type: dialogueActionSetFlag, Flag: "met"},
+					{Type: dialogueActionGiveItem, Item: "aid", Count: 2},
+				}},
+				{Text: "Welcome."},
+			},
+			Choices: []JSONDialogueChoice{
+				{Text: "Buy", Actions: []JSONDialogueAction{{Type: dialogueActionEvent, Event: "buy"}}},
+				{Text: "Secret", Next: "secret", If: []string{"vip"}},
+				{Text: "Bye", Next: "bye"},
+			},
+		},
+		"secret": {
+			Lines: []JSONDialogueLine{{Text: "Psst."}},
+		},
+		"bye": {
+			Lines: []JSONDialogueLine{{Text: "Come back.", Actions: []JSONDialogueAction{{Type: dialogueActionTakeItem, Item: "aid"}}}},
+		},
+	},
+}
+
+func TestDialogueFlagsAndChoices(t *testing.T) {
+	sim, _ := newTestSimulation(t, newTestLevel())
+	g := sim.GetGame()
+	box := new(DialogueBox)
+
+	r := NewDialogueRunner(g, testDialogue, box)
+	r.Start()
+
+	if box.name != "Morshu" || box.GetText() != "First time?" || r.IsChoosing() {
+		t.Fatalf("first line = %s: %q, choosing %t", box.name, box.GetText(), r.IsChoosing())
+	}
+
+	if !g.HasFlag("met") || g.char.GetItemCount("aid") != 2 {
+		t.Fatal("first line actions did not run")
+	}
+
+	r.Advance()
+	if box.GetRevealedText() != "First time?" || r.GetNodeID() != "hello" {
+		t.Fatalf("advancing while typing showed %q", box.GetRevealedText())
+	}
+
+	r.Advance()
+
+	if box.name != "Morshu" || box.GetText() != "Welcome." || len(box.choices) != 2 {
+		t.Fatalf("last line = %s: %q with choices %v", box.name, box.GetText(), box.choices)
+	}
+
+	box.Skip()
+	box.MoveChoice(1)
+	r.Advance()
+
+	if r.GetNodeID() != "bye" || g.char.GetItemCount("aid") != 1 {
+		t.Fatalf("node %s with %d aid after choosing bye", r.GetNodeID(), g.char.GetItemCount("aid"))
+	}
+
+	box.Skip()
+	r.Advance()
+
+	if !r.IsFinished() {
+		t.Fatal("dialogue did not end after the last node")
+	}
+
+	// Met before, so the greeting is skipped
+	g.SetFlag("vip", true)
+	r.Start()
+
+	if box.GetText() != "Welcome." || len(box.choices) != 3 {
+		t.Fatalf("second visit starts with %q and choices %v", box.GetText(), box.choices)
+	}
+}
+
+func TestDialogueEvents(t *testing.T) {
+	sim, _ := newTestSimulation(t, newTestLevel())
+	g := sim.GetGame()
+	g.SetFlag("met", true)
+
+	var events []string
+	box := new(DialogueBox)
+	r := NewDialogueRunner(g, testDialogue, box)
+	r.SetEventHandler(func(event string) {
+		events = append(events, event)
+	})
+
+	r.Start()
+	box.Skip()
+	r.Advance()
+
+	if len(events) != 1 || events[0] != "buy" || !r.IsFinished() {
+		t.Fatalf("events = %v, finished %t", events, r.IsFinished())
+	}
+}
+
+func TestDialogueScreenUsesInput(t *testing.T) {
+	sim, input := newTestSimulation(t, newTestLevel())
+	g := sim.GetGame()
+	stack := &sim.screen.overlayStack
+
+	ds := NewDialogueScreen(sim.screen, testDialogue)
+	stack.Push(ds)
+
+	input.Press(0, kbConfirm)
+	input.Press(1, kbConfirm)
+	input.Press(2, kbConfirm)
+	input.Press(3, kbPlayerMoveDown)
+	input.Press(4, kbConfirm)
+	sim.Step(5)
+
+	if ds.GetRunner().GetNodeID() != "bye" || g.char.GetItemCount("aid") != 1 {
+		t.Fatalf("node %s with %d aid after choosing bye", ds.GetRunner().GetNodeID(), g.char.GetItemCount("aid"))
+	}
+
+	input.Press(5, kbBacklog)
+	sim.Step(1)
+	if stack.Size != 2 {
+		t.Fatal("backlog did not open")
+	}
+
+	input.Press(6, kbGameMenu)
+	input.Press(7, kbGameMenu)
+	sim.Step(2)
+	if stack.Size != 0 {
+		t.Fatalf("%d overlays left after closing the backlog and the dialogue", stack.Size)
+	}
+}
+
+func TestDialogueBoxTyping(t *testing.T) {
+	box := new(DialogueBox)
+	box.SetTextSpeed(1)
+	box.SetText("Hi, you.")
+
+	box.Update()
+	box.Update()
+	box.Update()
+	if box.GetRevealedText() != "Hi," {
+		t.Fatalf("typed %q, want to stop at the comma", box.GetRevealedText())
+	}
+
+	for i := 0; i < dialogueCommaPause; i++ {
+		box.Update()
+	}
+	if box.GetRevealedText() != "Hi," {
+		t.Fatalf("typed %q during the comma pause", box.GetRevealedText())
+	}
+
+	box.Update()
+	if box.GetRevealedText() != "Hi, " {
+		t.Fatalf("typed %q after the comma pause", box.GetRevealedText())
+	}
+
+	box.SetFastForward(true)
+	box.Update()
+	if box.IsTyping() {
+		t.Fatalf("fast-forward typed only %q", box.GetRevealedText())
+	}
+
+	for i := 0; i < dialogueBacklogSize+5; i++ {
+		box.SetText("Line")
+	}
+	if backlog := box.GetBacklog(); len(backlog) != dialogueBacklogSize || backlog[0].text != "Line" {
+		t.Fatalf("backlog holds %d lines", len(backlog))
+	}
+}
+
+func TestDialogueFastForward(t *testing.T) {
+	sim, _ := newTestSimulation(t, newTestLevel())
+	g := sim.GetGame()
+
+	box := new(DialogueBox)
+	r := NewDialogueRunner(g, testDialogue, box)
+	r.Start()
+	box.SetFastForward(true)
+
+	for i := 0; i < 100; i++ {
+		r.Update()
+	}
+
+	if box.GetText() != "Welcome." || !r.IsChoosing() || r.IsFinished() || box.IsTyping() {
+		t.Fatalf("fast-forward stopped at %q, finished %t", box.GetText(), r.IsFinished())
+	}
+}
+
+func TestDialogueEndsOnSilentCycle(t *testing.T) {
+	sim, _ := newTestSimulation(t, newTestLevel())
+	g := sim.GetGame()
+	box := new(DialogueBox)
+
+	script := &JSONDialogue{
+		Start: "loop",
+		Nodes: map[string]*JSONDialogueNode{
+			"loop":  {Lines: []JSONDialogueLine{{Text: "Hidden.", If: []string{"never"}}}, Next: "again"},
+			"again": {Next: "loop"},
+		},
+	}
+	if err := script.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewDialogueRunner(g, script, box)
+	r.Start()
+
+	if !r.IsFinished() || box.GetText() != "" {
+		t.Fatalf("silent cycle showed %q, finished %t", box.GetText(), r.IsFinished())
+	}
+}
+
+func TestDialogueValidate(t *testing.T) {
+	if err := testDialogue.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	broken := &JSONDialogue{
+		Start: "a",
+		Nodes: map[string]*JSONDialogueNode{
+			"a": {Choices: []JSONDialogueChoice{{Text: "?", Next: "missing"}}},
+		},
+	}
+
+	if broken.Validate() == nil {
+		t.Fatal("choice leading to a missing node passed validation")
+	}
+
+	if _, err := LoadDialogue("assets/shop/morshu/dialogue.json"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShippedDialoguesLoad(t *testing.T) {
+	for _, path := range []string{"assets/shop/morshu/dialogue.json", "assets/dialogue/michael.json"} {
+		if _, err := LoadDialogue(path); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+}
+
+func TestTalkToNPC(t *testing.T) {
+	level := newTestLevel()
+	level.entityDefs.Entities = []JSONEntitySpawn{
+		{Class: "michael", X: 3, Y: 3, Dialogue: "assets/shop/morshu/dialogue.json"},
+	}
+
+	sim, _ := newTestSimulation(t, level)
+	g := sim.GetGame()
+	g.entities = append(g.entities, g.CreateLevelEntities(level)...)
+	sim.Spawn(g.char, 2, 3)
+
+	g.char.GetLivingEntity().look = LooksLeft
+	if g.FindTalkTarget() != nil {
+		t.Fatal("talked to an entity behind the player")
+	}
+
+	g.char.GetLivingEntity().look = LooksRight
+	if npc := g.FindTalkTarget(); npc == nil || EntityClassKey(npc) != "michael" {
+		t.Fatal("NPC in front of the player can not be talked to")
+	}
+}
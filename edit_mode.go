@@ -6,7 +6,6 @@ import (
 	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
 type EditMode struct {
@@ -28,24 +27,24 @@ func (m *EditMode) Draw(screen *ebiten.Image) {
 		op.GeoM.Translate(-2, -2)
 		op.GeoM.Scale(cameraZoom, cameraZoom)
 		op.GeoM.Translate(pos.X, pos.Y)
-		screen.DrawImage(tileCursor, op)
+		DrawImage(screen, tileCursor, op)
 
 		if !m.swapSampleView {
 			tile := GetTileSprite(tilesImage, tileXNum, tileSize, m.brushTile)
 			op.GeoM.Translate(2*cameraZoom, 2*cameraZoom)
 			op.ColorM.Scale(1.0, 1.0, 1.0, 0.8+math.Sin(float64(tickCounter)/2.0)*0.2)
-			screen.DrawImage(tile, op)
+			DrawImage(screen, tile, op)
 		} else {
 			tilePos := m.cursor.y*g.level.width + m.cursor.x
-			selTile := g.level.tileLayers[m.selLayer][tilePos]
+			selTile := g.level.GetTile(m.selLayer, tilePos)
 
 			tile := GetTileSprite(tilesImage, tileXNum, tileSize, 63)
 
 			op.GeoM.Translate(2*cameraZoom, 2*cameraZoom)
-			screen.DrawImage(tile, op)
+			DrawImage(screen, tile, op)
 
 			tile = GetTileSprite(tilesImage, tileXNum, tileSize, selTile)
-			screen.DrawImage(tile, op)
+			DrawImage(screen, tile, op)
 
 			fontRenderer := g.fontRenderer
 
@@ -82,7 +81,7 @@ func (m *EditMode) Draw(screen *ebiten.Image) {
 
 		currentOp.GeoM.Scale(previewTileScale*g.view.guiScale, previewTileScale*g.view.guiScale)
 		currentOp.GeoM.Translate(screenPos.X, screenPos.Y)
-		screen.DrawImage(tile, currentOp)
+		DrawImage(screen, tile, currentOp)
 
 		guiPos = guiPos.Translate(previewTileGUISize.ScaleVec2f(Vec2f{1.0, 0.0}))
 		guiPos = guiPos.Translate(Vec2f{margin, margin / 2})
@@ -164,6 +163,28 @@ func (m *EditMode) Draw(screen *ebiten.Image) {
 			return fmt.Sprintf("ID: %d", tile), TextFormat{scale: 0.5, textColor: color.Black, shadow: false}
 		})
 
+		if link := g.worldGraph.GetLink(g.level.fileName, m.cursor.x, m.cursor.y); link != nil && IsLinkTile(tile) {
+			stringProviders = append(stringProviders, func() (string, TextFormat) {
+				return fmt.Sprintf("-> %s (%d, %d)", link.Target, link.SpawnX, link.SpawnY),
+					TextFormat{scale: 0.5, textColor: color.RGBA{0, 0, 255, 255}, shadow: false}
+			})
+		}
+
+		if wire := g.level.signals.GetEmitter(m.cursor.x, m.cursor.y); wire != nil && isEmitterTile(tile) {
+			stringProviders = append(stringProviders, func() (string, TextFormat) {
+				return fmt.Sprintf("%s %d", I18n("string_signal_channel", "Channel"), wire.Channel),
+					TextFormat{scale: 0.5, textColor: color.RGBA{0, 128, 0, 255}, shadow: false}
+			})
+		} else if wire := g.level.signals.GetReceiver(m.cursor.x, m.cursor.y); wire != nil && isReceiverTile(tile) {
+			stringProviders = append(stringProviders, func() (string, TextFormat) {
+				text := fmt.Sprintf("%s %d", I18n("string_signal_channel", "Channel"), wire.Channel)
+				if wire.Invert {
+					text += " (" + I18n("string_signal_inverted", "inverted") + ")"
+				}
+				return text, TextFormat{scale: 0.5, textColor: color.RGBA{0, 128, 0, 255}, shadow: false}
+			})
+		}
+
 		DrawTooltip(tile, title, stringProviders)
 	}
 
@@ -182,7 +203,7 @@ func (m *EditMode) Draw(screen *ebiten.Image) {
 	// Draw current layer tile
 	if !m.swapSampleView {
 		tilePos := m.cursor.y*g.level.width + m.cursor.x
-		selTile := g.level.tileLayers[m.selLayer][tilePos]
+		selTile := g.level.GetTile(m.selLayer, tilePos)
 
 		DrawTileInfoTooltip(selTile, fmt.Sprintf("%s: %d", I18n("string_layer", "Layer"), m.selLayer))
 	} else {
@@ -199,51 +220,53 @@ func NewEditMode(g *Game) *EditMode {
 }
 
 func (m *EditMode) ProcessKeyEvents() {
-	if inpututil.IsKeyJustPressed(ebiten.KeyRight) {
+	input := m.game.input
+
+	if input.IsActionJustPressed(kbPlayerMoveRight) {
 		m.cursor.x = int(math.Min(float64(m.game.level.width-1), float64(m.cursor.x+1)))
 	}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyLeft) {
+	if input.IsActionJustPressed(kbPlayerMoveLeft) {
 		m.cursor.x = int(math.Max(0, float64(m.cursor.x-1)))
 	}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyUp) {
+	if input.IsActionJustPressed(kbPlayerMoveUp) {
 		m.cursor.y = int(math.Max(0, float64(m.cursor.y-1)))
 	}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+	if input.IsActionJustPressed(kbPlayerMoveDown) {
 		m.cursor.y = int(math.Min(float64(m.game.level.height-1), float64(m.cursor.y+1)))
 	}
 
-	if inpututil.IsKeyJustPressed(keyBinds[kbEditorPlace]) {
+	if input.IsActionJustPressed(kbEditorPlace) {
 		tilePos := m.cursor.y*m.game.level.width + m.cursor.x
 
-		m.game.level.tileLayers[m.selLayer][tilePos] = m.brushTile
+		m.game.level.SetTile(m.selLayer, tilePos, m.brushTile)
 	}
 
-	if inpututil.IsKeyJustPressed(keyBinds[kbEditorDelete]) {
+	if input.IsActionJustPressed(kbEditorDelete) {
 		tilePos := m.cursor.y*m.game.level.width + m.cursor.x
 
-		m.game.level.tileLayers[m.selLayer][tilePos] = tileIDEmpty
+		m.game.level.SetTile(m.selLayer, tilePos, tileIDEmpty)
 	}
 
-	if inpututil.IsKeyJustPressed(keyBinds[kbEditorPrevBrush]) {
+	if input.IsActionJustPressed(kbEditorPrevBrush) {
 		m.brushTile--
 	}
 
-	if inpututil.IsKeyJustPressed(keyBinds[kbEditorNextBrush]) {
+	if input.IsActionJustPressed(kbEditorNextBrush) {
 		m.brushTile++
 	}
 
-	if inpututil.IsKeyJustPressed(keyBinds[kbEditorSwitchMode]) {
+	if input.IsActionJustPressed(kbEditorSwitchMode) {
 		m.swapSampleView = !m.swapSampleView
 	}
 
-	if inpututil.IsKeyJustPressed(keyBinds[kbEditorPrevLayer]) {
+	if input.IsActionJustPressed(kbEditorPrevLayer) {
 		m.selLayer = int(math.Max(0, float64(m.selLayer-1)))
 	}
 
-	if inpututil.IsKeyJustPressed(keyBinds[kbEditorNextLayer]) {
-		m.selLayer = int(math.Min(float64(len(m.game.level.tileLayers)-1), float64(m.selLayer+1)))
+	if input.IsActionJustPressed(kbEditorNextLayer) {
+		m.selLayer = int(math.Min(float64(m.game.level.GetLayerCount()-1), float64(m.selLayer+1)))
 	}
 }
@@ -2,7 +2,6 @@ package main
 
 import (
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
 type GameplayModeEdit struct {
@@ -13,7 +12,17 @@ type GameplayModeEdit struct {
 func (mode *GameplayModeEdit) ProcessKeyEvents() bool {
 	mode.editMode.ProcessKeyEvents()
 
-	if inpututil.IsKeyJustPressed(keyBinds[kbToggleEditMode]) {
+	if mode.gameplayScreen.game.input.IsActionJustPressed(kbEditorEditLink) {
+		mode.EditLink()
+		return false
+	}
+
+	if mode.gameplayScreen.game.input.IsActionJustPressed(kbEditorEditSignal) {
+		mode.EditSignal()
+		return false
+	}
+
+	if mode.gameplayScreen.game.input.IsActionJustPressed(kbToggleEditMode) {
 		mode.gameplayScreen.SetGameplayMode(NewGameplayModeDefault(mode.gameplayScreen))
 		return false
 	}
@@ -21,6 +30,31 @@ func (mode *GameplayModeEdit) ProcessKeyEvents() bool {
 	return true
 }
 
+// Opens the destination dialog if the cursor is on a door, elevator or pit
+func (mode *GameplayModeEdit) EditLink() {
+	g := mode.gameplayScreen.game
+	cursor := mode.editMode.cursor
+	tilePos := cursor.y*g.level.width + cursor.x
+
+	for i := 0; i < g.level.GetLayerCount(); i++ {
+		if IsLinkTile(g.level.GetTile(i, tilePos)) {
+			mode.gameplayScreen.overlayStack.Push(CreateLevelLinkScreen(mode.gameplayScreen, cursor.x, cursor.y))
+			return
+		}
+	}
+}
+
+// Opens the channel dialog if the cursor is on a signal emitter or receiver
+func (mode *GameplayModeEdit) EditSignal() {
+	g := mode.gameplayScreen.game
+	cursor := mode.editMode.cursor
+	tilePos := cursor.y*g.level.width + cursor.x
+
+	if g.level.findSignalTile(tilePos, isEmitterTile) >= 0 || g.level.findSignalTile(tilePos, isReceiverTile) >= 0 {
+		mode.gameplayScreen.overlayStack.Push(CreateSignalWireScreen(mode.gameplayScreen, cursor.x, cursor.y))
+	}
+}
+
 func (mode *GameplayModeEdit) Draw(screen *ebiten.Image) {
 	mode.editMode.Draw(screen)
 }
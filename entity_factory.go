@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type EntityFactory func(g *Game) ILivingEntity
+
+var entityFactories map[string]EntityFactory
+
+// Makes an entity class available to level files, spawners and the console
+func RegisterEntityClass(class string, factory EntityFactory) {
+	if entityFactories == nil {
+		entityFactories = make(map[string]EntityFactory)
+	}
+
+	entityFactories[class] = factory
+}
+
+func init() {
+	RegisterEntityClass("michael", func(g *Game) ILivingEntity { return CreateMichael(g) })
+	RegisterEntityClass("morgen", func(g *Game) ILivingEntity { return CreateMorgen(g) })
+	RegisterEntityClass("flan", func(g *Game) ILivingEntity { return CreateFlan(g) })
+	RegisterEntityClass("monobear", func(g *Game) ILivingEntity { return CreateMonobear(g) })
+}
+
+func IsEntityClassRegistered(class string) bool {
+	_, has := entityFactories[class]
+	return has
+}
+
+// Returns registered class names in alphabetical order
+func GetEntityClassNames() []string {
+	var names []string
+	for name := range entityFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func CreateEntity(g *Game, class string) (ILivingEntity, error) {
+	factory, has := entityFactories[class]
+	if !has {
+		return nil, errors.New("unknown entity class " + class + ", known: " + strings.Join(GetEntityClassNames(), ", "))
+	}
+
+	entity := factory(g)
+	if entity == nil {
+		return nil, errors.New("entity class " + class + " failed to create an entity")
+	}
+
+	entity.GetLivingEntity().classKey = class
+	return entity, nil
+}
+
+// Returns the registry name of the entity's class, "unknown" for entities
+// not created by CreateEntity
+func EntityClassKey(e ILivingEntity) string {
+	if key := e.GetLivingEntity().classKey; key != "" {
+		return key
+	}
+
+	return "unknown"
+}
+
+var lookDirectionNames = map[string]LookDirection{
+	"right": LooksRight,
+	"left":  LooksLeft,
+	"up":    LooksUp,
+	"down":  LooksDown,
+}
+
+// Entity placed in a level file. Position is in tiles.
+//
+// Params override entity properties: "health" and "speed". Dialogue is the
+// path of the dialogue script the player starts by talking to the entity.
+type JSONEntitySpawn struct {
+	Class    string             `json:"class"`
+	X        int                `json:"x"`
+	Y        int                `json:"y"`
+	Look     string             `json:"look,omitempty"`
+	Params   map[string]float64 `json:"params,omitempty"`
+	Dialogue string             `json:"dialogue,omitempty"`
+}
+
+// Entities and regions of a level, stored next to the level file
+type JSONLevelEntities struct {
+	Player     *JSONEntitySpawn  `json:"player,omitempty"`
+	Entities   []JSONEntitySpawn `json:"entities,omitempty"`
+	Spawners   []JSONSpawner     `json:"spawners,omitempty"`
+	Minefields []JSONMinefield   `json:"minefields,omitempty"`
+}
+
+// Returns the entity file path: "level0.lvl" has "level0.entities.json",
+// a world directory has "entities.json" inside
+func LevelEntitiesPath(levelPath string) string {
+	if IsWorldDir(levelPath) {
+		return filepath.Join(levelPath, "entities.json")
+	}
+
+	return strings.TrimSuffix(levelPath, filepath.Ext(levelPath)) + ".entities.json"
+}
+
+// Reads the level's entity file; a level without one has no entities
+func LoadLevelEntities(levelPath string) (*JSONLevelEntities, error) {
+	entities := new(JSONLevelEntities)
+
+	data, err := os.ReadFile(LevelEntitiesPath(levelPath))
+	if os.IsNotExist(err) {
+		return entities, nil
+	} else if err != nil {
+		return entities, err
+	}
+
+	err = json.Unmarshal(data, entities)
+	return entities, err
+}
+
+func SaveLevelEntities(levelPath string, entities *JSONLevelEntities) error {
+	data, err := json.MarshalIndent(entities, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(LevelEntitiesPath(levelPath), data, 0644)
+}
+
+// Moves the entity to the tile center of the level and applies facing and
+// parameters. The level does not have to be the current one.
+func (def *JSONEntitySpawn) Apply(entity ILivingEntity, level *Level) {
+	e := entity.GetLivingEntity()
+	e.worldPos = Vec2f{float64(def.X*tileSize + tileSize/2), float64(def.Y*tileSize + tileSize/2)}
+	e.prevTilePos = def.Y*level.width + def.X
+
+	if def.Look != "" {
+		look, has := lookDirectionNames[def.Look]
+		if !has {
+			log.Println("[Entity] Unknown look direction " + def.Look)
+		}
+		e.look = look
+	}
+
+	if def.Dialogue != "" {
+		e.dialogue = def.Dialogue
+	}
+
+	for name, value := range def.Params {
+		switch name {
+		case "health":
+			e.health = math.Max(0, value)
+		case "speed":
+			e.baseSpeed = value
+		default:
+			log.Println("[Entity] Unknown parameter " + name + " for " + def.Class)
+		}
+	}
+}
+
+// Creates the entity described by the spawn definition
+func (def *JSONEntitySpawn) Create(g *Game, level *Level) (ILivingEntity, error) {
+	entity, err := CreateEntity(g, def.Class)
+	if err != nil {
+		return nil, err
+	}
+
+	def.Apply(entity, level)
+	return entity, nil
+}
+
+// Creates the entities listed in the level file, skipping unknown classes,
+// and turns ball tiles of loaded chunks into balls
+func (g *Game) CreateLevelEntities(level *Level) []ILivingEntity {
+	var entities []ILivingEntity
+
+	for _, chunk := range level.GetLoadedChunks() {
+		entities = append(entities, g.createTileBalls(level, chunk)...)
+	}
+
+	for i := range level.entityDefs.Entities {
+		entity, err := level.entityDefs.Entities[i].Create(g, level)
+		if err != nil {
+			log.Println("[Entity] " + err.Error())
+			continue
+		}
+
+		entities = append(entities, entity)
+	}
+
+	return entities
+}
+
+// Places the player at the level's spawn point, if the level has one
+func (g *Game) PlacePlayer(level *Level) {
+	if g.char != nil && level.entityDefs.Player != nil {
+		level.entityDefs.Player.Apply(g.char, level)
+	}
+}
+
+// Adds the current level's entities to the world and moves the player to
+// its spawn point
+func (g *Game) SpawnLevelEntities() {
+	if g.level == nil {
+		return
+	}
+
+	g.PlacePlayer(g.level)
+
+	entities := g.CreateLevelEntities(g.level)
+
+	g.entityListMutex.Lock()
+	g.entities = append(g.entities, entities...)
+	g.entityListMutex.Unlock()
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLevelEntityFileSpawnsEntities(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "town.lvl")
+	writeTestLevel(t, path, newTestLevel())
+
+	defs := &JSONLevelEntities{
+		Player: &JSONEntitySpawn{X: 3, Y: 4, Look: "up"},
+		Entities: []JSONEntitySpawn{
+			{Class: "flan", X: 6, Y: 6, Look: "left", Params: map[string]float64{"health": 25, "speed": 2}},
+			{Class: "dragon", X: 1, Y: 1},
+			{Class: "monobear", X: 9, Y: 2},
+		},
+	}
+	if err := SaveLevelEntities(path, defs); err != nil {
+		t.Fatalf("SaveLevelEntities: %v", err)
+	}
+
+	sim, _ := newTestSimulation(t, newTestLevel())
+	g := sim.GetGame()
+	g.entities = append(g.entities, g.char)
+
+	if err := g.LoadLevel(path); err != nil {
+		t.Fatalf("LoadLevel: %v", err)
+	}
+	g.SpawnLevelEntities()
+
+	if len(g.entities) != 3 || EntityClassKey(g.entities[1]) != "flan" || EntityClassKey(g.entities[2]) != "monobear" {
+		t.Fatalf("spawned %d entities, want the player, flan and monobear", len(g.entities))
+	}
+
+	flan := g.entities[1].GetLivingEntity()
+	if tilePos, _ := flan.GetTilePos(); tilePos != tileIndex(g.level, 6, 6) || flan.look != LooksLeft || flan.health != 25 || flan.baseSpeed != 2 {
+		t.Fatalf("flan spawned at %d looking %d with %f health", tilePos, flan.look, flan.health)
+	}
+
+	if tilePos, _ := g.char.GetTilePos(); tilePos != tileIndex(g.level, 3, 4) || g.char.GetLivingEntity().look != LooksUp {
+		t.Fatal("player was not moved to the level spawn point")
+	}
+}
+
+func TestEntityClassKeyOfRegisteredClass(t *testing.T) {
+	RegisterEntityClass("twin", func(g *Game) ILivingEntity { return CreateMichael(g) })
+	t.Cleanup(func() {
+		delete(entityFactories, "twin")
+	})
+
+	sim, _ := newTestSimulation(t, newTestLevel())
+	g := sim.GetGame()
+
+	twin, err := CreateEntity(g, "twin")
+	if err != nil {
+		t.Fatalf("CreateEntity: %v", err)
+	}
+
+	if EntityClassKey(twin) != "twin" || EntityClassKey(g.char) != "player" {
+		t.Fatalf("class keys are %s and %s", EntityClassKey(twin), EntityClassKey(g.char))
+	}
+}
+
+func TestDefaultLevelSpawnsMichaels(t *testing.T) {
+	defs, err := LoadLevelEntities(defaultLevelPath)
+	if err != nil {
+		t.Fatalf("LoadLevelEntities: %v", err)
+	}
+
+	if len(defs.Entities) != 8 {
+		t.Fatalf("default level has %d entities, want 8", len(defs.Entities))
+	}
+
+	for _, def := range defs.Entities {
+		michael := CreateMichael(nil)
+		def.Apply(michael, newTestLevel())
+
+		if def.Class != "michael" || michael.worldPos != (Vec2f{CHARACTER_SPAWN_X, CHARACTER_SPAWN_Y}) {
+			t.Fatalf("%s spawns at %v, want a michael at the character spawn", def.Class, michael.worldPos)
+		}
+	}
+}
+
+func TestSaveLevelRemovesLastEntity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "town.lvl")
+
+	level := newTestLevel()
+	level.SetEntityDefs(&JSONLevelEntities{Entities: []JSONEntitySpawn{{Class: "flan", X: 6, Y: 6}}})
+	writeTestLevel(t, path, level)
+
+	level.SetEntityDefs(new(JSONLevelEntities))
+	writeTestLevel(t, path, level)
+
+	defs, err := LoadLevelEntities(path)
+	if err != nil {
+		t.Fatalf("LoadLevelEntities: %v", err)
+	}
+
+	if len(defs.Entities) != 0 {
+		t.Fatalf("%d entities came back after removing the last one", len(defs.Entities))
+	}
+}
+
+func TestSpawnerRespawnsAfterInterval(t *testing.T) {
+	level := newTestLevel()
+	level.SetEntityDefs(&JSONLevelEntities{
+		Spawners: []JSONSpawner{{
+			JSONEntitySpawn: JSONEntitySpawn{Class: "michael", X: 12, Y: 12},
+			Interval:        10,
+			MaxAlive:        1,
+		}},
+	})
+
+	sim, _ := newTestSimulation(t, level)
+	g := sim.GetGame()
+	sim.Spawn(g.char, 2, 2)
+
+	sim.Step(1)
+	if len(g.entities) != 2 {
+		t.Fatalf("entity count = %d, the spawner did not spawn on the first tick", len(g.entities))
+	}
+
+	sim.Step(20)
+	if len(g.entities) != 2 {
+		t.Fatalf("entity count = %d, the spawner went over its limit", len(g.entities))
+	}
+
+	g.entities[1].GetLivingEntity().health = 0
+	sim.Step(5)
+	if len(g.entities) != 1 {
+		t.Fatal("spawner replaced the dead entity before the interval")
+	}
+
+	sim.Step(10)
+	if len(g.entities) != 2 || EntityClassKey(g.entities[1]) != "michael" {
+		t.Fatal("spawner did not replace the dead entity")
+	}
+}
+
+func TestSpawnerCondition(t *testing.T) {
+	level := newTestLevel()
+	level.SetEntityDefs(&JSONLevelEntities{
+		Spawners: []JSONSpawner{{
+			JSONEntitySpawn: JSONEntitySpawn{Class: "monobear", X: 4, Y: 4},
+			Condition:       "player_away",
+			Radius:          5,
+		}},
+	})
+
+	sim, _ := newTestSimulation(t, level)
+	g := sim.GetGame()
+	sim.Spawn(g.char, 5, 5)
+
+	sim.Step(3)
+	if len(g.entities) != 1 {
+		t.Fatal("spawned while the player stands next to the spawn point")
+	}
+
+	g.char.GetLivingEntity().worldPos = tileCenter(13, 13)
+	sim.Step(1)
+	if len(g.entities) != 2 {
+		t.Fatal("did not spawn after the player went away")
+	}
+}
@@ -0,0 +1,500 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Editable entity property shown as a single inspector row
+//
+// Adjust receives -1 or 1, multiplied by 10 while Shift is held.
+type InspectorField struct {
+	name   string
+	print  func(ins *EntityInspector, e *LivingEntity) string
+	adjust func(ins *EntityInspector, e *LivingEntity, delta float64)
+}
+
+var inspectorFields = []InspectorField{
+	{"id", func(ins *EntityInspector, e *LivingEntity) string {
+		return fmt.Sprintf("%d", e.id)
+	}, nil},
+	{"class", func(ins *EntityInspector, e *LivingEntity) string {
+		return EntityClassKey(ins.selected) + " (" + e.entityClass + ")"
+	}, func(ins *EntityInspector, e *LivingEntity, delta float64) {
+		ins.CycleClass(int(delta))
+	}},
+	{"x", func(ins *EntityInspector, e *LivingEntity) string {
+		return fmt.Sprintf("%.1f", e.worldPos.X)
+	}, func(ins *EntityInspector, e *LivingEntity, delta float64) {
+		e.worldPos.X += delta
+	}},
+	{"y", func(ins *EntityInspector, e *LivingEntity) string {
+		return fmt.Sprintf("%.1f", e.worldPos.Y)
+	}, func(ins *EntityInspector, e *LivingEntity, delta float64) {
+		e.worldPos.Y += delta
+	}},
+	{"tile", func(ins *EntityInspector, e *LivingEntity) string {
+		tilePos, _ := e.GetTilePos()
+		return fmt.Sprintf("%d (prev %d)", tilePos, e.prevTilePos)
+	}, nil},
+	{"health", func(ins *EntityInspector, e *LivingEntity) string {
+		return fmt.Sprintf("%.1f", e.health)
+	}, func(ins *EntityInspector, e *LivingEntity, delta float64) {
+		e.health += delta * 5
+		if e.health > 100 {
+			e.health = 100
+		}
+	}},
+	{"speed", func(ins *EntityInspector, e *LivingEntity) string {
+		return fmt.Sprintf("%.2f", e.baseSpeed)
+	}, func(ins *EntityInspector, e *LivingEntity, delta float64) {
+		e.baseSpeed += delta * 0.25
+		if e.baseSpeed < 0 {
+			e.baseSpeed = 0
+		}
+	}},
+	{"look", func(ins *EntityInspector, e *LivingEntity) string {
+		return [...]string{"right", "left", "up", "down"}[e.look]
+	}, func(ins *EntityInspector, e *LivingEntity, delta float64) {
+		e.look = LookDirection((int(e.look) + 4 + int(delta)%4) % 4)
+	}},
+	{"walking", func(ins *EntityInspector, e *LivingEntity) string {
+		return fmt.Sprintf("%t", e.walking)
+	}, func(ins *EntityInspector, e *LivingEntity, delta float64) {
+		e.walking = !e.walking
+	}},
+	{"spells", func(ins *EntityInspector, e *LivingEntity) string {
+		var names []string
+		for _, spell := range e.spells {
+			names = append(names, strings.TrimPrefix(fmt.Sprintf("%T", spell), "*main."))
+		}
+
+		if len(names) == 0 {
+			return "none"
+		}
+
+		return strings.Join(names, ", ")
+	}, func(ins *EntityInspector, e *LivingEntity, delta float64) {
+		if delta > 0 {
+			e.spells = append(e.spells, CreateMonobearExplosion(ins.selected))
+		} else if len(e.spells) > 0 {
+			e.spells = e.spells[:len(e.spells)-1]
+		}
+	}},
+	{"effects", func(ins *EntityInspector, e *LivingEntity) string {
+		return fmt.Sprintf("speed x%.2f", e.speedModifier)
+	}, func(ins *EntityInspector, e *LivingEntity, delta float64) {
+		e.speedModifier += delta * 0.25
+		if e.speedModifier < 0 {
+			e.speedModifier = 0
+		}
+	}},
+}
+
+// Overlay listing the fields of one selected entity and editing them live
+//
+// Left click selects an entity, right click teleports the selection to the
+// clicked spot. Tab cycles entities, Up/Down pick a field, Left/Right edit it
+// (Shift for bigger steps), P pins the camera, K kills, C clones. Selection
+// and edits run as inspect console commands, so recorded sessions replay them.
+type EntityInspector struct {
+	Screen
+	gameplayScreen *GameplayScreen
+	selected       ILivingEntity
+	fieldIndex     int
+	pinned         bool
+}
+
+func (ins *EntityInspector) GetSelected() ILivingEntity {
+	return ins.selected
+}
+
+func (ins *EntityInspector) Select(e ILivingEntity) {
+	ins.selected = e
+
+	if ins.pinned && e != nil {
+		ins.game.camera.TargetEntity(e)
+	}
+}
+
+// Returns the entity inspector open over the gameplay screen, if any
+func (g *Game) GetEntityInspector() *EntityInspector {
+	gs, ok := g.currentScreen.(*GameplayScreen)
+	if !ok {
+		return nil
+	}
+
+	for i := gs.overlayStack.head; i != nil; i = i.next {
+		if ins, ok := i.screen.(*EntityInspector); ok {
+			return ins
+		}
+	}
+
+	return nil
+}
+
+// Returns the entity following the selection in the entity list, nil if
+// there are none
+func (ins *EntityInspector) findNext(step int) ILivingEntity {
+	g := ins.game
+
+	g.entityListMutex.RLock()
+	defer g.entityListMutex.RUnlock()
+
+	if len(g.entities) == 0 {
+		return nil
+	}
+
+	index := 0
+	for i, entity := range g.entities {
+		if entity == ins.selected {
+			index = (i + step + len(g.entities)) % len(g.entities)
+			break
+		}
+	}
+
+	return g.entities[index]
+}
+
+// Selects the entity following the current one in the entity list
+func (ins *EntityInspector) SelectNext(step int) {
+	if next := ins.findNext(step); next != nil {
+		ins.Select(next)
+	} else {
+		ins.selected = nil
+	}
+}
+
+// Returns the front-most entity whose sprite covers the world position
+func (ins *EntityInspector) findAt(pos Vec2f) ILivingEntity {
+	g := ins.game
+
+	g.entityListMutex.RLock()
+	var hit ILivingEntity
+	for _, entity := range g.entities {
+		e := entity.GetLivingEntity()
+		left := e.worldPos.X - e.anchorPos.X
+		top := e.worldPos.Y - e.anchorPos.Y
+
+		if pos.X >= left && pos.X < left+tileSize && pos.Y >= top && pos.Y < top+tileSize {
+			if hit == nil || e.worldPos.Y > hit.GetWorldPos().Y {
+				hit = entity
+			}
+		}
+	}
+	g.entityListMutex.RUnlock()
+
+	return hit
+}
+
+// Selects the front-most entity whose sprite covers the world position
+func (ins *EntityInspector) SelectAt(pos Vec2f) bool {
+	hit := ins.findAt(pos)
+	if hit != nil {
+		ins.Select(hit)
+	}
+
+	return hit != nil
+}
+
+func (ins *EntityInspector) AdjustField(delta float64) {
+	if ins.selected == nil {
+		return
+	}
+
+	field := inspectorFields[ins.fieldIndex]
+	if field.adjust != nil {
+		field.adjust(ins, ins.selected.GetLivingEntity(), delta)
+	}
+}
+
+// Edits the field with the given name, false if it can not be edited
+func (ins *EntityInspector) AdjustNamedField(name string, delta float64) bool {
+	for i, field := range inspectorFields {
+		if field.name == name && field.adjust != nil {
+			ins.fieldIndex = i
+			ins.AdjustField(delta)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ins *EntityInspector) TogglePin() {
+	ins.pinned = !ins.pinned
+
+	if ins.pinned && ins.selected != nil {
+		ins.game.camera.TargetEntity(ins.selected)
+	} else if ins.game.char != nil {
+		ins.game.camera.TargetEntity(ins.game.char)
+	}
+}
+
+// Zeroes the selection's health, so the world removes it on the next step
+func (ins *EntityInspector) Kill() {
+	if ins.selected == nil {
+		return
+	}
+
+	ins.selected.GetLivingEntity().health = 0
+
+	if ins.pinned && ins.game.char != nil {
+		ins.game.camera.TargetEntity(ins.game.char)
+		ins.pinned = false
+	}
+}
+
+// Adds a copy of the selection next to it and selects the copy
+func (ins *EntityInspector) Clone() {
+	if ins.selected == nil {
+		return
+	}
+
+	g := ins.game
+	src := ins.selected.GetLivingEntity()
+
+	clone, err := CreateEntity(g, EntityClassKey(ins.selected))
+	if err != nil {
+		return
+	}
+
+	e := clone.GetLivingEntity()
+	e.worldPos = src.worldPos.Translate(Vec2f{tileSize, 0})
+	e.health = src.health
+	e.baseSpeed = src.baseSpeed
+	e.look = src.look
+	e.prevTilePos, _ = e.GetTilePos()
+
+	g.entityListMutex.Lock()
+	g.entities = append(g.entities, clone)
+	g.entityListMutex.Unlock()
+
+	ins.Select(clone)
+}
+
+// Replaces the selection with an entity of another class in place
+//
+// The player can not change class.
+func (ins *EntityInspector) CycleClass(step int) {
+	if ins.selected == nil || ins.selected == ins.game.char {
+		return
+	}
+
+	classes := GetEntityClassNames()
+
+	current := EntityClassKey(ins.selected)
+	next := classes[0]
+	for i, class := range classes {
+		if class == current {
+			next = classes[(i+step+len(classes))%len(classes)]
+			break
+		}
+	}
+
+	g := ins.game
+	src := ins.selected.GetLivingEntity()
+
+	replacement, err := CreateEntity(g, next)
+	if err != nil {
+		log.Println("[Inspector] " + err.Error())
+		return
+	}
+
+	e := replacement.GetLivingEntity()
+	e.id = src.id
+	e.worldPos = src.worldPos
+	e.prevTilePos = src.prevTilePos
+	e.health = src.health
+	e.look = src.look
+
+	g.entityListMutex.Lock()
+	for i, entity := range g.entities {
+		if entity == ins.selected {
+			g.entities[i] = replacement
+		}
+	}
+	g.entityListMutex.Unlock()
+
+	ins.Select(replacement)
+}
+
+func (ins *EntityInspector) TeleportTo(pos Vec2f) {
+	if ins.selected == nil {
+		return
+	}
+
+	e := ins.selected.GetLivingEntity()
+	e.worldPos = pos
+	e.prevTilePos, _ = e.GetTilePos()
+}
+
+// Queues an edit as an inspect console command for the next tick
+func (ins *EntityInspector) runCommand(args ...string) {
+	ins.game.QueueCommand("inspect " + strings.Join(args, " "))
+}
+
+func (ins *EntityInspector) runSelect(e ILivingEntity) {
+	if e != nil {
+		ins.runCommand("select", strconv.Itoa(e.GetLivingEntity().id))
+	}
+}
+
+func formatInspectorFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func (ins *EntityInspector) ProcessKeyEvents() bool {
+	input := ins.game.input
+
+	step := 1.0
+	if ebiten.IsKeyPressed(ebiten.KeyShift) {
+		step = 10.0
+	}
+
+	field := inspectorFields[ins.fieldIndex].name
+
+	switch {
+	case input.IsActionJustPressed(kbGameMenu) || input.IsActionJustPressed(kbToggleEntityInspector):
+		ins.Close()
+
+	case inpututil.IsKeyJustPressed(ebiten.KeyTab):
+		if ebiten.IsKeyPressed(ebiten.KeyShift) {
+			ins.runSelect(ins.findNext(-1))
+		} else {
+			ins.runSelect(ins.findNext(1))
+		}
+
+	case repeatingKeyPressed(ebiten.KeyUp):
+		ins.fieldIndex = (ins.fieldIndex + len(inspectorFields) - 1) % len(inspectorFields)
+
+	case repeatingKeyPressed(ebiten.KeyDown):
+		ins.fieldIndex = (ins.fieldIndex + 1) % len(inspectorFields)
+
+	case repeatingKeyPressed(ebiten.KeyLeft):
+		ins.runCommand("adjust", field, formatInspectorFloat(-step))
+
+	case repeatingKeyPressed(ebiten.KeyRight):
+		ins.runCommand("adjust", field, formatInspectorFloat(step))
+
+	case inpututil.IsKeyJustPressed(ebiten.KeyP):
+		ins.runCommand("pin")
+
+	case inpututil.IsKeyJustPressed(ebiten.KeyK):
+		ins.runCommand("kill")
+
+	case inpututil.IsKeyJustPressed(ebiten.KeyC):
+		ins.runCommand("clone")
+	}
+
+	curX, curY := ebiten.CursorPosition()
+	cursorWorldPos := ins.game.camera.ScreenToWorld(Vec2f{float64(curX), float64(curY)})
+
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		ins.runSelect(ins.findAt(cursorWorldPos))
+	}
+
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+		ins.runCommand("teleport", formatInspectorFloat(cursorWorldPos.X), formatInspectorFloat(cursorWorldPos.Y))
+	}
+
+	return false
+}
+
+func (ins *EntityInspector) Update() {
+	if ins.selected != nil && ins.selected.GetHealth() <= 0 {
+		ins.SelectNext(1)
+	}
+}
+
+func (ins *EntityInspector) Close() {
+	if ins.pinned {
+		ins.TogglePin()
+	}
+
+	ins.gameplayScreen.overlayStack.Pop()
+}
+
+func (ins *EntityInspector) Draw(screen *ebiten.Image) {
+	g := ins.game
+
+	fontRenderer := g.fontRenderer
+	fontRenderer.PushState()
+	fontRenderer.Reset()
+	fontRenderer.SetScale(g.view.guiScale)
+	fontRenderer.EnableShadow(true)
+
+	lineHeight := fontRenderer.GetGlyphSize().Y + 4
+	panelWidth := 280.0
+	panelX := float64(screenWidth) - panelWidth
+	panelHeight := lineHeight*float64(len(inspectorFields)+3) + 8
+
+	ebitenutil.DrawRect(screen, panelX, 0, panelWidth, panelHeight, color.RGBA{0, 0, 0, 160})
+
+	pos := Vec2f{panelX + 8, 4}
+
+	title := "Inspector"
+	if ins.pinned {
+		title += " [pinned]"
+	}
+	fontRenderer.SetTextColor(color.RGBA{255, 255, 0, 255})
+	fontRenderer.DrawTextAt(screen, title, pos)
+	pos.Y += lineHeight
+
+	if ins.selected == nil {
+		fontRenderer.SetTextColor(color.White)
+		fontRenderer.DrawTextAt(screen, "Click or Tab to select", pos)
+		fontRenderer.PopState()
+		return
+	}
+
+	e := ins.selected.GetLivingEntity()
+
+	for i, field := range inspectorFields {
+		switch {
+		case i == ins.fieldIndex:
+			fontRenderer.SetTextColor(color.RGBA{0, 255, 0, 255})
+		case field.adjust == nil:
+			fontRenderer.SetTextColor(color.RGBA{160, 160, 160, 255})
+		default:
+			fontRenderer.SetTextColor(color.White)
+		}
+
+		fontRenderer.DrawTextAt(screen, field.name+": "+field.print(ins, e), pos)
+		pos.Y += lineHeight
+	}
+
+	fontRenderer.SetTextColor(color.RGBA{160, 160, 160, 255})
+	fontRenderer.DrawTextAt(screen, "P pin  K kill  C clone", Vec2f{pos.X, pos.Y + 4})
+
+	fontRenderer.PopState()
+
+	// Frame around the selected sprite
+	zoom := g.camera.GetZoom()
+	topLeft := g.camera.WorldToScreen2(e.worldPos.Subtract(e.anchorPos))
+	size := tileSize * zoom
+	frameColor := color.RGBA{255, 255, 0, 255}
+
+	ebitenutil.DrawRect(screen, topLeft.X, topLeft.Y, size, 1, frameColor)
+	ebitenutil.DrawRect(screen, topLeft.X, topLeft.Y+size-1, size, 1, frameColor)
+	ebitenutil.DrawRect(screen, topLeft.X, topLeft.Y, 1, size, frameColor)
+	ebitenutil.DrawRect(screen, topLeft.X+size-1, topLeft.Y, 1, size, frameColor)
+}
+
+func NewEntityInspector(s *GameplayScreen) *EntityInspector {
+	ins := new(EntityInspector)
+	ins.IScreen = ins
+	ins.game = s.game
+	ins.gameplayScreen = s
+
+	ins.Select(s.game.char)
+
+	return ins
+}
@@ -0,0 +1,120 @@
+package main
+
+import "testing"
+
+func newTestInspector(t *testing.T) (*HeadlessSimulation, *EntityInspector, ILivingEntity) {
+	t.Helper()
+
+	sim, _ := newTestSimulation(t, newTestLevel())
+	g := sim.GetGame()
+	sim.Spawn(g.char, 2, 2)
+
+	npc, _ := CreateEntity(g, "michael")
+	sim.Spawn(npc, 6, 6)
+
+	return sim, NewEntityInspector(sim.screen), npc
+}
+
+func TestInspectorSelection(t *testing.T) {
+	sim, ins, npc := newTestInspector(t)
+	g := sim.GetGame()
+
+	if ins.GetSelected() != g.char {
+		t.Fatal("inspector does not start on the player")
+	}
+
+	ins.SelectNext(1)
+	if ins.GetSelected() != npc {
+		t.Fatal("Tab did not cycle to the NPC")
+	}
+
+	if !ins.SelectAt(g.char.GetWorldPos().Translate(Vec2f{0, -4})) || ins.GetSelected() != g.char {
+		t.Fatal("clicking the player sprite did not select it")
+	}
+
+	if ins.SelectAt(Vec2f{200, 20}) {
+		t.Fatal("clicking an empty spot selected something")
+	}
+}
+
+func TestInspectorEditsFields(t *testing.T) {
+	sim, ins, npc := newTestInspector(t)
+	g := sim.GetGame()
+	ins.Select(npc)
+
+	for i, field := range inspectorFields {
+		if field.name == "health" {
+			ins.fieldIndex = i
+		}
+	}
+
+	ins.AdjustField(-10)
+	if npc.GetHealth() != 50 {
+		t.Fatalf("health = %f, want 50", npc.GetHealth())
+	}
+
+	ins.CycleClass(1)
+	replaced := ins.GetSelected()
+	if EntityClassKey(replaced) != "monobear" || replaced.GetLivingEntity().id != npc.GetLivingEntity().id {
+		t.Fatalf("class change produced %s with id %d", EntityClassKey(replaced), replaced.GetLivingEntity().id)
+	}
+
+	if len(g.entities) != 2 || g.entities[1] != replaced {
+		t.Fatal("class change did not replace the entity in place")
+	}
+
+	ins.Clone()
+	if len(g.entities) != 3 || EntityClassKey(ins.GetSelected()) != "monobear" {
+		t.Fatal("clone was not added and selected")
+	}
+
+	ins.Kill()
+	sim.Step(1)
+	if len(g.entities) != 2 {
+		t.Fatalf("entity count = %d after kill, want 2", len(g.entities))
+	}
+}
+
+func TestInspectorKeepsEntityWhenClassFails(t *testing.T) {
+	sim, ins, npc := newTestInspector(t)
+	g := sim.GetGame()
+	ins.Select(npc)
+
+	RegisterEntityClass("mimic", func(g *Game) ILivingEntity { return nil })
+	t.Cleanup(func() {
+		delete(entityFactories, "mimic")
+	})
+
+	ins.CycleClass(1)
+
+	if ins.GetSelected() != npc || g.entities[1] != npc {
+		t.Fatal("failed class change replaced the entity")
+	}
+}
+
+func TestInspectorPinsCamera(t *testing.T) {
+	sim, ins, npc := newTestInspector(t)
+	g := sim.GetGame()
+
+	ins.Select(npc)
+	ins.TogglePin()
+	if g.camera.targetEntity != npc {
+		t.Fatal("pinning did not target the camera on the selection")
+	}
+
+	ins.TogglePin()
+	if g.camera.targetEntity != g.char {
+		t.Fatal("unpinning did not return the camera to the player")
+	}
+}
+
+func TestCameraScreenToWorld(t *testing.T) {
+	var c Camera
+	c.SetZoom(4.0)
+	c.currentWorldPos = Vec2f{100, 50}
+
+	world := Vec2f{90, 70}
+	if got := c.ScreenToWorld(c.WorldToScreen2(world)); got != world {
+		t.Fatalf("round trip = %v, want %v", got, world)
+	}
+}
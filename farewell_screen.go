@@ -16,6 +16,7 @@ type FarewellScreen struct {
 
 func (s *FarewellScreen) Update() {
 	if s.game.appTicker-s.startTime > ebiten.MaxTPS()*3 {
+		s.game.CloseInput()
 		os.Exit(0)
 	}
 }
@@ -45,7 +46,7 @@ func (s *FarewellScreen) Draw(screen *ebiten.Image) {
 	op.GeoM.Scale(4.0, 4.0)
 	op.GeoM.Translate(pos.X, pos.Y+100)
 
-	screen.DrawImage(tile, op)
+	DrawImage(screen, tile, op)
 }
 
 func (*FarewellScreen) ProcessKeyEvents() bool {
@@ -9,6 +9,6 @@ func CreateFlan(g *Game) *Flan {
 	e.etype = e
 	e._ConstructLivingEntity(g)
 	e.entityClass = langData["entity_flan"]
-	e.sprite = flanSprite
+	e.SetSprite(flanSprite, "flan")
 	return e
 }
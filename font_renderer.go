@@ -8,6 +8,7 @@ import (
 	_ "image/png"
 	"io/ioutil"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -214,14 +215,16 @@ func (fontRenderer *FontRenderer) _DrawGlyph(screen *ebiten.Image, glyph *ebiten
 
 		fontRenderer.op.ColorM.Reset()
 		ColorM_Colorize(&fontRenderer.op.ColorM, format.shadowColor)
-		screen.DrawImage(glyph, &fontRenderer.op)
+		DrawImage(screen, glyph, &fontRenderer.op)
 
 		fontRenderer.op.GeoM = geomSave
 	}
 
 	fontRenderer.op.ColorM.Reset()
 	ColorM_Colorize(&fontRenderer.op.ColorM, format.textColor)
-	screen.DrawImage(glyph, &fontRenderer.op)
+	DrawImage(screen, glyph, &fontRenderer.op)
+
+	Profiler_GetInstance().CountGlyph()
 }
 
 /*
@@ -352,6 +355,33 @@ func (fontRenderer *FontRenderer) DrawTextFormattedAt(screen *ebiten.Image, text
 	fontRenderer.PopState()
 }
 
+// Splits text into lines no wider than width, breaking between words and
+// at line feeds
+func (fontRenderer *FontRenderer) WrapText(text string, width float64) []string {
+	var lines []string
+
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+
+			if line != "" && fontRenderer.GetStringDimensions(candidate).X > width {
+				lines = append(lines, line)
+				candidate = word
+			}
+
+			line = candidate
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
 func IsWordChar(ch rune) bool {
 	return (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= 'А' && ch <= 'Я') || (ch >= 'а' && ch <= 'я')
 }
@@ -1,10 +1,5 @@
 package main
 
-import (
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
-)
-
 type GameMenu struct {
 	GenericWidgetContainerScreen
 	gameplayScreen *GameplayScreen
@@ -12,7 +7,7 @@ type GameMenu struct {
 
 func (s *GameMenu) ProcessKeyEvents() bool {
 	if s.GenericWidgetContainerScreen.ProcessKeyEvents() {
-		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		if s.game.input.IsActionJustPressed(kbGameMenu) {
 			s.gameplayScreen.overlayStack.Pop()
 		}
 	}
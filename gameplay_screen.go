@@ -4,7 +4,6 @@ import (
 	"sort"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
 type GameplayScreen struct {
@@ -19,7 +18,9 @@ func (s *GameplayScreen) SetGameplayMode(mode IGameplayMode) {
 
 func (s *GameplayScreen) Draw(screen *ebiten.Image) {
 	game := s.game
+	prof := Profiler_GetInstance()
 
+	prof.BeginScope(profScopeWorldDraw)
 	game.DrawWorld(screen)
 
 	var entities []ILivingEntity
@@ -39,20 +40,24 @@ func (s *GameplayScreen) Draw(screen *ebiten.Image) {
 			spell.Draw(screen)
 		}
 	}
+	game.DrawEffects(screen)
+	prof.EndScope(profScopeWorldDraw)
 
-	if game.showDebugInfo {
-		for _, entity := range entities {
-			entity.DrawDebugInfo(screen)
-		}
+	if game.transition != nil {
+		game.transition.Draw(screen)
 	}
 
+	prof.BeginScope(profScopeGUI)
 	s.gameplayMode.Draw(screen)
+	game.DrawSokobanBanner(screen)
+	game.DrawMinefieldBanner(screen)
 
 	s.overlayStack.Draw(screen)
 
 	if game.showDebugInfo {
 		game.debugScreen.Draw(screen)
 	}
+	prof.EndScope(profScopeGUI)
 }
 
 func (s *GameplayScreen) ProcessKeyEvents() bool {
@@ -60,27 +65,37 @@ func (s *GameplayScreen) ProcessKeyEvents() bool {
 
 	if s.gameplayMode.ProcessKeyEvents() {
 
-		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		if game.input.IsActionJustPressed(kbGameMenu) {
 			s.overlayStack.Push(CreateGameMenu(s))
 		}
 
-		if inpututil.IsKeyJustPressed(keyBinds[kbToggleEditMode]) {
+		if game.input.IsActionJustPressed(kbToggleEditMode) {
 			s.SetGameplayMode(NewGameplayModeEdit(s))
 		}
 
-		if inpututil.IsKeyJustPressed(keyBinds[kbToggleEntityFocusRotation]) {
+		if game.input.IsActionJustPressed(kbToggleEntityFocusRotation) {
 			s.SetGameplayMode(NewGameplayModeEntityFocusRotation(s))
 		}
 
-		if inpututil.IsKeyJustPressed(ebiten.KeyF3) {
+		if game.input.IsActionJustPressed(kbShowDebugInfo) {
 			game.ToggleDebugInfoShow()
 		}
+
+		if game.input.IsActionJustPressed(kbToggleEntityInspector) {
+			s.overlayStack.Push(NewEntityInspector(s))
+		}
+
+		if game.input.IsActionJustPressed(kbInventory) && game.char != nil {
+			s.overlayStack.Push(NewInventoryScreen(s))
+		}
 	}
 
 	return true
 }
 
 func (s *GameplayScreen) Update() {
+	s.game.input.Update()
+	s.game.RunQueuedCommands()
 
 	keyOpaque := true
 	for i := s.overlayStack.head; i != nil; i = i.next {
@@ -95,25 +110,7 @@ func (s *GameplayScreen) Update() {
 		i.screen.Update()
 	}
 
-	for _, entity := range s.game.entities {
-		entity.Update()
-	}
-
-	a := &s.game.entities
-	for i := len(*a) - 1; i >= 0; i-- {
-		if (*a)[i].GetHealth() <= 0 {
-			s.game.entityListMutex.Lock()
-
-			(*a)[i] = (*a)[len(*a)-1]
-			(*a)[len(*a)-1] = nil
-			*a = (*a)[:len(*a)-1]
-			s.game.entityListMutex.Unlock()
-		}
-	}
-
-	s.game.camera.Update()
-
-	tickCounter++
+	s.game.UpdateWorld()
 }
 
 func NewGameplayScreen(g *Game) *GameplayScreen {
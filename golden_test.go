@@ -0,0 +1,302 @@
+//go:build gpu
+// +build gpu
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden images instead of comparing against them")
+
+const (
+	goldenDir       = "testdata/golden"
+	goldenFailedDir = "testdata/golden/failed"
+)
+
+// Allowed difference between a rendered frame and its golden image
+type goldenTolerance struct {
+	// Maximum per-channel difference for a pixel to still count as equal
+	channel uint8
+	// Maximum share of unequal pixels in the whole frame
+	ratio float64
+}
+
+var defaultGoldenTolerance = goldenTolerance{channel: 2, ratio: 0.001}
+
+type goldenCase struct {
+	name      string
+	assets    []string
+	ticks     int
+	tolerance goldenTolerance
+	screen    func(*Game) IScreen
+}
+
+func skipWithoutFiles(t *testing.T, paths ...string) {
+	t.Helper()
+
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Skipf("%s is not available: %v", path, err)
+		}
+	}
+}
+
+func newGoldenGame(t *testing.T) *Game {
+	t.Helper()
+
+	tickCounter = 0
+	eidCounter = 0
+
+	g := NewGame(DefaultGameConfig())
+	g.SetSeed(1)
+	g.input = NewScriptedInputSource()
+	g.fontRenderer = NewFontRenderer()
+	g.systemFontRenderer = NewFontRenderer()
+	g.view.guiScale = 2.0
+
+	g.Load()
+
+	return g
+}
+
+// Attaches the screen, advances it by the given number of ticks and renders
+// the result into an offscreen image
+func renderScreenAt(g *Game, screen IScreen, ticks int) *image.RGBA {
+	g.SetScreen(screen)
+
+	for i := 0; i < ticks; i++ {
+		_ = g.Update()
+	}
+
+	offscreen := ebiten.NewImage(screenWidth, screenHeight)
+	g.Draw(offscreen)
+
+	return imageToRGBA(offscreen)
+}
+
+// Reads the pixels of an ebiten image back into memory
+func imageToRGBA(img *ebiten.Image) *image.RGBA {
+	w, h := img.Size()
+
+	rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			rgba.Set(x, y, img.At(x, y))
+		}
+	}
+
+	return rgba
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+
+	return b - a
+}
+
+// Returns the number of mismatching pixels and an image highlighting them
+// in red over a dimmed copy of the expected frame
+func diffImages(got, want *image.RGBA, tolerance goldenTolerance) (int, *image.RGBA) {
+	bounds := want.Bounds()
+	diff := image.NewRGBA(bounds)
+	mismatched := 0
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			g := got.RGBAAt(x, y)
+			w := want.RGBAAt(x, y)
+
+			if absDiff(g.R, w.R) > tolerance.channel ||
+				absDiff(g.G, w.G) > tolerance.channel ||
+				absDiff(g.B, w.B) > tolerance.channel ||
+				absDiff(g.A, w.A) > tolerance.channel {
+				mismatched++
+				diff.SetRGBA(x, y, color.RGBA{255, 0, 0, 255})
+				continue
+			}
+
+			luma := uint8((uint32(w.R)*299 + uint32(w.G)*587 + uint32(w.B)*114) / 1000 / 4)
+			diff.SetRGBA(x, y, color.RGBA{luma, luma, luma, 255})
+		}
+	}
+
+	return mismatched, diff
+}
+
+func readPNG(path string) (*image.RGBA, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	img, err := png.Decode(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	rgba := image.NewRGBA(img.Bounds())
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			rgba.Set(x, y, img.At(x, y))
+		}
+	}
+
+	return rgba, nil
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	fd, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	return png.Encode(fd, img)
+}
+
+// Compares a rendered frame with testdata/golden/<name>.png, failing if
+// there is none
+//
+// With -update the golden image is written instead. On mismatch the
+// rendered frame and a diff image are written to testdata/golden/failed.
+func compareGolden(t *testing.T, name string, got *image.RGBA, tolerance goldenTolerance) {
+	t.Helper()
+
+	goldenPath := filepath.Join(goldenDir, name+".png")
+
+	if *updateGolden {
+		if err := writePNG(goldenPath, got); err != nil {
+			t.Fatalf("writing golden image: %v", err)
+		}
+		return
+	}
+
+	want, err := readPNG(goldenPath)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden image %s is missing, generate it with: go test -tags gpu -run TestGoldenScreens -update", goldenPath)
+	} else if err != nil {
+		t.Fatalf("reading golden image: %v", err)
+	}
+
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("frame size %v, golden size %v", got.Bounds().Size(), want.Bounds().Size())
+	}
+
+	mismatched, diff := diffImages(got, want, tolerance)
+	total := want.Bounds().Dx() * want.Bounds().Dy()
+
+	if float64(mismatched)/float64(total) <= tolerance.ratio {
+		return
+	}
+
+	gotPath := filepath.Join(goldenFailedDir, name+".got.png")
+	diffPath := filepath.Join(goldenFailedDir, name+".diff.png")
+
+	if err := writePNG(gotPath, got); err != nil {
+		t.Errorf("writing rendered frame: %v", err)
+	}
+
+	if err := writePNG(diffPath, diff); err != nil {
+		t.Errorf("writing diff image: %v", err)
+	}
+
+	t.Fatalf("%d of %d pixels differ from %s, see %s", mismatched, total, goldenPath, diffPath)
+}
+
+var goldenCases = []goldenCase{
+	{
+		name:   "branding_fade_in",
+		assets: []string{"assets/aragajaga.png"},
+		ticks:  30,
+		screen: func(g *Game) IScreen { return NewBrandingScreen(g, nil) },
+	},
+	{
+		name:   "branding",
+		assets: []string{"assets/aragajaga.png"},
+		ticks:  90,
+		screen: func(g *Game) IScreen { return NewBrandingScreen(g, nil) },
+	},
+	{
+		name:   "font_test",
+		assets: []string{"font/font_runic.json", "font/font_system.json"},
+		screen: func(g *Game) IScreen { return NewFontTestScreen(g) },
+	},
+	{
+		name:   "winxp_desktop",
+		assets: []string{"assets/computer/wallpaper.png", "assets/computer/taskband.png", "font/font_fantasy.json"},
+		ticks:  1,
+		screen: func(g *Game) IScreen { return NewWinXPScreen(g, nil) },
+	},
+	{
+		name:   "shop",
+		assets: []string{"assets/shop/background.png", "assets/shop/morshu/morshu.json"},
+		ticks:  1,
+		screen: func(g *Game) IScreen { return NewShopScreen(g) },
+	},
+	{
+		name:   "gameplay",
+		assets: []string{defaultLevelPath, "assets/tilemap2.png", "assets/char2.png", "assets/michael.png"},
+		ticks:  120,
+		screen: func(g *Game) IScreen { return NewGameplayScreen(g) },
+	},
+}
+
+func TestGoldenScreens(t *testing.T) {
+	// The taskbar clock would show the wall time
+	xpClockNow = func() time.Time { return time.Date(2006, 1, 2, 15, 4, 0, 0, time.UTC) }
+	defer func() { xpClockNow = time.Now }()
+
+	for _, tc := range goldenCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			skipWithoutFiles(t, tc.assets...)
+
+			tolerance := tc.tolerance
+			if tolerance == (goldenTolerance{}) {
+				tolerance = defaultGoldenTolerance
+			}
+
+			g := newGoldenGame(t)
+			frame := renderScreenAt(g, tc.screen(g), tc.ticks)
+
+			compareGolden(t, fmt.Sprintf("%s_%d", tc.name, tc.ticks), frame, tolerance)
+		})
+	}
+}
+
+func TestDiffImagesHighlightsMismatch(t *testing.T) {
+	want := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	got := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	got.SetRGBA(1, 2, color.RGBA{255, 255, 255, 255})
+	got.SetRGBA(3, 3, color.RGBA{1, 1, 1, 1})
+
+	mismatched, diff := diffImages(got, want, defaultGoldenTolerance)
+
+	if mismatched != 1 {
+		t.Fatalf("mismatched = %d, want 1", mismatched)
+	}
+
+	if c := diff.RGBAAt(1, 2); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Fatalf("diff pixel = %v, want red", c)
+	}
+}
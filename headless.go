@@ -0,0 +1,135 @@
+package main
+
+// Input source driven by a script of actions bound to simulation ticks
+//
+// Used to run the simulation without a keyboard, e.g. in tests.
+type ScriptedInputSource struct {
+	IInputSource
+	pressed  map[int][]KeyBind
+	released map[int][]KeyBind
+	held     map[KeyBind]bool
+	tick     int
+}
+
+func (src *ScriptedInputSource) Press(tick int, kb KeyBind) {
+	src.pressed[tick] = append(src.pressed[tick], kb)
+}
+
+func (src *ScriptedInputSource) Release(tick int, kb KeyBind) {
+	src.released[tick] = append(src.released[tick], kb)
+}
+
+// Presses an action at the given tick and releases it after duration ticks
+func (src *ScriptedInputSource) Hold(tick int, duration int, kb KeyBind) {
+	src.Press(tick, kb)
+	src.Release(tick+duration, kb)
+}
+
+func (src *ScriptedInputSource) Update() {
+	src.tick++
+
+	for _, kb := range src.pressed[src.tick] {
+		src.held[kb] = true
+	}
+
+	for _, kb := range src.released[src.tick] {
+		src.held[kb] = false
+	}
+}
+
+func (src *ScriptedInputSource) IsActionPressed(kb KeyBind) bool {
+	return src.held[kb]
+}
+
+func (src *ScriptedInputSource) IsActionJustPressed(kb KeyBind) bool {
+	for _, pressed := range src.pressed[src.tick] {
+		if pressed == kb {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (src *ScriptedInputSource) IsActionJustReleased(kb KeyBind) bool {
+	for _, released := range src.released[src.tick] {
+		if released == kb {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (src *ScriptedInputSource) RecordCommand(string) {
+
+}
+
+// Returns the tick the next Update call will advance to
+func (src *ScriptedInputSource) NextTick() int {
+	return src.tick + 1
+}
+
+func NewScriptedInputSource() *ScriptedInputSource {
+	src := new(ScriptedInputSource)
+	src.tick = -1
+	src.pressed = make(map[int][]KeyBind)
+	src.released = make(map[int][]KeyBind)
+	src.held = make(map[KeyBind]bool)
+	return src
+}
+
+// Simulation running without a window, renderer or loaded assets
+//
+// Loading this package still initializes GLFW, so a display is needed
+// anyway; see headless_test.go.
+//
+// It steps the same GameplayScreen.Update as the real game, so gameplay
+// modes, tile effects, collision and entity removal behave identically.
+// Entities have no sprites, so nothing may be drawn.
+type HeadlessSimulation struct {
+	game   *Game
+	screen *GameplayScreen
+}
+
+func (sim *HeadlessSimulation) Step(ticks int) {
+	for i := 0; i < ticks; i++ {
+		sim.screen.Update()
+	}
+}
+
+func (sim *HeadlessSimulation) GetGame() *Game {
+	return sim.game
+}
+
+// Places an entity on the center of the given tile and adds it to the world
+func (sim *HeadlessSimulation) Spawn(e ILivingEntity, tileX, tileY int) {
+	le := e.GetLivingEntity()
+	le.worldPos = Vec2f{float64(tileX*tileSize + tileSize/2), float64(tileY*tileSize + tileSize/2)}
+	le.prevTilePos, _ = le.GetTilePos()
+
+	sim.game.entityListMutex.Lock()
+	sim.game.entities = append(sim.game.entities, e)
+	sim.game.entityListMutex.Unlock()
+}
+
+func NewHeadlessSimulation(level *Level, seed int64, input IInputSource) *HeadlessSimulation {
+	sim := new(HeadlessSimulation)
+
+	tickCounter = 0
+	eidCounter = 0
+
+	g := NewGame(DefaultGameConfig())
+	g.SetSeed(seed)
+	g.input = input
+	g.level = level
+	g.camera.SetZoom(4.0)
+
+	g.char = CreateCharacter(g)
+
+	sim.game = g
+	sim.screen = NewGameplayScreen(g)
+	g.currentScreen = sim.screen
+
+	return sim
+}
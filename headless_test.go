@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+// Simulation tests open no window and draw nothing, but they are not
+// display-free: ebiten v2.2's GLFW driver initializes in its package init and
+// panics without a display, before any test runs. Every simulation type in
+// this package holds ebiten images, so there is no way to skip that from
+// here. On Linux without a display, run them under Xvfb:
+//
+//	xvfb-run go test ./...
+
+const (
+	testLevelSize   = 15
+	testFloorLayer  = 0
+	testObjectLayer = 1
+)
+
+func newTestLevel() *Level {
+	level := NewLevel(testLevelSize, testLevelSize, 2)
+
+	for i := 0; i < level.width*level.height; i++ {
+		level.SetTile(testFloorLayer, i, tileIDGrass)
+	}
+
+	return level
+}
+
+func tileIndex(level *Level, x, y int) int {
+	return y*level.width + x
+}
+
+func newTestSimulation(t *testing.T, level *Level) (*HeadlessSimulation, *ScriptedInputSource) {
+	t.Helper()
+
+	input := NewScriptedInputSource()
+	sim := NewHeadlessSimulation(level, 1, input)
+
+	return sim, input
+}
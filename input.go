@@ -0,0 +1,66 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Source of gameplay actions consumed by the simulation
+//
+// Game logic must query actions through the game's input source instead of
+// reading inpututil directly, so a session can be recorded and replayed.
+type IInputSource interface {
+	Update()
+	IsActionPressed(KeyBind) bool
+	IsActionJustPressed(KeyBind) bool
+	IsActionJustReleased(KeyBind) bool
+	// Called with every console command the player runs, so replays can run
+	// it before the same tick
+	RecordCommand(line string)
+}
+
+// Input source reading the live keyboard state through the keybind map
+type LiveInputSource struct {
+	IInputSource
+}
+
+func (src *LiveInputSource) Update() {
+
+}
+
+func (src *LiveInputSource) IsActionPressed(kb KeyBind) bool {
+	return ebiten.IsKeyPressed(keyBinds[kb])
+}
+
+func (src *LiveInputSource) IsActionJustPressed(kb KeyBind) bool {
+	return inpututil.IsKeyJustPressed(keyBinds[kb])
+}
+
+func (src *LiveInputSource) IsActionJustReleased(kb KeyBind) bool {
+	return inpututil.IsKeyJustReleased(keyBinds[kb])
+}
+
+func (src *LiveInputSource) RecordCommand(string) {
+
+}
+
+func NewLiveInputSource() *LiveInputSource {
+	return new(LiveInputSource)
+}
+
+// Returns bound actions in ascending order, so per-tick action lists are stable
+func SortedKeyBinds() []KeyBind {
+	var binds []KeyBind
+
+	for kb := range keyBinds {
+		binds = append(binds, kb)
+	}
+
+	sort.Slice(binds, func(i, j int) bool {
+		return binds[i] < binds[j]
+	})
+
+	return binds
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// Size of an inventory slot in GUI pixels
+const inventorySlotSize = 40.0
+
+// Overlay showing the player inventory as a grid
+//
+// The movement keys move the cursor, the use key uses the selected item,
+// the menu or inventory key closes it.
+type InventoryScreen struct {
+	Screen
+	gameplayScreen *GameplayScreen
+	cursor         int
+}
+
+func (s *InventoryScreen) GetCursor() int {
+	return s.cursor
+}
+
+// Moves the cursor by whole cells, wrapping around the grid edges
+func (s *InventoryScreen) MoveCursor(dx, dy int) {
+	x := (s.cursor%inventoryColumns + dx + inventoryColumns) % inventoryColumns
+	y := (s.cursor/inventoryColumns + dy + inventoryRows) % inventoryRows
+	s.cursor = y*inventoryColumns + x
+}
+
+func (s *InventoryScreen) ProcessKeyEvents() bool {
+	input := s.game.input
+
+	switch {
+	case input.IsActionJustPressed(kbGameMenu) || input.IsActionJustPressed(kbInventory):
+		s.gameplayScreen.overlayStack.Pop()
+
+	case input.IsActionJustPressed(kbPlayerMoveLeft):
+		s.MoveCursor(-1, 0)
+
+	case input.IsActionJustPressed(kbPlayerMoveRight):
+		s.MoveCursor(1, 0)
+
+	case input.IsActionJustPressed(kbPlayerMoveUp):
+		s.MoveCursor(0, -1)
+
+	case input.IsActionJustPressed(kbPlayerMoveDown):
+		s.MoveCursor(0, 1)
+
+	case input.IsActionJustPressed(kbUseItem):
+		s.game.UseItem(s.cursor)
+	}
+
+	return false
+}
+
+func (s *InventoryScreen) Draw(screen *ebiten.Image) {
+	g := s.game
+	if g.char == nil {
+		return
+	}
+
+	inv := g.char.GetInventory()
+	scale := g.view.guiScale
+	slotSize := inventorySlotSize * scale
+	margin := 8 * scale
+
+	fontRenderer := g.fontRenderer
+	fontRenderer.PushState()
+	fontRenderer.Reset()
+	fontRenderer.SetScale(scale)
+	fontRenderer.EnableShadow(true)
+
+	lineHeight := fontRenderer.GetGlyphSize().Y + 4
+	width := slotSize*inventoryColumns + margin*2
+	height := slotSize*inventoryRows + margin*2 + lineHeight*2
+	left := (screenWidth - width) / 2
+	top := (screenHeight - height) / 2
+
+	g.DrawHerbGUIFrame(screen, left, top, width, height)
+
+	fontRenderer.SetTextColor(color.RGBA{255, 255, 0, 255})
+	fontRenderer.DrawTextAt(screen, I18n("string_inventory", "Inventory"), Vec2f{left + margin, top + margin})
+
+	gridTop := top + margin + lineHeight
+	iconScale := (inventorySlotSize - 8) / tileSize * scale
+
+	for i := 0; i < inv.GetSize(); i++ {
+		x := left + margin + float64(i%inventoryColumns)*slotSize
+		y := gridTop + float64(i/inventoryColumns)*slotSize
+
+		slotColor := color.RGBA{0, 0, 0, 128}
+		if i == s.cursor {
+			slotColor = color.RGBA{255, 255, 255, 96}
+		}
+		ebitenutil.DrawRect(screen, x+1, y+1, slotSize-2, slotSize-2, slotColor)
+
+		stack := inv.GetSlot(i)
+		def := stack.GetDef()
+		if stack.IsEmpty() || def == nil {
+			continue
+		}
+
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(iconScale, iconScale)
+		op.GeoM.Translate(x+4*scale, y+4*scale)
+		DrawImage(screen, GetTileSprite(tilesImage, tileXNum, tileSize, def.Icon), op)
+
+		if stack.count > 1 {
+			count := fmt.Sprintf("%d", stack.count)
+			dim := fontRenderer.GetStringDimensions(count)
+			fontRenderer.SetTextColor(color.White)
+			fontRenderer.DrawTextAt(screen, count, Vec2f{x + slotSize - dim.X - 2, y + slotSize - dim.Y - 2})
+		}
+	}
+
+	if def := inv.GetSlot(s.cursor).GetDef(); def != nil && !inv.GetSlot(s.cursor).IsEmpty() {
+		name := def.GetName()
+		if def.Use != nil {
+			name += " - " + I18n("string_inventory_use", "Enter to use")
+		}
+
+		fontRenderer.SetTextColor(color.White)
+		fontRenderer.DrawTextAt(screen, name, Vec2f{left + margin, gridTop + slotSize*inventoryRows + 4})
+	}
+
+	fontRenderer.PopState()
+}
+
+func NewInventoryScreen(s *GameplayScreen) *InventoryScreen {
+	inv := new(InventoryScreen)
+	inv.IScreen = inv
+	inv.game = s.game
+	inv.gameplayScreen = s
+	return inv
+}
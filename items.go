@@ -0,0 +1,265 @@
+package main
+
+import (
+	"log"
+	"math"
+	"sort"
+)
+
+// Slots of the player inventory, laid out as a grid
+const (
+	inventoryColumns = 5
+	inventoryRows    = 4
+)
+
+// Called when the player uses an item; returns whether the item was used up
+type ItemEffect func(g *Game, user ICharacter) bool
+
+// Item kind. The icon is a tile of the tile atlas. An item with Pickup set
+// lies in the world as its icon tile and is picked up by walking onto it.
+type ItemDef struct {
+	ID        string
+	NameKey   string
+	Icon      Tile
+	StackSize int
+	Pickup    bool
+	Use       ItemEffect
+}
+
+func (def *ItemDef) GetName() string {
+	return I18n(def.NameKey, def.ID)
+}
+
+var itemDefs map[string]*ItemDef
+
+func RegisterItem(def *ItemDef) {
+	if itemDefs == nil {
+		itemDefs = make(map[string]*ItemDef)
+	}
+
+	if def.StackSize <= 0 {
+		def.StackSize = 1
+	}
+
+	itemDefs[def.ID] = def
+}
+
+// Returns the item definition, or nil for an unknown item
+func GetItemDef(id string) *ItemDef {
+	return itemDefs[id]
+}
+
+// Returns registered item IDs in alphabetical order
+func GetItemIDs() []string {
+	var ids []string
+	for id := range itemDefs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Returns the item picked up from a world tile, or nil
+func GetPickupItem(tile Tile) *ItemDef {
+	for _, id := range GetItemIDs() {
+		if def := itemDefs[id]; def.Pickup && def.Icon == tile {
+			return def
+		}
+	}
+
+	return nil
+}
+
+// Effect restoring health, up to the maximum of 100
+func HealEffect(amount float64) ItemEffect {
+	return func(g *Game, user ICharacter) bool {
+		e := user.GetLivingEntity()
+		if e.health >= 100 {
+			return false
+		}
+
+		e.health = math.Min(100, e.health+amount)
+		return true
+	}
+}
+
+func init() {
+	RegisterItem(&ItemDef{ID: "aid", NameKey: "item_aid", Icon: tileIDAid, StackSize: 5, Pickup: true, Use: HealEffect(50)})
+	RegisterItem(&ItemDef{ID: "berries", NameKey: "item_berries", Icon: tileIDBerryBush, StackSize: 20, Use: HealEffect(10)})
+	RegisterItem(&ItemDef{ID: "blue_rose", NameKey: "item_blue_rose", Icon: tileIDBlueRose, StackSize: 5})
+	RegisterItem(&ItemDef{ID: flowerSeedsItem, NameKey: "item_flower_seeds", Icon: tileIDFlowerSprout, StackSize: 20})
+	RegisterItem(&ItemDef{ID: "poop", NameKey: "item_poop", Icon: tileIDPoop, StackSize: 10, Pickup: true})
+}
+
+type ItemStack struct {
+	id    string
+	count int
+}
+
+func (s ItemStack) IsEmpty() bool {
+	return s.count <= 0
+}
+
+func (s ItemStack) GetDef() *ItemDef {
+	return GetItemDef(s.id)
+}
+
+// Fixed grid of item stacks
+type Inventory struct {
+	slots []ItemStack
+}
+
+func NewInventory(size int) *Inventory {
+	inv := new(Inventory)
+	inv.slots = make([]ItemStack, size)
+	return inv
+}
+
+func (inv *Inventory) GetSize() int {
+	return len(inv.slots)
+}
+
+func (inv *Inventory) GetSlot(i int) ItemStack {
+	return inv.slots[i]
+}
+
+// Adds items to existing stacks first, then to free slots, and returns
+// how many did not fit
+func (inv *Inventory) Add(id string, count int) int {
+	def := GetItemDef(id)
+	if def == nil {
+		log.Println("[Inventory] Unknown item " + id)
+		return count
+	}
+
+	for i := range inv.slots {
+		if count == 0 {
+			break
+		}
+
+		if slot := &inv.slots[i]; slot.id == id && slot.count < def.StackSize {
+			added := minInt(count, def.StackSize-slot.count)
+			slot.count += added
+			count -= added
+		}
+	}
+
+	for i := range inv.slots {
+		if count == 0 {
+			break
+		}
+
+		if slot := &inv.slots[i]; slot.IsEmpty() {
+			added := minInt(count, def.StackSize)
+			*slot = ItemStack{id, added}
+			count -= added
+		}
+	}
+
+	return count
+}
+
+func (inv *Inventory) Count(id string) int {
+	count := 0
+	for _, slot := range inv.slots {
+		if slot.id == id {
+			count += slot.count
+		}
+	}
+
+	return count
+}
+
+// Removes items, taking from the last stacks first, if there are enough
+func (inv *Inventory) Take(id string, count int) bool {
+	if inv.Count(id) < count {
+		return false
+	}
+
+	for i := len(inv.slots) - 1; i >= 0 && count > 0; i-- {
+		if slot := &inv.slots[i]; slot.id == id {
+			taken := minInt(count, slot.count)
+			slot.count -= taken
+			count -= taken
+
+			if slot.IsEmpty() {
+				*slot = ItemStack{}
+			}
+		}
+	}
+
+	return true
+}
+
+// Returns carried item IDs with their total counts, sorted by ID
+func (inv *Inventory) GetTotals() ([]string, map[string]int) {
+	totals := make(map[string]int)
+	for _, slot := range inv.slots {
+		if !slot.IsEmpty() {
+			totals[slot.id] += slot.count
+		}
+	}
+
+	ids := make([]string, 0, len(totals))
+	for id := range totals {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids, totals
+}
+
+// Uses one item of the player's inventory slot
+func (g *Game) UseItem(slot int) bool {
+	if g.char == nil {
+		return false
+	}
+
+	inv := g.char.GetInventory()
+	stack := inv.GetSlot(slot)
+	def := stack.GetDef()
+
+	if stack.IsEmpty() || def == nil || def.Use == nil {
+		return false
+	}
+
+	if !def.Use(g, g.char) {
+		return false
+	}
+
+	inv.slots[slot].count--
+	if inv.slots[slot].IsEmpty() {
+		inv.slots[slot] = ItemStack{}
+	}
+
+	log.Println("[Inventory] Used " + def.ID)
+	return true
+}
+
+// Picks up item tiles the player stepped on, if they fit
+func (g *Game) PickUpItems(e ILivingEntity, tilePos int) {
+	if e != g.char {
+		return
+	}
+
+	level := g.level
+	for layer := level.GetLayerCount() - 1; layer >= 0; layer-- {
+		def := GetPickupItem(level.GetTile(layer, tilePos))
+		if def == nil {
+			continue
+		}
+
+		if g.char.AddItem(def.ID, 1) == 0 {
+			level.SetTile(layer, tilePos, tileIDEmpty)
+		}
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,112 @@
+package main
+
+import "testing"
+
+func TestInventoryStacksAndOverflows(t *testing.T) {
+	inv := NewInventory(2)
+
+	if left := inv.Add("aid", 7); left != 0 {
+		t.Fatalf("%d aid kits did not fit into empty slots", left)
+	}
+
+	if got := inv.GetSlot(0).count; got != 5 {
+		t.Fatalf("first stack holds %d, want the stack size 5", got)
+	}
+
+	if left := inv.Add("aid", 5); left != 2 {
+		t.Fatalf("%d aid kits left over, want 2", left)
+	}
+
+	if left := inv.Add("berries", 1); left != 1 {
+		t.Fatal("berries were added to a full inventory")
+	}
+
+	if !inv.Take("aid", 6) || inv.Count("aid") != 4 {
+		t.Fatalf("aid count = %d after taking 6 of 10", inv.Count("aid"))
+	}
+
+	if !inv.GetSlot(1).IsEmpty() || inv.GetSlot(0).count != 4 {
+		t.Fatalf("slots = %v, want the last stack emptied first", inv.slots)
+	}
+
+	if inv.Take("aid", 5) {
+		t.Fatal("took more aid kits than carried")
+	}
+}
+
+func TestPickUpAndUseAid(t *testing.T) {
+	level := newTestLevel()
+	aidPos := tileIndex(level, 3, 3)
+	level.SetTile(testObjectLayer, aidPos, tileIDAid)
+
+	sim, input := newTestSimulation(t, level)
+	g := sim.GetGame()
+	sim.Spawn(g.char, 2, 3)
+	g.char.GetLivingEntity().health = 30
+
+	input.Hold(0, 20, kbPlayerMoveRight)
+	sim.Step(20)
+
+	if g.char.GetItemCount("aid") != 1 || level.GetTile(testObjectLayer, aidPos) != tileIDEmpty {
+		t.Fatal("aid kit was not picked up")
+	}
+
+	if !g.UseItem(0) {
+		t.Fatal("aid kit was not used")
+	}
+
+	if got := g.char.GetHealth(); got != 80 {
+		t.Fatalf("health = %.1f after using the aid kit, want 80", got)
+	}
+
+	if !g.char.GetInventory().GetSlot(0).IsEmpty() {
+		t.Fatal("used aid kit is still in the inventory")
+	}
+
+	g.char.AddItem("aid", 1)
+	g.char.GetLivingEntity().health = 100
+
+	if g.UseItem(0) || g.char.GetItemCount("aid") != 1 {
+		t.Fatal("aid kit was used up at full health")
+	}
+}
+
+func TestInventoryScreenUsesRecordedInput(t *testing.T) {
+	sim, input := newTestSimulation(t, newTestLevel())
+	g := sim.GetGame()
+	sim.Spawn(g.char, 5, 5)
+	g.char.GetLivingEntity().health = 30
+	g.char.AddItem("poop", 1)
+	g.char.AddItem("aid", 1)
+
+	input.Press(0, kbInventory)
+	input.Hold(2, 1, kbPlayerMoveRight)
+	input.Press(4, kbUseItem)
+	input.Press(6, kbGameMenu)
+	sim.Step(7)
+
+	if got := g.char.GetHealth(); got != 80 || g.char.GetItemCount("aid") != 0 {
+		t.Fatalf("health = %.1f with %d aid left, want the aid kit used", got, g.char.GetItemCount("aid"))
+	}
+
+	if sim.screen.overlayStack.head != nil {
+		t.Fatal("inventory did not close")
+	}
+}
+
+func TestHarvestNeedsInventorySpace(t *testing.T) {
+	level := newTestLevel()
+	bushPos := tileIndex(level, 3, 3)
+	level.SetTile(testObjectLayer, bushPos, tileIDBerryBush)
+
+	sim, _ := newTestSimulation(t, level)
+	g := sim.GetGame()
+	sim.Spawn(g.char, 2, 3)
+	g.char.GetLivingEntity().look = LooksRight
+
+	g.char.AddItem("poop", inventoryColumns*inventoryRows*10)
+
+	if g.Interact() || level.GetTile(testObjectLayer, bushPos) != tileIDBerryBush {
+		t.Fatal("harvested into a full inventory")
+	}
+}
@@ -0,0 +1,537 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+type Tile uint8
+
+type TileLayer []Tile
+
+// Side of a level storage chunk in tiles
+const levelChunkSize = 32
+
+// Square block of tiles, the unit in which streamed levels are loaded
+type LevelChunk struct {
+	x, y     int
+	layers   []TileLayer
+	modified bool
+}
+
+func NewLevelChunk(x, y, layerCount int) *LevelChunk {
+	chunk := new(LevelChunk)
+	chunk.x = x
+	chunk.y = y
+
+	for i := 0; i < layerCount; i++ {
+		chunk.layers = append(chunk.layers, make(TileLayer, levelChunkSize*levelChunkSize))
+	}
+
+	return chunk
+}
+
+// Tile grid addressed by a flat tile position: y*width + x
+//
+// Tiles are stored in chunks. A level without a chunk store keeps all of its
+// chunks in memory, a streamed one loads them from disk on demand, see
+// Game.StreamChunks. Tiles of unloaded chunks read as empty.
+type Level struct {
+	width          int
+	height         int
+	layerCount     int
+	fileName       string
+	chunks         map[int]*LevelChunk
+	store          *ChunkStore
+	revision       uint32
+	chunkRevisions map[int]uint32
+	entityDefs     *JSONLevelEntities
+	spawners       []*Spawner
+	minefields     []*Minefield
+	signals        *JSONLevelSignals
+	circuit        *SignalCircuit
+	conveyorMoves  map[int]float64
+	growing        map[tileStateKey]int
+}
+
+// Creates an empty in-memory level with the given dimensions and number of tile layers
+func NewLevel(width, height, layerCount int) *Level {
+	level := newLevelShell(width, height, layerCount)
+
+	for cy := 0; cy < level.GetChunksY(); cy++ {
+		for cx := 0; cx < level.GetChunksX(); cx++ {
+			level.chunks[level.chunkIndex(cx, cy)] = NewLevelChunk(cx, cy, layerCount)
+		}
+	}
+
+	return level
+}
+
+func newLevelShell(width, height, layerCount int) *Level {
+	level := new(Level)
+	level.width = width
+	level.height = height
+	level.layerCount = layerCount
+	level.chunks = make(map[int]*LevelChunk)
+	level.entityDefs = new(JSONLevelEntities)
+	level.SetSignals(new(JSONLevelSignals))
+	level.conveyorMoves = make(map[int]float64)
+	level.growing = make(map[tileStateKey]int)
+	return level
+}
+
+// Replaces the level's entity list, spawners and minefields
+func (lv *Level) SetEntityDefs(defs *JSONLevelEntities) {
+	lv.entityDefs = defs
+	lv.spawners = nil
+	lv.minefields = nil
+
+	for _, def := range defs.Spawners {
+		lv.spawners = append(lv.spawners, NewSpawner(def))
+	}
+
+	for _, def := range defs.Minefields {
+		if err := def.Validate(); err != nil {
+			log.Println("[Level] Skipping " + err.Error())
+			continue
+		}
+
+		lv.minefields = append(lv.minefields, NewMinefield(def))
+	}
+}
+
+func (lv *Level) GetLayerCount() int {
+	return lv.layerCount
+}
+
+func (lv *Level) GetChunksX() int {
+	return (lv.width + levelChunkSize - 1) / levelChunkSize
+}
+
+func (lv *Level) GetChunksY() int {
+	return (lv.height + levelChunkSize - 1) / levelChunkSize
+}
+
+func (lv *Level) chunkIndex(cx, cy int) int {
+	return cy*lv.GetChunksX() + cx
+}
+
+// Returns the chunk holding the tile and the tile offset inside it
+func (lv *Level) chunkOf(tilePos int) (*LevelChunk, int) {
+	x := tilePos % lv.width
+	y := tilePos / lv.width
+
+	chunk := lv.chunks[lv.chunkIndex(x/levelChunkSize, y/levelChunkSize)]
+	return chunk, (y%levelChunkSize)*levelChunkSize + x%levelChunkSize
+}
+
+func (lv *Level) ContainsTile(x, y int) bool {
+	return x >= 0 && y >= 0 && x < lv.width && y < lv.height
+}
+
+func (lv *Level) IsTileLoaded(tilePos int) bool {
+	chunk, _ := lv.chunkOf(tilePos)
+	return chunk != nil
+}
+
+func (lv *Level) GetTile(layer int, tilePos int) Tile {
+	if tile := lv.GetTilePtr(layer, tilePos); tile != nil {
+		return *tile
+	}
+
+	return tileIDEmpty
+}
+
+// Returns a pointer to the stored tile, or nil if its chunk is not loaded
+//
+// Writing through the pointer requires a MarkTileDirty call afterwards.
+func (lv *Level) GetTilePtr(layer int, tilePos int) *Tile {
+	chunk, offset := lv.chunkOf(tilePos)
+	if chunk == nil {
+		return nil
+	}
+
+	return &chunk.layers[layer][offset]
+}
+
+// Replaces a tile and invalidates its cached render chunk
+//
+// Game logic must change tiles through SetTile, ReplaceAll or call
+// MarkTileDirty after writing through GetTilePtr, otherwise the change
+// stays invisible and a streamed chunk is not saved on unload.
+func (lv *Level) SetTile(layer int, tilePos int, tile Tile) {
+	ptr := lv.GetTilePtr(layer, tilePos)
+
+	if ptr != nil && *ptr != tile {
+		*ptr = tile
+		lv.MarkTileDirty(tilePos)
+	}
+}
+
+// Replaces a tile everywhere in the loaded part of the level
+func (lv *Level) ReplaceAll(from, to Tile) {
+	if from == to {
+		return
+	}
+
+	for _, chunk := range lv.chunks {
+		changed := false
+		for _, layer := range chunk.layers {
+			for offset, tile := range layer {
+				if tile == from {
+					layer[offset] = to
+					changed = true
+				}
+			}
+		}
+
+		if changed {
+			chunk.modified = true
+			lv.markChunkDirty(chunk)
+		}
+	}
+}
+
+// Returns loaded chunks in index order, so iteration is deterministic
+func (lv *Level) GetLoadedChunks() []*LevelChunk {
+	var indices []int
+	for index := range lv.chunks {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	var chunks []*LevelChunk
+	for _, index := range indices {
+		chunks = append(chunks, lv.chunks[index])
+	}
+
+	return chunks
+}
+
+// Entity saved with an unloaded chunk of a streamed level
+type JSONChunkEntity struct {
+	Class  string        `json:"class"`
+	X      float64       `json:"x"`
+	Y      float64       `json:"y"`
+	Health float64       `json:"health"`
+	Look   LookDirection `json:"look"`
+}
+
+type JSONWorldHeader struct {
+	Width     int `json:"width"`
+	Height    int `json:"height"`
+	Layers    int `json:"layers"`
+	ChunkSize int `json:"chunk_size"`
+}
+
+const worldHeaderFile = "world.json"
+
+// Directory with a world header and one tile and one entity file per chunk
+//
+// Chunks that were never saved are empty.
+type ChunkStore struct {
+	dir    string
+	header JSONWorldHeader
+}
+
+func (cs *ChunkStore) chunkPath(cx, cy int, ext string) string {
+	return filepath.Join(cs.dir, "chunks", fmt.Sprintf("%d_%d%s", cx, cy, ext))
+}
+
+func (cs *ChunkStore) LoadChunk(cx, cy int) (*LevelChunk, []JSONChunkEntity, error) {
+	chunk := NewLevelChunk(cx, cy, cs.header.Layers)
+
+	data, err := os.ReadFile(cs.chunkPath(cx, cy, ".bin"))
+	if err == nil {
+		if len(data) != len(chunk.layers)*levelChunkSize*levelChunkSize {
+			return nil, nil, fmt.Errorf("chunk %d,%d has %d bytes", cx, cy, len(data))
+		}
+
+		for i, layer := range chunk.layers {
+			for j := range layer {
+				layer[j] = Tile(data[i*len(layer)+j])
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	var entities []JSONChunkEntity
+
+	data, err = os.ReadFile(cs.chunkPath(cx, cy, ".json"))
+	if err == nil {
+		if err := json.Unmarshal(data, &entities); err != nil {
+			return nil, nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	return chunk, entities, nil
+}
+
+// Writes the chunk's entity list and, if they were modified, its tiles
+func (cs *ChunkStore) SaveChunk(chunk *LevelChunk, entities []JSONChunkEntity) error {
+	if err := os.MkdirAll(filepath.Join(cs.dir, "chunks"), 0755); err != nil {
+		return err
+	}
+
+	if chunk.modified {
+		var data []byte
+		for _, layer := range chunk.layers {
+			for _, tile := range layer {
+				data = append(data, byte(tile))
+			}
+		}
+
+		if err := os.WriteFile(cs.chunkPath(chunk.x, chunk.y, ".bin"), data, 0644); err != nil {
+			return err
+		}
+
+		chunk.modified = false
+	}
+
+	data, err := json.Marshal(entities)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(cs.chunkPath(chunk.x, chunk.y, ".json"), data, 0644)
+}
+
+// Creates a world directory for an empty streamed level
+func CreateWorld(dir string, width, height, layerCount int) error {
+	if err := os.MkdirAll(filepath.Join(dir, "chunks"), 0755); err != nil {
+		return err
+	}
+
+	header := JSONWorldHeader{
+		Width:     width,
+		Height:    height,
+		Layers:    layerCount,
+		ChunkSize: levelChunkSize,
+	}
+
+	data, err := json.MarshalIndent(header, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, worldHeaderFile), data, 0644)
+}
+
+func IsWorldDir(path string) bool {
+	_, err := os.Stat(filepath.Join(path, worldHeaderFile))
+	return err == nil
+}
+
+// Opens a streamed level; no chunks are loaded until the first StreamChunks
+func LoadWorld(dir string) (*Level, error) {
+	data, err := os.ReadFile(filepath.Join(dir, worldHeaderFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var header JSONWorldHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	if header.ChunkSize != levelChunkSize {
+		return nil, fmt.Errorf("world chunk size is %d, only %d is supported", header.ChunkSize, levelChunkSize)
+	}
+
+	if header.Width <= 0 || header.Height <= 0 || header.Layers <= 0 {
+		return nil, errors.New("world header has no size")
+	}
+
+	level := newLevelShell(header.Width, header.Height, header.Layers)
+	level.fileName = dir
+	level.store = &ChunkStore{dir: dir, header: header}
+
+	return level, nil
+}
+
+// Chunks kept loaded in every direction around the camera and the player
+const chunkStreamRadius = 1
+
+func (g *Game) wantedChunks() map[int]bool {
+	level := g.level
+	wanted := make(map[int]bool)
+
+	centers := []Vec2f{g.camera.GetCurrentPosition()}
+	if g.char != nil {
+		centers = append(centers, g.char.GetWorldPos())
+	}
+
+	chunkWorldSize := float64(levelChunkSize * tileSize)
+
+	for _, center := range centers {
+		ccx := int(center.X / chunkWorldSize)
+		ccy := int(center.Y / chunkWorldSize)
+
+		for cy := ccy - chunkStreamRadius; cy <= ccy+chunkStreamRadius; cy++ {
+			for cx := ccx - chunkStreamRadius; cx <= ccx+chunkStreamRadius; cx++ {
+				if cx >= 0 && cy >= 0 && cx < level.GetChunksX() && cy < level.GetChunksY() {
+					wanted[level.chunkIndex(cx, cy)] = true
+				}
+			}
+		}
+	}
+
+	return wanted
+}
+
+// Loads chunks around the camera and the player and unloads the rest
+//
+// Entities standing in an unloaded chunk leave the entity list and are saved
+// with the chunk, then come back when it is loaded again. The player always
+// stays. Does nothing for in-memory levels.
+func (g *Game) StreamChunks() {
+	level := g.level
+	if level == nil || level.store == nil {
+		return
+	}
+
+	wanted := g.wantedChunks()
+
+	for index, chunk := range level.chunks {
+		if !wanted[index] {
+			if err := g.unloadChunk(chunk); err != nil {
+				log.Println("[World] Failed to save chunk: " + err.Error())
+				continue
+			}
+
+			delete(level.chunks, index)
+			level.markChunkDirty(chunk)
+		}
+	}
+
+	var indices []int
+	for index := range wanted {
+		if _, loaded := level.chunks[index]; !loaded {
+			indices = append(indices, index)
+		}
+	}
+	sort.Ints(indices)
+
+	for _, index := range indices {
+		cx, cy := index%level.GetChunksX(), index/level.GetChunksX()
+
+		chunk, entities, err := level.store.LoadChunk(cx, cy)
+		if err != nil {
+			log.Println("[World] Failed to load chunk: " + err.Error())
+			chunk = NewLevelChunk(cx, cy, level.layerCount)
+		}
+
+		level.chunks[index] = chunk
+		level.markChunkDirty(chunk)
+
+		g.spawnChunkEntities(entities)
+
+		balls := g.createTileBalls(level, chunk)
+		g.entityListMutex.Lock()
+		g.entities = append(g.entities, balls...)
+		g.entityListMutex.Unlock()
+	}
+}
+
+func (g *Game) chunkOfEntity(e ILivingEntity) (int, bool) {
+	tilePos, err := e.GetTilePos()
+	if err != nil {
+		return 0, false
+	}
+
+	x, y := tilePos%g.level.width, tilePos/g.level.width
+	return g.level.chunkIndex(x/levelChunkSize, y/levelChunkSize), true
+}
+
+// Saves the chunk and takes its entities out of the world
+func (g *Game) unloadChunk(chunk *LevelChunk) error {
+	index := g.level.chunkIndex(chunk.x, chunk.y)
+	var saved []JSONChunkEntity
+
+	g.entityListMutex.Lock()
+	remaining := g.entities[:0]
+	for _, entity := range g.entities {
+		entityChunk, ok := g.chunkOfEntity(entity)
+		known := IsEntityClassRegistered(EntityClassKey(entity))
+
+		if ok && entityChunk == index && entity != g.char && known {
+			e := entity.GetLivingEntity()
+			saved = append(saved, JSONChunkEntity{
+				Class:  EntityClassKey(entity),
+				X:      e.worldPos.X,
+				Y:      e.worldPos.Y,
+				Health: e.health,
+				Look:   e.look,
+			})
+			continue
+		}
+
+		remaining = append(remaining, entity)
+	}
+	for i := len(remaining); i < len(g.entities); i++ {
+		g.entities[i] = nil
+	}
+	g.entities = remaining
+	g.entityListMutex.Unlock()
+
+	return g.level.store.SaveChunk(chunk, saved)
+}
+
+func (g *Game) spawnChunkEntities(entities []JSONChunkEntity) {
+	for _, saved := range entities {
+		entity, err := CreateEntity(g, saved.Class)
+		if err != nil {
+			log.Println("[World] " + err.Error())
+			continue
+		}
+
+		e := entity.GetLivingEntity()
+		e.worldPos = Vec2f{saved.X, saved.Y}
+		e.health = saved.Health
+		e.look = saved.Look
+		e.prevTilePos, _ = e.GetTilePos()
+
+		g.entityListMutex.Lock()
+		g.entities = append(g.entities, entity)
+		g.entityListMutex.Unlock()
+	}
+}
+
+// Saves every loaded chunk of a streamed level with the entities on it
+func (g *Game) SaveWorld() error {
+	level := g.level
+	if level.store == nil {
+		return errors.New("level is not a streamed world")
+	}
+
+	for _, chunk := range level.GetLoadedChunks() {
+		index := level.chunkIndex(chunk.x, chunk.y)
+		var saved []JSONChunkEntity
+
+		g.entityListMutex.RLock()
+		for _, entity := range g.entities {
+			entityChunk, ok := g.chunkOfEntity(entity)
+			if known := IsEntityClassRegistered(EntityClassKey(entity)); !known || !ok || entityChunk != index || entity == g.char {
+				continue
+			}
+
+			e := entity.GetLivingEntity()
+			saved = append(saved, JSONChunkEntity{EntityClassKey(entity), e.worldPos.X, e.worldPos.Y, e.health, e.look})
+		}
+		g.entityListMutex.RUnlock()
+
+		if err := level.store.SaveChunk(chunk, saved); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
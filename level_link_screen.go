@@ -0,0 +1,99 @@
+package main
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Editor dialog setting the destination of a door, elevator or pit
+//
+// Fields are the target level path and the spawn tile X and Y.
+type LevelLinkScreen struct {
+	GenericWidgetContainerScreen
+	gameplayScreen *GameplayScreen
+	x, y           int
+}
+
+func (s *LevelLinkScreen) ProcessKeyEvents() bool {
+	if s.GenericWidgetContainerScreen.ProcessKeyEvents() {
+		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			s.gameplayScreen.overlayStack.Pop()
+		}
+	}
+
+	return false
+}
+
+// Stores the link from the dialog fields and writes the world graph
+func (s *LevelLinkScreen) Apply() error {
+	spawnX, err := strconv.Atoi(s.widgets[1].GetText())
+	if err != nil {
+		return err
+	}
+
+	spawnY, err := strconv.Atoi(s.widgets[2].GetText())
+	if err != nil {
+		return err
+	}
+
+	g := s.game
+	g.worldGraph.SetLink(JSONLevelLink{
+		Level:  g.level.fileName,
+		X:      s.x,
+		Y:      s.y,
+		Target: s.widgets[0].GetText(),
+		SpawnX: spawnX,
+		SpawnY: spawnY,
+	})
+
+	return g.worldGraph.Save()
+}
+
+func (s *LevelLinkScreen) Remove() error {
+	s.game.worldGraph.RemoveLink(s.game.level.fileName, s.x, s.y)
+	return s.game.worldGraph.Save()
+}
+
+func CreateLevelLinkScreen(gameplayScreen *GameplayScreen, x, y int) *LevelLinkScreen {
+	s := new(LevelLinkScreen)
+	InititalizeGenericWidgetContainerScreen(&s.GenericWidgetContainerScreen)
+
+	s.gameplayScreen = gameplayScreen
+	s.game = gameplayScreen.game
+	s.x = x
+	s.y = y
+	g := gameplayScreen.game
+	s.title = I18n("string_level_link", "Destination: level, spawn X, Y")
+
+	link := g.worldGraph.GetLink(g.level.fileName, x, y)
+	if link == nil {
+		link = &JSONLevelLink{Target: defaultLevelPath}
+	}
+
+	for _, text := range []string{link.Target, strconv.Itoa(link.SpawnX), strconv.Itoa(link.SpawnY)} {
+		editBox := CreateCommonEditBox(g, func(g *Game) {})
+		editBox.SetText(text)
+		s.widgets = append(s.widgets, editBox)
+	}
+
+	s.widgets = append(s.widgets, CreateCommonButton(g, I18n("string_verb_save", "Save"), func(g *Game) {
+		if err := s.Apply(); err != nil {
+			log.Println("[World] Failed to save level link: " + err.Error())
+			return
+		}
+		gameplayScreen.overlayStack.Pop()
+	}))
+
+	s.widgets = append(s.widgets, CreateCommonButton(g, I18n("string_verb_remove", "Remove"), func(g *Game) {
+		if err := s.Remove(); err != nil {
+			log.Println("[World] Failed to save world graph: " + err.Error())
+		}
+		gameplayScreen.overlayStack.Pop()
+	}))
+
+	s.SetInitialFocus()
+	return s
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLevelSaveLoadRoundTrip(t *testing.T) {
+	level := newTestLevel()
+	level.SetTile(testObjectLayer, tileIndex(level, 3, 4), tileIDSwitch)
+	level.SetTile(testObjectLayer, tileIndex(level, 14, 14), tileIDThornsActive)
+
+	sim, _ := newTestSimulation(t, level)
+	path := filepath.Join(t.TempDir(), "round_trip.lvl")
+
+	if err := sim.GetGame().SaveLevel(path); err != nil {
+		t.Fatalf("SaveLevel: %v", err)
+	}
+
+	loaded := new(Game)
+	if err := loaded.LoadLevel(path); err != nil {
+		t.Fatalf("LoadLevel: %v", err)
+	}
+
+	if loaded.level.width != level.width || loaded.level.height != level.height {
+		t.Fatalf("size = %dx%d, want %dx%d", loaded.level.width, loaded.level.height, level.width, level.height)
+	}
+
+	if loaded.level.GetLayerCount() != level.GetLayerCount() {
+		t.Fatalf("layer count = %d, want %d", loaded.level.GetLayerCount(), level.GetLayerCount())
+	}
+
+	for i := 0; i < level.GetLayerCount(); i++ {
+		for j := 0; j < level.width*level.height; j++ {
+			if got, tile := loaded.level.GetTile(i, j), level.GetTile(i, j); got != tile {
+				t.Fatalf("layer %d tile %d = %d, want %d", i, j, got, tile)
+			}
+		}
+	}
+}
+
+func TestLoadLevelMissingFile(t *testing.T) {
+	g := new(Game)
+
+	if err := g.LoadLevel(filepath.Join(t.TempDir(), "missing.lvl")); err == nil {
+		t.Fatal("LoadLevel succeeded for a missing file")
+	}
+}
+
+func TestLevelChunkBoundaries(t *testing.T) {
+	level := NewLevel(70, 40, 2)
+	sim, _ := newTestSimulation(t, level)
+	g := sim.GetGame()
+
+	level.SetTile(testObjectLayer, tileIndex(level, 31, 5), tileIDRock)
+	level.SetTile(testObjectLayer, tileIndex(level, 32, 5), tileIDWater)
+	level.SetTile(testObjectLayer, tileIndex(level, 69, 39), tileIDRock)
+
+	if level.GetChunksX() != 3 || level.GetChunksY() != 2 {
+		t.Fatalf("chunk grid = %dx%d, want 3x2", level.GetChunksX(), level.GetChunksY())
+	}
+
+	left, _ := g.GetUnderlyingTilesAt(31*tileSize+tileSize-1, 5*tileSize)
+	right, _ := g.GetUnderlyingTilesAt(32*tileSize, 5*tileSize)
+	if len(left) != 1 || left[0] != tileIDRock || len(right) != 1 || right[0] != tileIDWater {
+		t.Fatalf("tiles across the chunk border = %v and %v", left, right)
+	}
+
+	if !g.IsTileSolidAt(69*tileSize+1, 39*tileSize+1) || g.IsTileSolidAt(33*tileSize, 5*tileSize) {
+		t.Fatal("collision does not follow the tiles in the last chunk")
+	}
+
+	if _, err := g.WorldPosToTilePos(-1, 10); err == nil || !g.IsTileSolidAt(70*tileSize, 10) {
+		t.Fatal("positions outside the level are not rejected")
+	}
+}
+
+func TestWorldRejectsSaveElsewhere(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "world")
+	if err := CreateWorld(dir, 64, 64, 2); err != nil {
+		t.Fatalf("CreateWorld: %v", err)
+	}
+
+	level, err := LoadWorld(dir)
+	if err != nil {
+		t.Fatalf("LoadWorld: %v", err)
+	}
+
+	sim, _ := newTestSimulation(t, level)
+	g := sim.GetGame()
+	level.entityDefs.Entities = append(level.entityDefs.Entities, JSONEntitySpawn{Class: "michael"})
+
+	other := filepath.Join(t.TempDir(), "other.lvl")
+	if err := g.SaveLevel(other); err == nil {
+		t.Fatal("streamed world was saved to another path")
+	}
+
+	if _, err := os.Stat(LevelEntitiesPath(other)); err == nil {
+		t.Fatal("rejected save wrote an entity file")
+	}
+}
+
+func TestLevelReplaceAllMarksChangedChunks(t *testing.T) {
+	level := NewLevel(70, 40, 2)
+	level.SetTile(testObjectLayer, tileIndex(level, 5, 5), tileIDThornsActive)
+
+	changed, untouched := tileIndex(level, 5, 5), tileIndex(level, 69, 39)
+	_, changedBefore := level.getChunkRevision(level.renderChunkIndex(changed))
+	_, untouchedBefore := level.getChunkRevision(level.renderChunkIndex(untouched))
+
+	level.ReplaceAll(tileIDThornsActive, tileIDThorns)
+
+	if level.GetTile(testObjectLayer, changed) != tileIDThorns {
+		t.Fatal("tile was not replaced")
+	}
+
+	if _, rev := level.getChunkRevision(level.renderChunkIndex(changed)); rev == changedBefore {
+		t.Error("chunk with a replaced tile was not invalidated")
+	}
+
+	if _, rev := level.getChunkRevision(level.renderChunkIndex(untouched)); rev != untouchedBefore {
+		t.Error("chunk without the tile was invalidated")
+	}
+}
+
+func TestWorldStreamsChunksAndEntities(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "world")
+	if err := CreateWorld(dir, 160, 96, 2); err != nil {
+		t.Fatalf("CreateWorld: %v", err)
+	}
+
+	level, err := LoadWorld(dir)
+	if err != nil {
+		t.Fatalf("LoadWorld: %v", err)
+	}
+
+	sim, _ := newTestSimulation(t, level)
+	g := sim.GetGame()
+
+	home := Vec2f{10 * tileSize, 10 * tileSize}
+	g.camera.TargetPosition(home)
+	sim.Spawn(g.char, 10, 10)
+	sim.Step(1)
+
+	if !level.IsTileLoaded(tileIndex(level, 40, 40)) || level.IsTileLoaded(tileIndex(level, 100, 10)) {
+		t.Fatal("chunks around the camera are not the loaded ones")
+	}
+
+	level.SetTile(testObjectLayer, tileIndex(level, 12, 10), tileIDRock)
+	npc, _ := CreateEntity(g, "michael")
+	sim.Spawn(npc, 20, 20)
+	npc.GetLivingEntity().health = 42
+
+	// Walk away far enough for the home chunk to unload
+	far := Vec2f{150 * tileSize, 90 * tileSize}
+	g.char.GetLivingEntity().worldPos = far
+	g.camera.TargetPosition(far)
+	sim.Step(1)
+
+	if level.IsTileLoaded(tileIndex(level, 12, 10)) || len(g.entities) != 1 {
+		t.Fatalf("home chunk still loaded with %d entities", len(g.entities))
+	}
+
+	g.char.GetLivingEntity().worldPos = home
+	g.camera.TargetPosition(home)
+	sim.Step(1)
+
+	if level.GetTile(testObjectLayer, tileIndex(level, 12, 10)) != tileIDRock {
+		t.Fatal("tile change was lost across unload")
+	}
+
+	if len(g.entities) != 2 || EntityClassKey(g.entities[1]) != "michael" || g.entities[1].GetHealth() != 42 {
+		t.Fatalf("entity did not come back with its chunk: %d entities", len(g.entities))
+	}
+}
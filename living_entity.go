@@ -1,8 +1,6 @@
 package main
 
 import (
-	"fmt"
-	"image"
 	"image/color"
 	"math"
 
@@ -17,7 +15,6 @@ type ILivingEntity interface {
 	GetTilePos() (int, error)
 	Update()
 	Draw(*ebiten.Image)
-	DrawDebugInfo(*ebiten.Image)
 	GetHealth() float64
 	GetWorldPos() Vec2f
 	GetLivingEntity() *LivingEntity
@@ -27,6 +24,7 @@ type ILivingEntity interface {
 type LivingEntity struct {
 	etype         interface{}
 	entityClass   string
+	classKey      string
 	look          LookDirection
 	walking       bool
 	worldPos      Vec2f
@@ -35,10 +33,14 @@ type LivingEntity struct {
 	health        float64
 	speedModifier float64
 	sprite        *ebiten.Image
+	clips         *SpriteClipPlayer
+	action        string
+	animTick      int
 	game          *Game
 	id            int
 	spells        []ISpell
 	prevTilePos   int
+	dialogue      string
 }
 
 func (e *LivingEntity) SetGame(g *Game) {
@@ -62,21 +64,29 @@ func (e *LivingEntity) ProcessWalk() {
 		case LooksRight:
 			if !e.game.IsTileSolidAt(e.worldPos.X+speed, e.worldPos.Y) {
 				e.worldPos.X += speed
+			} else {
+				e.game.TryPushBox(e, e.worldPos.X+speed, e.worldPos.Y)
 			}
 
 		case LooksLeft:
 			if !e.game.IsTileSolidAt(e.worldPos.X-speed, e.worldPos.Y) {
 				e.worldPos.X -= speed
+			} else {
+				e.game.TryPushBox(e, e.worldPos.X-speed, e.worldPos.Y)
 			}
 
 		case LooksUp:
 			if !e.game.IsTileSolidAt(e.worldPos.X, e.worldPos.Y-speed) {
 				e.worldPos.Y -= speed
+			} else {
+				e.game.TryPushBox(e, e.worldPos.X, e.worldPos.Y-speed)
 			}
 
 		case LooksDown:
 			if !e.game.IsTileSolidAt(e.worldPos.X, e.worldPos.Y+speed) {
 				e.worldPos.Y += speed
+			} else {
+				e.game.TryPushBox(e, e.worldPos.X, e.worldPos.Y+speed)
 			}
 		}
 	}
@@ -92,6 +102,15 @@ func (e *LivingEntity) Update() {
 	}
 }
 
+// Decreases health, unless the entity is the player and god mode is on
+func (e *LivingEntity) Damage(amount float64) {
+	if e.game.godMode && e.game.char != nil && e.game.char.GetLivingEntity() == e {
+		return
+	}
+
+	e.health -= amount
+}
+
 func (e *LivingEntity) SetSpeedModifier(speed float64) {
 	e.speedModifier = speed
 }
@@ -162,9 +181,12 @@ func (s *Spell) GetSpell() *Spell {
 
 type MonobearExplosion struct {
 	Spell
+	clips *SpriteClipPlayer
 }
 
 func (s *MonobearExplosion) Update() {
+	s.clips.Update(1)
+
 	/*
 		elapsed := tickCounter - s.creationTime
 
@@ -184,6 +206,8 @@ func CreateMonobearExplosion(caster ILivingEntity) *MonobearExplosion {
 	s := new(MonobearExplosion)
 	s.caster = caster
 	s.creationTime = tickCounter
+	s.clips = NewSpriteClipPlayer(GetSpriteClips("explosion"))
+	s.clips.Play("explode")
 
 	return s
 }
@@ -198,7 +222,7 @@ func (s *MonobearExplosion) Draw(screen *ebiten.Image) {
 
 		op.GeoM.Translate(-16, -16)
 		op.GeoM.Translate(pos.X, pos.Y)
-		screen.DrawImage(skillMonobearExplosion, op)
+		DrawImage(screen, skillMonobearExplosion, op)
 	}
 
 	{
@@ -215,7 +239,7 @@ func (s *MonobearExplosion) Draw(screen *ebiten.Image) {
 		e.game.fontRenderer.DrawTextAt(screen, "Monobear Explosion", pos)
 	}
 
-	{
+	if frame := s.clips.GetFrame(explosionSprite); frame != nil {
 		op := &ebiten.DrawImageOptions{}
 
 		screenCenter := Vec2f{screenWidth / 2, screenHeight / 2}
@@ -228,94 +252,87 @@ func (s *MonobearExplosion) Draw(screen *ebiten.Image) {
 		pos = pos.Scale(cameraZoom)
 		pos = screenCenter.Subtract(pos)
 
-		i := tickCounter / 4 % 12
-
-		sx := (i % 4) * 16
-		sy := (i / 4) * 16
-
-		op.GeoM.Translate(-(float64(tileSize) / 2), -(float64(tileSize) / 2))
+		width, height := frame.Size()
+		op.GeoM.Translate(-(float64(width) / 2), -(float64(height) / 2))
 		op.GeoM.Scale(cameraZoom, cameraZoom)
 		op.GeoM.Translate(pos.X, pos.Y)
 
-		tile := explosionSprite.SubImage(image.Rect(sx, sy, sx+tileSize, sy+tileSize)).(*ebiten.Image)
-		screen.DrawImage(tile, op)
+		DrawImage(screen, frame, op)
 	}
 }
 
-func (e *LivingEntity) Draw(screen *ebiten.Image) {
-	var walkAnim int
-	var spriteLine int
-	var sx int
-	var sy int
-	var sprite *ebiten.Image
-
-	op := &ebiten.DrawImageOptions{}
-	walkAnim = 0
+// Sets the sprite sheet image and the name of its clip descriptor
+func (e *LivingEntity) SetSprite(sprite *ebiten.Image, sheet string) {
+	e.sprite = sprite
+	e.clips.SetClips(GetSpriteClips(sheet))
+}
 
-	if e.walking {
-		speed := e.baseSpeed * e.speedModifier
-		animTicker := int(float64(float64(tickCounter) * speed))
-		walkAnim += animTicker / 4 % 3
+// Plays a one-shot clip such as "attack" from the start instead of
+// standing or walking
+func (e *LivingEntity) PlayClip(name string) bool {
+	if !e.clips.Play(name) {
+		return false
 	}
-	spriteLine = int(e.look) * 4
 
-	sx = ((spriteLine + walkAnim) % 4) * tileSize
-	sy = ((spriteLine + walkAnim) / 4) * tileSize
+	e.action = name
+	e.clips.Restart()
+	e.animTick = tickCounter
+	return true
+}
 
-	cameraZoom := e.game.camera.GetZoom()
+// Returns the clip to show: the playing action, else standing or walking
+// in the look direction
+func (e *LivingEntity) GetClipName() string {
+	if e.action != "" {
+		return e.action
+	}
 
-	pos := e.game.camera.WorldToScreen2(e.worldPos)
+	state := clipIdle
+	if e.walking {
+		state = clipWalk
+	}
 
-	op.GeoM.Translate(-e.anchorPos.X, -e.anchorPos.Y)
-	op.GeoM.Scale(cameraZoom, cameraZoom)
-	op.GeoM.Translate(pos.X, pos.Y)
+	return state + "_" + lookDirectionClipNames[e.look]
+}
 
-	sprite = e.sprite.SubImage(image.Rect(sx, sy, sx+tileSize, sy+tileSize)).(*ebiten.Image)
-	screen.DrawImage(sprite, op)
+// Advances the clip by the ticks passed since the last call, walking as
+// fast as the entity moves. Called once a tick by StepWorld.
+func (e *LivingEntity) UpdateClip() {
+	elapsed := float64(tickCounter - e.animTick)
+	e.animTick = tickCounter
 
-	e.DrawHealthBar(screen)
-}
+	e.clips.Play(e.GetClipName())
 
-func (e *LivingEntity) DrawDebugInfo(screen *ebiten.Image) {
-	// health bar offset from character origin in world coordinates
-	healthBarOffset := Vec2f{float64(CHAR_TILE_SIZE) / 2, -(float64(CHAR_TILE_SIZE))}
+	if e.action == "" && e.walking {
+		elapsed *= e.baseSpeed * e.speedModifier
+	}
+	e.clips.Update(elapsed)
 
-	pos := Vec2f{screenWidth / 2, screenHeight / 2}
-	pos = pos.Translate(
-		e.game.camera.GetCurrentPosition().Translate(
-			e.GetWorldPos().Translate(healthBarOffset).Negate()).Scale(
-			e.game.camera.GetZoom()).Negate())
+	if e.action != "" && e.clips.IsFinished() {
+		e.action = ""
+		e.clips.Play(e.GetClipName())
+	}
+}
 
-	infoPrinters := []func(*LivingEntity) string{
-		func(e *LivingEntity) string {
-			return fmt.Sprintf("x: %f, y: %f", e.GetWorldPos().X, e.GetWorldPos().Y)
-		},
-		func(e *LivingEntity) string {
-			return fmt.Sprintf("walking: %t", e.walking)
-		},
-		func(e *LivingEntity) string {
-			return fmt.Sprintf("health: %f", e.health)
-		},
-		func(e *LivingEntity) string {
-			tilePos, _ := e.GetTilePos()
-			return fmt.Sprintf("CurTilePos: %d", tilePos)
-		},
-		func(e *LivingEntity) string {
-			return fmt.Sprintf("PrevTilePos: %d", e.GetLivingEntity().prevTilePos)
-		},
+func (e *LivingEntity) Draw(screen *ebiten.Image) {
+	sprite := e.clips.GetFrame(e.sprite)
+	if sprite == nil {
+		e.DrawHealthBar(screen)
+		return
 	}
 
-	fontRenderer := e.game.fontRenderer
+	op := &ebiten.DrawImageOptions{}
+	cameraZoom := e.game.camera.GetZoom()
 
-	fontRenderer.PushState()
-	fontRenderer.Reset()
+	pos := e.game.camera.WorldToScreen2(e.worldPos)
 
-	for i, printer := range infoPrinters {
-		fontRenderer.DrawTextAt(screen, printer(e), pos)
-		pos = pos.Translate(Vec2f{0, float64(fontRenderer.GetGlyphSize().X+1) * float64(i)})
-	}
+	op.GeoM.Translate(-e.anchorPos.X, -e.anchorPos.Y)
+	op.GeoM.Scale(cameraZoom, cameraZoom)
+	op.GeoM.Translate(pos.X, pos.Y)
 
-	fontRenderer.PopState()
+	DrawImage(screen, sprite, op)
+
+	e.DrawHealthBar(screen)
 }
 
 var eidCounter int
@@ -326,6 +343,8 @@ func (e *LivingEntity) _ConstructLivingEntity(g *Game) {
 	e.baseSpeed = 1.0
 	e.speedModifier = 1.0
 	e.health = 100.0
+	e.clips = NewSpriteClipPlayer(GetSpriteClips(defaultSpriteClips))
+	e.animTick = tickCounter
 	e.id = eidCounter
 	eidCounter++
 	e.SetGame(g)
@@ -339,9 +358,9 @@ func (e *LivingEntity) GetUnderlyingTiles() ([]Tile, error) {
 		return nil, err
 	}
 
-	for _, layer := range e.game.level.tileLayers {
-		if layer[tilePos] != Tile(tileIDEmpty) {
-			tiles = append(tiles, layer[tilePos])
+	for i := 0; i < e.game.level.GetLayerCount(); i++ {
+		if tile := e.game.level.GetTile(i, tilePos); tile != Tile(tileIDEmpty) {
+			tiles = append(tiles, tile)
 		}
 	}
 
@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestWalkStopsAtSolidTile(t *testing.T) {
+	level := newTestLevel()
+	level.SetTile(testObjectLayer, tileIndex(level, 6, 3), tileIDRock)
+
+	sim, input := newTestSimulation(t, level)
+	player := sim.GetGame().char
+	sim.Spawn(player, 3, 3)
+
+	input.Hold(0, tileSize*4, kbPlayerMoveRight)
+	sim.Step(tileSize*4 + 1)
+
+	if x := player.GetWorldPos().X; x != 6*tileSize-1 {
+		t.Fatalf("player x = %f, want to stop at %d", x, 6*tileSize-1)
+	}
+}
+
+func TestWalkOnWaterIsSlowed(t *testing.T) {
+	level := newTestLevel()
+	for x := 0; x < level.width; x++ {
+		level.SetTile(testObjectLayer, tileIndex(level, x, 3), tileIDWater)
+	}
+
+	sim, input := newTestSimulation(t, level)
+	player := sim.GetGame().char
+	sim.Spawn(player, 3, 3)
+
+	startX := player.GetWorldPos().X
+
+	input.Hold(0, tileSize, kbPlayerMoveRight)
+	sim.Step(tileSize + 1)
+
+	if moved := player.GetWorldPos().X - startX; moved >= tileSize {
+		t.Fatalf("player moved %f px in water, want less than %d", moved, tileSize)
+	}
+}
+
+func TestDeadEntityIsRemoved(t *testing.T) {
+	level := newTestLevel()
+	for y := 2; y <= 4; y++ {
+		for x := 2; x <= 4; x++ {
+			level.SetTile(testObjectLayer, tileIndex(level, x, y), tileIDThornsActive)
+		}
+	}
+
+	sim, _ := newTestSimulation(t, level)
+	g := sim.GetGame()
+
+	npc := CreateMichael(g)
+	sim.Spawn(npc, 3, 3)
+	sim.Spawn(g.char, 10, 10)
+
+	sim.Step(10)
+
+	if npc.GetHealth() > 0 {
+		t.Fatalf("npc health = %f on armed thorns, want dead", npc.GetHealth())
+	}
+
+	for _, e := range g.entities {
+		if e == ILivingEntity(npc) {
+			t.Fatal("dead npc is still in the entity list")
+		}
+	}
+
+	if len(g.entities) != 1 {
+		t.Fatalf("entity count = %d, want 1", len(g.entities))
+	}
+}
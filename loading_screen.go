@@ -17,7 +17,7 @@ func (s *LoadingScreen) Draw(screen *ebiten.Image) {
 	op := &ebiten.DrawImageOptions{}
 	op.GeoM.Translate(-float64(splash.Bounds().Dx())/2, -float64(splash.Bounds().Dy())/2)
 	op.GeoM.Translate(screenWidth/2, screenHeight/2)
-	screen.DrawImage(splash, op)
+	DrawImage(screen, splash, op)
 
 	s.game.systemFontRenderer.DrawTextFormattedAt(screen, "Loading...", TextFormat{textColor: color.White, scale: 2.0, shadow: true}, 10, 10)
 
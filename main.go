@@ -2,6 +2,8 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	_ "image/png"
@@ -20,7 +22,6 @@ import (
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
 type TextGrid struct {
@@ -107,12 +108,24 @@ const (
 	kbShowDebugInfo             KeyBind = 14
 	kbWorldZoomIn               KeyBind = 15
 	kbWorldZoomOut              KeyBind = 16
+	kbToggleConsole             KeyBind = 17
+	kbToggleEntityInspector     KeyBind = 18
+	kbToggleProfiler            KeyBind = 19
+	kbEditorEditLink            KeyBind = 20
+	kbUndoPush                  KeyBind = 21
+	kbEditorEditSignal          KeyBind = 22
+	kbPlaceFlag                 KeyBind = 23
+	kbInteract                  KeyBind = 24
+	kbInventory                 KeyBind = 25
+	kbGameMenu                  KeyBind = 26
+	kbUseItem                   KeyBind = 27
+	kbConfirm                   KeyBind = 28
+	kbBacklog                   KeyBind = 29
+	kbFastForward               KeyBind = 30
 )
 
 var keyBinds KeyBindMap
 
-var r *rand.Rand
-
 type LookDirection int64
 
 const (
@@ -126,27 +139,6 @@ type View struct {
 	guiScale float64
 }
 
-type Tile uint8
-
-type TileLayer []Tile
-
-type Level struct {
-	tileLayers []TileLayer
-	width      int
-	height     int
-	fileName   string
-}
-
-func (lv *Level) ReplaceAll(from, to Tile) {
-	for i, layer := range lv.tileLayers {
-		for j, tile := range layer {
-			if tile == from {
-				lv.tileLayers[i][j] = to
-			}
-		}
-	}
-}
-
 func I18n(stringID, fallbackText string) string {
 	translationString, has := langData[stringID]
 
@@ -245,8 +237,6 @@ var tileNameMap map[Tile]string
 var brandingImage *ebiten.Image
 
 func init() {
-	r = rand.New(rand.NewSource(time.Now().UnixNano()))
-
 	tileNameMap = make(map[Tile]string)
 
 	tileDescStorage = make(TileDescStorage)
@@ -311,7 +301,7 @@ func init() {
 	tileDescStorage.RegisterTile(tileIDPoppingBarrierPushed, "tile_id_popping_barrier_pushed", true)
 	tileDescStorage.RegisterTile(tileIDPoppingBarrier, "tile_id_popping_barrier", true)
 	tileDescStorage.RegisterTile(tileIDPoppingBarrierActive, "tile_id_popping_barrier_active", false)
-	tileDescStorage.RegisterTile(tileIDWeigth, "tile_id_weight", false)
+	tileDescStorage.RegisterTile(tileIDWeigth, "tile_id_weight", true)
 	tileDescStorage.RegisterTile(tileIDArrowRight, "tile_id_arrow_right", true)
 	tileDescStorage.RegisterTile(tileIDBerryBush, "tile_id_berry_bush", false)
 	tileDescStorage.RegisterTile(tileIDBall, "tile_id_ball", false)
@@ -338,6 +328,9 @@ func init() {
 	tileDescStorage.RegisterTile(tileIDColorDigit2, "tile_id_color_digit_2", true)
 	tileDescStorage.RegisterTile(tileIDColorDigit3, "tile_id_color_digit_3", true)
 	tileDescStorage.RegisterTile(tileIDPoop, "tile_id_poop", true)
+	tileDescStorage.RegisterTile(tileIDArrowUp, "tile_id_arrow_up", true)
+	tileDescStorage.RegisterTile(tileIDArrowDown, "tile_id_arrow_down", true)
+	tileDescStorage.RegisterTile(tileIDFlowerSprout, "tile_id_flower_sprout", true)
 
 	tileNameMap = map[Tile]string{
 		tileIDEmpty:                "Empty",
@@ -427,6 +420,9 @@ func init() {
 		tileIDColorDigit2:          "Color Digit 2",
 		tileIDColorDigit3:          "Color Digit 3",
 		tileIDPoop:                 "Poop",
+		tileIDArrowUp:              "Arrow Up",
+		tileIDArrowDown:            "Arrow Down",
+		tileIDFlowerSprout:         "Flower Sprout",
 	}
 }
 
@@ -435,15 +431,32 @@ func (g *Game) ToggleDebugInfoShow() {
 }
 
 func (g *Game) Update() error {
-	if g.currentScreen != nil {
+	prof := Profiler_GetInstance()
+	prof.BeginUpdate()
+
+	if g.input.IsActionJustPressed(kbToggleProfiler) {
+		prof.Toggle()
+	}
+
+	if g.input.IsActionJustPressed(kbToggleConsole) {
+		g.console.Toggle()
+	} else if g.console.IsOpen() {
+		g.console.Update()
+	} else if g.currentScreen != nil {
 		g.currentScreen.Update()
 	}
 
 	g.appTicker++
+
+	prof.EndUpdate()
 	return nil
 }
 
 func GetTileSprite(tileSet *ebiten.Image, tileSetWidth int, tileSize int, tile Tile) *ebiten.Image {
+	if sprite := getDerivedTileSprite(tileSet, tileSetWidth, tileSize, tile); sprite != nil {
+		return sprite
+	}
+
 	sx := (int(tile) % tileSetWidth) * tileSize
 	sy := (int(tile) / tileSetWidth) * tileSize
 
@@ -490,35 +503,56 @@ type GameplayModeDefault struct {
 func (mode *GameplayModeDefault) ProcessKeyEvents() bool {
 
 	player := mode.gameplayScreen.game.char
+	input := mode.gameplayScreen.game.input
 
-	if inpututil.IsKeyJustReleased(keyBinds[kbPlayerMoveRight]) ||
-		inpututil.IsKeyJustReleased(keyBinds[kbPlayerMoveLeft]) ||
-		inpututil.IsKeyJustReleased(keyBinds[kbPlayerMoveUp]) ||
-		inpututil.IsKeyJustReleased(keyBinds[kbPlayerMoveDown]) {
+	if input.IsActionJustReleased(kbPlayerMoveRight) ||
+		input.IsActionJustReleased(kbPlayerMoveLeft) ||
+		input.IsActionJustReleased(kbPlayerMoveUp) ||
+		input.IsActionJustReleased(kbPlayerMoveDown) {
 		player.EndWalk()
 		return false
 	}
 
-	if inpututil.IsKeyJustPressed(keyBinds[kbPlayerMoveRight]) {
+	if input.IsActionJustPressed(kbPlayerMoveRight) {
 		player.StartWalk(LooksRight)
 		return false
 	}
 
-	if inpututil.IsKeyJustPressed(keyBinds[kbPlayerMoveLeft]) {
+	if input.IsActionJustPressed(kbPlayerMoveLeft) {
 		player.StartWalk(LooksLeft)
 		return false
 	}
 
-	if inpututil.IsKeyJustPressed(keyBinds[kbPlayerMoveUp]) {
+	if input.IsActionJustPressed(kbPlayerMoveUp) {
 		player.StartWalk(LooksUp)
 		return false
 	}
 
-	if inpututil.IsKeyJustPressed(keyBinds[kbPlayerMoveDown]) {
+	if input.IsActionJustPressed(kbPlayerMoveDown) {
 		player.StartWalk(LooksDown)
 		return false
 	}
 
+	if input.IsActionJustPressed(kbUndoPush) {
+		mode.gameplayScreen.game.UndoPush()
+		return false
+	}
+
+	if input.IsActionJustPressed(kbPlaceFlag) {
+		mode.gameplayScreen.game.ToggleMineFlag()
+		return false
+	}
+
+	if input.IsActionJustPressed(kbInteract) {
+		if npc := mode.gameplayScreen.game.FindTalkTarget(); npc != nil {
+			player.EndWalk()
+			mode.gameplayScreen.OpenDialogue(npc.GetLivingEntity().dialogue)
+		} else {
+			mode.gameplayScreen.game.Interact()
+		}
+		return false
+	}
+
 	return true
 }
 
@@ -551,12 +585,12 @@ func (mode *GameplayModeEntityFocusRotation) Update() {
 		if len(game.entities) == 1 {
 			game.camera.TargetEntity(mode.gameplayScreen.game.entities[0])
 		} else if len(game.entities) > 0 {
-			game.camera.TargetEntity(game.entities[r.Intn(len(game.entities)-1)])
+			game.camera.TargetEntity(game.entities[game.rng.Intn(len(game.entities)-1)])
 		}
 	}
 }
 func (mode *GameplayModeEntityFocusRotation) ProcessKeyEvents() bool {
-	if inpututil.IsKeyJustPressed(keyBinds[kbToggleEntityFocusRotation]) {
+	if mode.gameplayScreen.game.input.IsActionJustPressed(kbToggleEntityFocusRotation) {
 		mode.gameplayScreen.SetGameplayMode(NewGameplayModeDefault(mode.gameplayScreen))
 		return false
 	}
@@ -608,13 +642,44 @@ type Game struct {
 	currentScreen      IScreen
 	audioManager       *AudioManager
 	volumeMusic        float64
+	seed               int64
+	rng                *rand.Rand
+	input              IInputSource
+	tilemapRenderer    *TilemapRenderer
+	config             GameConfig
+	console            *Console
+	commandQueue       []string
+	godMode            bool
+	timeScale          float64
+	timeAccumulator    float64
+	worldGraph         *WorldGraph
+	levelStates        map[string]*LevelState
+	transition         *LevelTransition
+	sokoban            *Sokoban
+	ballListeners      []BallListener
+	ballScore          int
+	storyFlags         map[string]bool
+	effects            []*SpriteEffect
+}
+
+// Reseeds the simulation random generator
+//
+// Every random decision of game logic must go through g.rng, so that the same
+// seed and input reproduce the same session.
+func (g *Game) SetSeed(seed int64) {
+	g.seed = seed
+	g.rng = rand.New(rand.NewSource(seed))
 }
 
 func (g *Game) WorldPosToTilePos(worldX float64, worldY float64) (int, error) {
-	x := int(worldX)
-	y := int(worldY)
+	x := int(math.Floor(worldX / tileSize))
+	y := int(math.Floor(worldY / tileSize))
+
+	if !g.level.ContainsTile(x, y) {
+		return 0, fmt.Errorf("position %.1f,%.1f is outside the level", worldX, worldY)
+	}
 
-	return y/tileSize*g.level.width + x/tileSize, nil
+	return y*g.level.width + x, nil
 }
 
 func (g *Game) GetUnderlyingTilesAt(worldX float64, worldY float64) ([]Tile, error) {
@@ -625,17 +690,35 @@ func (g *Game) GetUnderlyingTilesAt(worldX float64, worldY float64) ([]Tile, err
 		return nil, err
 	}
 
-	for _, layer := range g.level.tileLayers {
-		if layer[tilePos] != tileIDEmpty {
-			tiles = append(tiles, layer[tilePos])
+	if !g.level.IsTileLoaded(tilePos) {
+		return nil, errors.New("tile chunk is not loaded")
+	}
+
+	for i := 0; i < g.level.GetLayerCount(); i++ {
+		if tile := g.level.GetTile(i, tilePos); tile != tileIDEmpty {
+			tiles = append(tiles, tile)
 		}
 	}
 
-	return tiles, err
+	return tiles, nil
 }
 
+// Level edges and unloaded chunks block movement, doors leading to
+// another level let through
 func (g *Game) IsTileSolidAt(worldX float64, worldY float64) bool {
-	tiles, _ := g.GetUnderlyingTilesAt(worldX, worldY)
+	tiles, err := g.GetUnderlyingTilesAt(worldX, worldY)
+	if err != nil {
+		return true
+	}
+
+	tilePos, _ := g.WorldPosToTilePos(worldX, worldY)
+	if g.GetLevelLinkAt(tilePos) != nil {
+		return false
+	}
+
+	if g.level.IsMineFlagAt(tilePos) {
+		return true
+	}
 
 	for _, underlyingTile := range tiles {
 		if !tileDescStorage[underlyingTile].Walkable {
@@ -660,37 +743,39 @@ func (g *Game) ProcessTileEffects(e ILivingEntity, tile Tile) {
 	switch id := tile; id {
 
 	case tileIDVoid:
-		e.GetLivingEntity().health -= 0.1
+		e.GetLivingEntity().Damage(0.1)
 
 	case tileIDWater:
 		e.GetLivingEntity().SetSpeedModifier(0.25)
 
+	// Wired switches and buttons are handled by the level circuit
 	case tileIDSwitch:
+		if g.level.HasWiring() {
+			break
+		}
+
 		tilePos, _ := e.GetTilePos()
-		for _, layer := range g.level.tileLayers {
-			if layer[tilePos] == tileIDSwitch {
-				layer[tilePos] = tileIDSwitchActive
-
-				for j, layer := range g.level.tileLayers {
-					for k, tile := range layer {
-						if tile == tileIDThornsActive {
-							g.level.tileLayers[j][k] = tileIDThorns
-						}
-					}
-				}
+		for i := 0; i < g.level.GetLayerCount(); i++ {
+			if g.level.GetTile(i, tilePos) == tileIDSwitch {
+				g.level.SetTile(i, tilePos, tileIDSwitchActive)
+				g.level.ReplaceAll(tileIDThornsActive, tileIDThorns)
 			}
 		}
 
 	case tileIDButton:
+		if g.level.HasWiring() {
+			break
+		}
+
 		tilePos, _ := e.GetTilePos()
-		for _, layer := range g.level.tileLayers {
-			if layer[tilePos] == tileIDButton {
-				layer[tilePos] = tileIDButtonPushed
+		for i := 0; i < g.level.GetLayerCount(); i++ {
+			if g.level.GetTile(i, tilePos) == tileIDButton {
+				g.level.SetTile(i, tilePos, tileIDButtonPushed)
 			}
 		}
 
 	case tileIDThornsActive:
-		e.GetLivingEntity().health -= 10.0
+		e.GetLivingEntity().Damage(10.0)
 
 	case tileIDLaptop:
 		switch e.GetLivingEntity().etype.(type) {
@@ -703,10 +788,8 @@ func (g *Game) ProcessTileEffects(e ILivingEntity, tile Tile) {
 
 var langData map[string]string
 
-func (g *Game) Load() {
-	loadingLog = append(loadingLog, "Loading Keybinds")
-
-	keyBinds = KeyBindMap{
+func DefaultKeyBinds() KeyBindMap {
+	return KeyBindMap{
 		kbPlayerMoveRight:           ebiten.KeyArrowRight,
 		kbPlayerMoveLeft:            ebiten.KeyArrowLeft,
 		kbPlayerMoveUp:              ebiten.KeyArrowUp,
@@ -723,9 +806,32 @@ func (g *Game) Load() {
 		kbShowDebugInfo:             ebiten.KeyF3,
 		kbWorldZoomOut:              ebiten.KeyO,
 		kbWorldZoomIn:               ebiten.KeyP,
+		kbToggleConsole:             ebiten.KeyGraveAccent,
+		kbToggleEntityInspector:     ebiten.KeyF4,
+		kbToggleProfiler:            ebiten.KeyF9,
+		kbEditorEditLink:            ebiten.KeyL,
+		kbUndoPush:                  ebiten.KeyU,
+		kbEditorEditSignal:          ebiten.KeyC,
+		kbPlaceFlag:                 ebiten.KeyF,
+		kbInteract:                  ebiten.KeyE,
+		kbInventory:                 ebiten.KeyI,
+		kbGameMenu:                  ebiten.KeyEscape,
+		kbUseItem:                   ebiten.KeyEnter,
+		kbConfirm:                   ebiten.KeyEnter,
+		kbBacklog:                   ebiten.KeyTab,
+		kbFastForward:               ebiten.KeyControl,
 	}
+}
+
+func (g *Game) Load() {
+	loadingLog = append(loadingLog, "Loading Keybinds")
 
-	langFile, _ := ioutil.ReadFile("lang/ru_ru.json")
+	keyBinds = DefaultKeyBinds()
+
+	langFile, err := ioutil.ReadFile("lang/" + g.config.lang + ".json")
+	if err != nil {
+		log.Println("[Game] Failed to load language " + g.config.lang + ": " + err.Error())
+	}
 
 	langData = make(map[string]string)
 	_ = json.Unmarshal([]byte(langFile), &langData)
@@ -733,6 +839,10 @@ func (g *Game) Load() {
 	g.audioManager = NewAudioManager(g)
 
 	g.volumeMusic = 0.5
+	if g.config.mute {
+		g.volumeMusic = 0
+	}
+
 	g.audioManager.Load("bgm/stage_prepare", "sound/prepare.ogg")
 	g.audioManager.Load("bgm/main_menu", "sound/bgm_main_menu.ogg")
 	g.audioManager.Load("bgm/level0", "sound/bgm_level0.ogg")
@@ -778,7 +888,12 @@ func (g *Game) Load() {
 	g.fontRenderer = NewFontRenderer()
 
 	loadingLog = lazyAppend(loadingLog, "Loading level")
-	g.LoadLevel("level/level0.lvl")
+	g.LoadLevel(g.config.levelPath)
+
+	loadingLog = lazyAppend(loadingLog, "Loading world graph")
+	if g.worldGraph, err = LoadWorldGraph(defaultWorldGraphPath); err != nil {
+		log.Println("[World] Failed to load world graph: " + err.Error())
+	}
 
 	loadingLog = lazyAppend(loadingLog, "Setting camera zoom")
 	g.camera.SetZoom(4.0)
@@ -792,9 +907,8 @@ func (g *Game) Load() {
 	loadingLog = lazyAppend(loadingLog, "Appending character to entity list")
 	g.entities = append(g.entities, g.char)
 
-	for i := 0; i < 8; i++ {
-		g.entities = append(g.entities, CreateMichael(g))
-	}
+	loadingLog = lazyAppend(loadingLog, "Spawning level entities")
+	g.SpawnLevelEntities()
 
 	loadingLog = lazyAppend(loadingLog, "Creating Debug Screen")
 	g.debugScreen = CreateDebugScreen(g)
@@ -1277,7 +1391,7 @@ func (g *Game) DrawNineGrid2(screen *ebiten.Image, ng *NineGridInfo2, scale floa
 		op.GeoM.Reset()
 		op.GeoM.Scale(sw, sh)
 		op.GeoM.Translate(x, y)
-		screen.DrawImage(ng.tileCache[i], op)
+		DrawImage(screen, ng.tileCache[i], op)
 	}
 }
 
@@ -1295,51 +1409,51 @@ func (g *Game) DrawNineGrid(screen *ebiten.Image, img *ebiten.Image, scale float
 	op := &ebiten.DrawImageOptions{}
 	op.GeoM.Scale(scale, scale)
 	op.GeoM.Translate(x, y)
-	screen.DrawImage(cornerNW, op)
+	DrawImage(screen, cornerNW, op)
 
 	op.GeoM.Reset()
 	cornerTopWidth, _ := cornerTop.Size()
 	topTileWidth := (width - float64(inf.Left+inf.Right)*scale) / float64(cornerTopWidth)
 	op.GeoM.Scale(topTileWidth, scale)
 	op.GeoM.Translate(x+float64(inf.Left)*scale, y)
-	screen.DrawImage(cornerTop, op)
+	DrawImage(screen, cornerTop, op)
 
 	op.GeoM.Reset()
 	op.GeoM.Scale(scale, scale)
 	op.GeoM.Translate(x+(width-float64(inf.Right)*scale), y)
-	screen.DrawImage(cornerNE, op)
+	DrawImage(screen, cornerNE, op)
 
 	_, cornerLeftHeight := cornerLeft.Size()
 	op.GeoM.Reset()
 	leftTileHeight := (height - float64(inf.Top+inf.Bottom)*scale) / float64(cornerLeftHeight)
 	op.GeoM.Scale(scale, leftTileHeight)
 	op.GeoM.Translate(x, y+float64(inf.Top)*scale)
-	screen.DrawImage(cornerLeft, op)
+	DrawImage(screen, cornerLeft, op)
 
 	op.GeoM.Reset()
 	op.GeoM.Scale(topTileWidth, leftTileHeight)
 	op.GeoM.Translate(x+float64(inf.Left)*scale, y+float64(inf.Top)*scale)
-	screen.DrawImage(cornerCenter, op)
+	DrawImage(screen, cornerCenter, op)
 
 	op.GeoM.Reset()
 	op.GeoM.Scale(scale, leftTileHeight)
 	op.GeoM.Translate(x+(width-float64(inf.Right)*scale), y+float64(inf.Top)*scale)
-	screen.DrawImage(cornerRight, op)
+	DrawImage(screen, cornerRight, op)
 
 	op.GeoM.Reset()
 	op.GeoM.Scale(scale, scale)
 	op.GeoM.Translate(x, y+(height-float64(inf.Bottom)*scale))
-	screen.DrawImage(cornerSW, op)
+	DrawImage(screen, cornerSW, op)
 
 	op.GeoM.Reset()
 	op.GeoM.Scale(topTileWidth, scale)
 	op.GeoM.Translate(x+float64(inf.Left)*scale, y+(height-float64(inf.Bottom)*scale))
-	screen.DrawImage(cornerBottom, op)
+	DrawImage(screen, cornerBottom, op)
 
 	op.GeoM.Reset()
 	op.GeoM.Scale(scale, scale)
 	op.GeoM.Translate(x+(width-float64(inf.Right)*scale), y+(height-float64(inf.Bottom)*scale))
-	screen.DrawImage(cornerSE, op)
+	DrawImage(screen, cornerSE, op)
 }
 
 var nineGridGUIFrame *NineGridInfo2
@@ -1419,29 +1533,92 @@ func (g *Game) DrawTextPopup(screen *ebiten.Image, text string, x int, y int, wi
 */
 
 func (g *Game) Draw(screen *ebiten.Image) {
+	prof := Profiler_GetInstance()
+	prof.BeginDraw()
+
 	if g.currentScreen != nil {
 		g.currentScreen.Draw(screen)
 	}
+
+	if g.console.IsOpen() {
+		g.console.Draw(screen)
+	}
+
+	g.entityListMutex.RLock()
+	entityCount := len(g.entities)
+	g.entityListMutex.RUnlock()
+
+	prof.EndDraw(entityCount)
+
+	if prof.IsEnabled() {
+		prof.Draw(screen, g.fontRenderer)
+	}
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 	return screenWidth, screenHeight
 }
 
+// Makes a level current, streaming in the chunks around the camera
+//
+// Entities of the previous level are kept, see SpawnLevelEntities.
 func (g *Game) LoadLevel(path string) error {
+	level, err := ReadLevel(path)
+	if err != nil {
+		return err
+	}
+
+	g.level = level
+	g.StreamChunks()
+	g.ResetSokoban()
+
+	return nil
+}
+
+// Reads a level with its entity file
+func ReadLevel(path string) (*Level, error) {
+	level, err := readLevelTiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defs, err := LoadLevelEntities(path)
+	if err != nil {
+		log.Println("[Level] Failed to load entities of " + path + ": " + err.Error())
+	}
+	level.SetEntityDefs(defs)
+
+	signals, err := LoadLevelSignals(path)
+	if err != nil {
+		log.Println("[Level] Failed to load signals of " + path + ": " + err.Error())
+	}
+	level.SetSignals(signals)
+
+	tiles, err := LoadLevelTiles(path)
+	if err != nil {
+		log.Println("[Level] Failed to load tile states of " + path + ": " + err.Error())
+	}
+	level.SetTileStates(tiles)
+
+	return level, nil
+}
+
+// Reads a legacy 15x15 level file or opens a streamed world directory
+func readLevelTiles(path string) (*Level, error) {
+	if IsWorldDir(path) {
+		return LoadWorld(path)
+	}
+
 	fd, err := os.Open(path)
 	if fd != nil {
 		defer fd.Close()
 	}
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	level := new(Level)
-	level.fileName = path
-	level.width = 15
-	level.height = 15
+	var tileLayers []TileLayer
 
 	for {
 		byteLayer := make([]byte, 225)
@@ -1450,7 +1627,7 @@ func (g *Game) LoadLevel(path string) error {
 		if err == io.EOF {
 			break
 		} else if err != nil {
-			return err
+			return nil, err
 		}
 
 		tileLayer := make([]Tile, 225)
@@ -1459,31 +1636,68 @@ func (g *Game) LoadLevel(path string) error {
 			tileLayer[i] = Tile(byteLayer[i])
 		}
 
-		level.tileLayers = append(level.tileLayers, tileLayer)
+		tileLayers = append(tileLayers, tileLayer)
 	}
 
-	g.level = level
+	level := NewLevel(15, 15, len(tileLayers))
+	level.fileName = path
 
-	return nil
+	for i, layer := range tileLayers {
+		for tilePos, tile := range layer {
+			*level.GetTilePtr(i, tilePos) = tile
+		}
+	}
+
+	return level, nil
 }
 
 func (g *Game) Exit() {
 	g.SetScreen(NewFarewellScreen(g))
 }
 
-func (g *Game) SaveLevel(path string) {
+func (g *Game) SaveLevel(path string) error {
 	log.Println("Writing level file " + path)
 
 	level := g.level
+	if level.store != nil && path != level.fileName {
+		return errors.New("a streamed world can only be saved to its own directory")
+	}
 
-	buffer := make([]byte, len(level.tileLayers)*len(level.tileLayers[0]))
-	for i, layer := range level.tileLayers {
-		for j, tile := range layer {
-			buffer[i*len(level.tileLayers[0])+j] = byte(tile)
+	// Existing files are rewritten so removing the last entity or wire sticks
+	defs := level.entityDefs
+	if _, err := os.Stat(LevelEntitiesPath(path)); defs.Player != nil || len(defs.Entities) > 0 || len(defs.Spawners) > 0 || len(defs.Minefields) > 0 || err == nil {
+		if err := SaveLevelEntities(path, defs); err != nil {
+			return err
 		}
 	}
 
-	_ = os.WriteFile(path, buffer, 0644)
+	if _, err := os.Stat(LevelSignalsPath(path)); level.HasWiring() || level.signals.ConveyorSpeed != 0 || err == nil {
+		if err := SaveLevelSignals(path, level.signals); err != nil {
+			return err
+		}
+	}
+
+	if _, err := os.Stat(LevelTilesPath(path)); len(level.growing) > 0 || err == nil {
+		if err := SaveLevelTiles(path, level.GetTileStates()); err != nil {
+			return err
+		}
+	}
+
+	if level.store != nil {
+		return g.SaveWorld()
+	}
+
+	size := level.width * level.height
+
+	buffer := make([]byte, level.GetLayerCount()*size)
+	for i := 0; i < level.GetLayerCount(); i++ {
+		for j := 0; j < size; j++ {
+			buffer[i*size+j] = byte(level.GetTile(i, j))
+		}
+	}
+	g.stampBallTiles(buffer, size)
+
+	return os.WriteFile(path, buffer, 0644)
 
 	/*
 		fd, _ := os.Open(path)
@@ -1492,29 +1706,141 @@ func (g *Game) SaveLevel(path string) {
 		fd.Write(buffer)
 	*/
 }
+
+// Advances the simulation by one frame, running zero or more world steps
+// depending on the time scale
+func (g *Game) UpdateWorld() {
+	g.timeAccumulator += g.timeScale
+
+	for g.timeAccumulator >= 1.0 {
+		g.timeAccumulator -= 1.0
+		g.StepWorld()
+	}
+}
+
+// Advances the simulation by one tick: entities, signals, conveyors, plants,
+// deaths, the camera and chunk streaming
+//
+// Does not read input or draw anything, so it can run without a window.
+func (g *Game) StepWorld() {
+	prof := Profiler_GetInstance()
+
+	// The world is frozen while the screen fades between levels
+	if g.transition != nil {
+		g.UpdateTransition()
+		g.camera.Update()
+		tickCounter++
+		return
+	}
+
+	g.UpdateSpawners()
+
+	prof.BeginScope(profScopeEntityUpdate)
+	for _, entity := range g.entities {
+		entity.Update()
+		entity.GetLivingEntity().UpdateClip()
+	}
+	prof.EndScope(profScopeEntityUpdate)
+
+	g.UpdateSignals()
+	g.UpdateConveyors()
+	g.UpdatePlants()
+
+	g.UpdateEffects()
+
+	a := &g.entities
+	for i := len(*a) - 1; i >= 0; i-- {
+		if (*a)[i].GetHealth() <= 0 {
+			dead := (*a)[i].GetLivingEntity()
+			g.PlayEffect(dead.sprite, dead.clips.GetClips(), clipDeath, dead.worldPos)
+
+			g.entityListMutex.Lock()
+
+			(*a)[i] = (*a)[len(*a)-1]
+			(*a)[len(*a)-1] = nil
+			*a = (*a)[:len(*a)-1]
+			g.entityListMutex.Unlock()
+		}
+	}
+
+	g.camera.Update()
+	g.StreamChunks()
+
+	tickCounter++
+}
 func (g *Game) GetUnderlyingTilesAtTilePos(tilePos int) []*Tile {
 	var tiles []*Tile
 
-	for _, layer := range g.level.tileLayers {
-		tiles = append(tiles, &layer[tilePos])
+	for i := 0; i < g.level.GetLayerCount(); i++ {
+		if tile := g.level.GetTilePtr(i, tilePos); tile != nil {
+			tiles = append(tiles, tile)
+		}
 	}
 
 	return tiles
 }
 
+// Flips wired switches, picks up items, reveals minefield tiles and starts
+// a level transition when the player steps on a linked tile
+func (g *Game) ProcessTileEntering(e ILivingEntity, tilePos int) {
+	if e != g.char {
+		return
+	}
+
+	if g.level.HasWiring() {
+		g.toggleSwitch(tilePos)
+	}
+
+	g.PickUpItems(e, tilePos)
+	g.StepOnMinefield(e, tilePos)
+
+	link := g.GetLevelLinkAt(tilePos)
+	if link == nil {
+		return
+	}
+
+	for i := 0; i < g.level.GetLayerCount(); i++ {
+		if tile := g.level.GetTile(i, tilePos); IsLinkTile(tile) {
+			g.StartLevelTransition(link, tile)
+			return
+		}
+	}
+}
+
 func (g *Game) ProcessTileLeaving(e ILivingEntity, tilePos int) {
+	// Wired thorns follow their channel instead
+	if g.level.HasWiring() {
+		return
+	}
+
 	tiles := g.GetUnderlyingTilesAtTilePos(tilePos)
 
 	for _, tile := range tiles {
 		switch *tile {
 		case tileIDThorns:
 			*tile = tileIDThornsActive
+			g.level.MarkTileDirty(tilePos)
 
 			g.level.ReplaceAll(tileIDSwitchActive, tileIDSwitch)
 		}
 	}
 }
 
+// Draws a large centered message in the upper third of the screen
+func (g *Game) DrawBanner(screen *ebiten.Image, text string) {
+	fontRenderer := g.fontRenderer
+	fontRenderer.PushState()
+	fontRenderer.Reset()
+	fontRenderer.SetScale(g.view.guiScale * 2)
+	fontRenderer.SetTextColor(color.RGBA{255, 224, 64, 255})
+	fontRenderer.EnableShadow(true)
+
+	dim := fontRenderer.GetStringDimensions(text)
+	fontRenderer.DrawTextAt(screen, text, Vec2f{(screenWidth - dim.X) / 2, screenHeight/3 - dim.Y/2})
+
+	fontRenderer.PopState()
+}
+
 func (g *Game) DrawModeTitle(screen *ebiten.Image, text string) {
 	fontRenderer := g.fontRenderer
 
@@ -1546,7 +1872,7 @@ func (g *Game) DrawEntityInfo(screen *ebiten.Image, e ILivingEntity) {
 	op.GeoM.Translate(float64((screenWidth-(screenWidth-128))/2)+16, float64(screenHeight-128)+16)
 
 	sprite := e.GetLivingEntity().sprite.SubImage(image.Rect(0, tileSize*3, tileSize, tileSize*4)).(*ebiten.Image)
-	screen.DrawImage(sprite, op)
+	DrawImage(screen, sprite, op)
 
 	fontRenderer := g.fontRenderer
 
@@ -1593,41 +1919,75 @@ func (g *Game) DrawEntityInfo(screen *ebiten.Image, e ILivingEntity) {
 func (g *Game) DrawWorld(screen *ebiten.Image) {
 	DrawSimpleRepeatedTexture(screen, worldBorderImage, 1.0, 0, 0, float64(screenWidth), float64(screenHeight))
 
-	for _, l := range g.level.tileLayers {
-		for i, t := range l {
-			if t == tileIDEmpty {
-				continue
-			}
-
-			op := &ebiten.DrawImageOptions{}
+	if g.tilemapRenderer == nil || g.tilemapRenderer.GetLevel() != g.level {
+		g.tilemapRenderer = NewTilemapRenderer(g.level)
+	}
 
-			cameraZoom := g.camera.GetZoom()
+	g.tilemapRenderer.Draw(screen, &g.camera)
+	g.DrawMinefields(screen)
+}
 
-			tileWorldPos := Vec2f{
-				float64(i % g.level.width * tileSize),
-				float64(i / g.level.width * tileSize),
-			}
-			pos := g.camera.WorldToScreen2(tileWorldPos)
+type NextScreenBuilder func(*Game) IScreen
 
-			tile := GetTileSprite(tilesImage, tileXNum, tileSize, t)
+const defaultLevelPath = "level/level0.lvl"
 
-			op.GeoM.Scale(cameraZoom, cameraZoom)
-			op.GeoM.Translate(pos.X, pos.Y)
-			screen.DrawImage(tile, op)
-		}
+func NewGame(config GameConfig) *Game {
+	g := new(Game)
+	g.config = config
+	g.timeScale = 1.0
+	g.console = NewConsole(g)
+	g.worldGraph = NewWorldGraph(defaultWorldGraphPath)
+	g.sokoban = NewSokoban()
+	g.AddBallListener(ballScoreListener)
+
+	if keyBinds == nil {
+		keyBinds = DefaultKeyBinds()
 	}
-}
 
-type NextScreenBuilder func(*Game) IScreen
+	return g
+}
 
-func CreateGame() (*Game, error) {
-	g := new(Game)
+func CreateGame(config GameConfig) (*Game, error) {
+	g := NewGame(config)
 
 	g.fontRenderer = NewFontRenderer()
 	g.view.guiScale = 2.0
 
-	if directScreenSet {
-		screenBuilder, has := screenNames[directScreenName]
+	if config.hasSeed {
+		g.SetSeed(config.seed)
+	} else {
+		g.SetSeed(time.Now().UnixNano())
+	}
+	g.input = NewLiveInputSource()
+
+	if config.replayPath != "" {
+		replay, err := LoadReplay(g, config.replayPath)
+		if err != nil {
+			return nil, err
+		}
+
+		g.SetSeed(replay.GetSeed())
+		g.config.levelPath = replay.GetLevel()
+		g.input = replay
+
+		g.systemFontRenderer = NewFontRenderer()
+		g.Load()
+		g.SetScreen(NewGameplayScreen(g))
+
+		return g, nil
+	}
+
+	if config.recordPath != "" {
+		recorder, err := NewInputRecorder(g, g.input, config.recordPath)
+		if err != nil {
+			return nil, err
+		}
+
+		g.input = recorder
+	}
+
+	if config.screenName != "" {
+		screenBuilder, has := screenNames[config.screenName]
 		if has {
 			g.SetScreen(screenBuilder(g))
 		}
@@ -1660,99 +2020,10 @@ func GetAudioContext() *audio.Context {
 	return g_audioContext
 }
 
-var directScreenSet bool
-var directScreenName string
-
 type ScreenBuilder func(*Game) IScreen
 
 var screenNames map[string]ScreenBuilder
 
-var g_currentProcessCmdLine *CommandLine
-
-type SwitchMap map[string]string
-
-type CommandLine struct {
-	switches   SwitchMap
-	argv       []string
-	begin_args uint
-}
-
-func (cmdLine *CommandLine) Init() bool {
-	if g_currentProcessCmdLine == nil {
-		return false
-	}
-
-	g_currentProcessCmdLine = new(CommandLine)
-	cmdLine.FromOsArgs(os.Args)
-	return true
-}
-
-type AnyMap map[interface{}]interface{}
-
-func (cmdLine *CommandLine) FromOsArgs(argv []string) {
-	cmdLine.argv = []string{""}
-
-	// Clear the switch map
-	for k := range cmdLine.switches {
-		delete(cmdLine.switches, k)
-	}
-
-	cmdLine.begin_args = 1
-
-	if len(argv) == 0 {
-		cmdLine.SetProgram("")
-	} else {
-		cmdLine.SetProgram(argv[0])
-	}
-}
-
-func (cmdLine *CommandLine) GetSwitches() *SwitchMap {
-	return &cmdLine.switches
-}
-
-func (cmdLine *CommandLine) SetProgram(program string) {
-	cmdLine.argv[0] = strings.TrimSpace(program)
-}
-
-var switchPrefixes []string = []string{"--", "-", "/"}
-
-func GetSwitchPrefixLen(arg string) int {
-	for _, prefix := range switchPrefixes {
-		if substr(arg, 0, strlen(prefix)) == "prefix" {
-			return strlen(prefix)
-		}
-	}
-
-	return 0
-}
-
-var switchValueSeparator string = "="
-
-/*
- *  Fill in |switch_string| and |switch_value| if |string| is a switch.
- *	This will preserve the input switch prefix in the output |switch_string|.
- */
-func IsSwitch(arg string) (bool, string, string) {
-	prefixLen := GetSwitchPrefixLen(arg)
-	if prefixLen == 0 || prefixLen == strlen(arg) {
-		return false, "", ""
-	}
-
-	pair := strings.SplitN(arg, switchValueSeparator, 2)
-	return true, pair[0], pair[1]
-}
-
-func IsSwitchWithKey(arg string, key string) bool {
-	prefixLen := GetSwitchPrefixLen(arg)
-
-	if prefixLen == 0 || prefixLen == strlen(arg) {
-		return false
-	}
-
-	equalsPos := strings.Index(arg, switchValueSeparator)
-	return substr(arg, prefixLen, equalsPos-prefixLen) == key
-}
-
 func RegisterScreenName(name string, builder func(g *Game) IScreen) {
 	screenNames[name] = builder
 }
@@ -1784,22 +2055,25 @@ func main() {
 		return NewGameplayScreen(g)
 	})
 
-	argv := os.Args[1:]
-	for _, argument := range argv {
-		if strings.HasPrefix(argument, "--screen=") {
-			param := strings.SplitN(argument, "=", 2)[1]
-
-			_, has := screenNames[param]
-			if has {
-				directScreenSet = true
-				directScreenName = param
-				log.Println("Setting screen " + param)
-				break
-			}
-		}
+	cmdLine := CommandLine_ForCurrentProcess()
+
+	config, warnings, err := ParseGameConfig(cmdLine)
+	for _, warning := range warnings {
+		log.Println("[CommandLine] " + warning)
+	}
+
+	if config.showHelp {
+		PrintHelp(os.Stdout, cmdLine.GetProgram())
+		return
 	}
 
-	game, err := CreateGame()
+	if err != nil {
+		log.Println("[CommandLine] " + err.Error())
+		PrintHelp(os.Stderr, cmdLine.GetProgram())
+		os.Exit(2)
+	}
+
+	game, err := CreateGame(config)
 	if err != nil {
 		log.Fatal(err)
 		return
@@ -1811,23 +2085,21 @@ func main() {
 	iconImages = append(iconImages, icon)
 
 	ebiten.SetWindowIcon(iconImages)
-	ebiten.SetWindowSize(screenWidth, screenHeight)
+	ebiten.SetWindowSize(int(screenWidth*config.windowScale), int(screenHeight*config.windowScale))
+	ebiten.SetFullscreen(config.fullscreen)
 
-	var cmdLine string
-	var title string
-	for _, arg := range os.Args[1:] {
-		cmdLine += arg + " "
-	}
-
-	title = "Ronery"
+	title := "Ronery"
 
 	if len(os.Args[1:]) > 0 {
-		title += " (" + cmdLine + ")"
+		title += " (" + strings.Join(os.Args[1:], " ") + ")"
 	}
 
 	ebiten.SetWindowTitle(title)
 
-	if err := ebiten.RunGame(game); err != nil {
+	err = ebiten.RunGame(game)
+	game.CloseInput()
+
+	if err != nil {
 		log.Fatal(err)
 	}
 }
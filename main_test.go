@@ -0,0 +1,47 @@
+//go:build gpu
+// +build gpu
+
+package main
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+var errTestsFinished = errors.New("tests finished")
+
+// Runs the test binary inside a single ebiten frame
+//
+// Reading pixels back from an *ebiten.Image is only possible while the game
+// loop is running, so with the gpu tag all tests execute from the first
+// Update call. This opens a window; the other tests run headless without it.
+type testRunLoop struct {
+	m    *testing.M
+	code int
+}
+
+func (l *testRunLoop) Update() error {
+	l.code = l.m.Run()
+	return errTestsFinished
+}
+
+func (l *testRunLoop) Draw(*ebiten.Image) {
+
+}
+
+func (l *testRunLoop) Layout(int, int) (int, int) {
+	return screenWidth, screenHeight
+}
+
+func TestMain(m *testing.M) {
+	loop := &testRunLoop{m: m}
+
+	if err := ebiten.RunGame(loop); err != nil && err != errTestsFinished {
+		panic(err)
+	}
+
+	os.Exit(loop.code)
+}
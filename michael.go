@@ -9,6 +9,6 @@ func CreateMichael(g *Game) *Michael {
 	e.etype = e
 	e._ConstructLivingEntity(g)
 	e.entityClass = langData["entity_michael"]
-	e.sprite = michaelSprite
+	e.SetSprite(michaelSprite, "michael")
 	return e
 }
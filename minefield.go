@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Health a mine takes from the player
+const minefieldMineDamage = 50.0
+
+// Ticks the cleared banner stays on screen
+const minefieldClearedBannerTicks = 180
+
+// Rectangular minefield region of a level, in tiles
+//
+// Mines are placed when the player first steps in, never on that tile. A
+// zero seed uses the game seed. Revealed tiles and flags are drawn over the
+// level without changing its tiles.
+type JSONMinefield struct {
+	X      int   `json:"x"`
+	Y      int   `json:"y"`
+	Width  int   `json:"width"`
+	Height int   `json:"height"`
+	Mines  int   `json:"mines"`
+	Seed   int64 `json:"seed,omitempty"`
+}
+
+type Minefield struct {
+	def        JSONMinefield
+	mines      []bool
+	revealed   []bool
+	flagged    []bool
+	generated  bool
+	exploded   int
+	complete   bool
+	clearTick  int
+	safeTiles  int
+	safeOpened int
+}
+
+// Checks that the field has tiles and leaves at least one of them free
+func (def JSONMinefield) Validate() error {
+	if def.Width <= 0 || def.Height <= 0 {
+		return fmt.Errorf("minefield at %d, %d has an empty area of %dx%d", def.X, def.Y, def.Width, def.Height)
+	}
+
+	if area := def.Width * def.Height; def.Mines < 0 || def.Mines >= area {
+		return fmt.Errorf("minefield at %d, %d can not hold %d mines in %dx%d", def.X, def.Y, def.Mines, def.Width, def.Height)
+	}
+
+	return nil
+}
+
+// Creates a minefield from a definition that passed Validate
+func NewMinefield(def JSONMinefield) *Minefield {
+	m := new(Minefield)
+	m.def = def
+
+	area := def.Width * def.Height
+	m.mines = make([]bool, area)
+	m.revealed = make([]bool, area)
+	m.flagged = make([]bool, area)
+	m.safeTiles = area - m.def.Mines
+	return m
+}
+
+func (m *Minefield) Contains(x, y int) bool {
+	return x >= m.def.X && y >= m.def.Y && x < m.def.X+m.def.Width && y < m.def.Y+m.def.Height
+}
+
+func (m *Minefield) index(x, y int) int {
+	return (y-m.def.Y)*m.def.Width + x - m.def.X
+}
+
+func (m *Minefield) IsComplete() bool {
+	return m.complete
+}
+
+// Returns how many mines went off
+func (m *Minefield) GetExplodedCount() int {
+	return m.exploded
+}
+
+// Places the mines, keeping the given tile free
+func (m *Minefield) generate(seed int64, safeIndex int) {
+	if m.def.Seed != 0 {
+		seed = m.def.Seed
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+
+	for placed := 0; placed < m.def.Mines; {
+		i := rng.Intn(len(m.mines))
+		if i == safeIndex || m.mines[i] {
+			continue
+		}
+
+		m.mines[i] = true
+		placed++
+	}
+
+	m.generated = true
+}
+
+// Counts mines around a tile of the field
+func (m *Minefield) countAdjacent(x, y int) int {
+	count := 0
+
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if (dx != 0 || dy != 0) && m.Contains(x+dx, y+dy) && m.mines[m.index(x+dx, y+dy)] {
+				count++
+			}
+		}
+	}
+
+	return count
+}
+
+// Returns the tile showing a revealed count of adjacent mines
+func minefieldDigitTile(count int) Tile {
+	switch {
+	case count == 0:
+		return tileIDPavedRoad
+	case count <= 3:
+		return tileIDColorDigit1 + Tile(count-1)
+	}
+
+	return tileIDBloodDigit1 + Tile(count-1)
+}
+
+// Returns the minefield at the tile, or nil
+func (lv *Level) GetMinefieldAt(x, y int) *Minefield {
+	for _, m := range lv.minefields {
+		if m.Contains(x, y) {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// Returns the tile drawn over a field tile: a flag, a revealed mine or
+// count, tileIDEmpty if there is nothing to show
+func (m *Minefield) GetMark(x, y int) Tile {
+	i := m.index(x, y)
+
+	switch {
+	case m.flagged[i]:
+		return tileIDMineFlag
+	case !m.revealed[i]:
+		return tileIDEmpty
+	case m.mines[i]:
+		return tileIDMine
+	}
+
+	return minefieldDigitTile(m.countAdjacent(x, y))
+}
+
+// Flags keep entities off the tile like a solid one
+func (lv *Level) IsMineFlagAt(tilePos int) bool {
+	x, y := tilePos%lv.width, tilePos/lv.width
+
+	m := lv.GetMinefieldAt(x, y)
+	return m != nil && m.flagged[m.index(x, y)]
+}
+
+// Reveals the tile the player stepped on; a mine explodes, an empty tile
+// reveals its neighbours
+func (g *Game) StepOnMinefield(e ILivingEntity, tilePos int) {
+	level := g.level
+	x, y := tilePos%level.width, tilePos/level.width
+
+	m := level.GetMinefieldAt(x, y)
+	if m == nil || m.complete {
+		return
+	}
+
+	if !m.generated {
+		m.generate(g.seed, m.index(x, y))
+	}
+
+	i := m.index(x, y)
+	if m.revealed[i] || m.flagged[i] {
+		return
+	}
+
+	if m.mines[i] {
+		m.revealed[i] = true
+		m.exploded++
+		e.GetLivingEntity().Damage(minefieldMineDamage)
+		log.Printf("[Minefield] Mine at %d, %d went off", x, y)
+		return
+	}
+
+	g.revealMinefield(m, x, y)
+
+	if m.safeOpened == m.safeTiles {
+		m.complete = true
+		m.clearTick = tickCounter
+		log.Printf("[Minefield] Field at %d, %d cleared", m.def.X, m.def.Y)
+	}
+}
+
+// Reveals safe tiles starting at the given one, spreading over tiles
+// without adjacent mines
+func (g *Game) revealMinefield(m *Minefield, x, y int) {
+	queue := [][2]int{{x, y}}
+
+	for len(queue) > 0 {
+		x, y := queue[0][0], queue[0][1]
+		queue = queue[1:]
+
+		i := m.index(x, y)
+		if m.revealed[i] || m.flagged[i] || m.mines[i] {
+			continue
+		}
+
+		m.revealed[i] = true
+		m.safeOpened++
+
+		if m.countAdjacent(x, y) > 0 {
+			continue
+		}
+
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if m.Contains(x+dx, y+dy) {
+					queue = append(queue, [2]int{x + dx, y + dy})
+				}
+			}
+		}
+	}
+}
+
+// Places or removes a flag on the unrevealed minefield tile in front of
+// the player
+func (g *Game) ToggleMineFlag() bool {
+	if g.char == nil {
+		return false
+	}
+
+	e := g.char.GetLivingEntity()
+
+	tilePos, err := e.GetTilePos()
+	if err != nil {
+		return false
+	}
+
+	target, ok := g.neighbourTile(tilePos, e.look)
+	if !ok {
+		return false
+	}
+
+	level := g.level
+	x, y := target%level.width, target/level.width
+
+	m := level.GetMinefieldAt(x, y)
+	if m == nil || m.revealed[m.index(x, y)] {
+		return false
+	}
+
+	i := m.index(x, y)
+	m.flagged[i] = !m.flagged[i]
+	return true
+}
+
+// Describes the level's minefields for the console
+func (lv *Level) DescribeMinefields() string {
+	text := ""
+
+	for _, m := range lv.minefields {
+		if text != "" {
+			text += "; "
+		}
+
+		text += fmt.Sprintf("%d,%d %dx%d: %d/%d open, %d exploded", m.def.X, m.def.Y,
+			m.def.Width, m.def.Height, m.safeOpened, m.safeTiles, m.exploded)
+
+		if m.complete {
+			text += ", cleared"
+		}
+	}
+
+	return text
+}
+
+// Draws the flags and revealed tiles of the level's minefields
+func (g *Game) DrawMinefields(screen *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+	zoom := g.camera.GetZoom()
+
+	for _, m := range g.level.minefields {
+		for y := m.def.Y; y < m.def.Y+m.def.Height; y++ {
+			for x := m.def.X; x < m.def.X+m.def.Width; x++ {
+				mark := m.GetMark(x, y)
+				if mark == tileIDEmpty {
+					continue
+				}
+
+				pos := g.camera.WorldToScreen2(Vec2f{float64(x * tileSize), float64(y * tileSize)})
+
+				op.GeoM.Reset()
+				op.GeoM.Scale(zoom, zoom)
+				op.GeoM.Translate(pos.X, pos.Y)
+				DrawImage(screen, GetTileSprite(tilesImage, tileXNum, tileSize, mark), op)
+			}
+		}
+	}
+}
+
+func (g *Game) DrawMinefieldBanner(screen *ebiten.Image) {
+	for _, m := range g.level.minefields {
+		if m.complete && tickCounter-m.clearTick <= minefieldClearedBannerTicks {
+			g.DrawBanner(screen, I18n("string_minefield_cleared", "Minefield cleared"))
+			return
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import "testing"
+
+// Adds a minefield with mines at the given field indices
+func addTestMinefield(level *Level, def JSONMinefield, mines ...int) *Minefield {
+	def.Mines = len(mines)
+	m := NewMinefield(def)
+	for _, i := range mines {
+		m.mines[i] = true
+	}
+	m.generated = true
+
+	level.minefields = append(level.minefields, m)
+	return m
+}
+
+func TestMinefieldFloodReveal(t *testing.T) {
+	level := newTestLevel()
+	m := addTestMinefield(level, JSONMinefield{X: 4, Y: 4, Width: 5, Height: 5}, 24)
+	level.SetTile(testObjectLayer, tileIndex(level, 7, 7), tileIDPavedRoad2)
+
+	sim, _ := newTestSimulation(t, level)
+	g := sim.GetGame()
+	g.StepOnMinefield(g.char, tileIndex(level, 4, 4))
+
+	if !m.IsComplete() {
+		t.Fatalf("field not cleared, %d of %d safe tiles open", m.safeOpened, m.safeTiles)
+	}
+
+	if got := m.GetMark(4, 4); got != tileIDPavedRoad {
+		t.Errorf("empty tile mark = %d, want %d", got, tileIDPavedRoad)
+	}
+
+	if got := m.GetMark(7, 7); got != tileIDColorDigit1 {
+		t.Errorf("mark next to the mine = %d, want %d", got, tileIDColorDigit1)
+	}
+
+	if got := m.GetMark(8, 8); got != tileIDEmpty {
+		t.Errorf("mine mark = %d, want it hidden", got)
+	}
+
+	if got := level.GetTile(testObjectLayer, tileIndex(level, 7, 7)); got != tileIDPavedRoad2 {
+		t.Errorf("level tile under the mark = %d, want it kept", got)
+	}
+}
+
+func TestMineDamagesPlayer(t *testing.T) {
+	level := newTestLevel()
+	m := addTestMinefield(level, JSONMinefield{X: 4, Y: 3, Width: 3, Height: 1}, 0)
+
+	sim, input := newTestSimulation(t, level)
+	g := sim.GetGame()
+	sim.Spawn(g.char, 3, 3)
+	g.char.GetLivingEntity().look = LooksRight
+
+	// A flag keeps the player off the mine
+	if !g.ToggleMineFlag() {
+		t.Fatal("flag was not placed")
+	}
+
+	input.Hold(0, tileSize, kbPlayerMoveRight)
+	sim.Step(tileSize + 1)
+
+	if tilePos, _ := g.char.GetTilePos(); tilePos != tileIndex(level, 3, 3) {
+		t.Fatal("player walked onto the flag")
+	}
+
+	if !g.ToggleMineFlag() || m.GetMark(4, 3) != tileIDEmpty || level.IsMineFlagAt(tileIndex(level, 4, 3)) {
+		t.Fatal("flag was not removed")
+	}
+
+	input.Hold(input.NextTick(), tileSize, kbPlayerMoveRight)
+	sim.Step(tileSize + 1)
+
+	if m.GetExplodedCount() != 1 || g.char.GetHealth() != 100-minefieldMineDamage {
+		t.Fatalf("exploded = %d, health = %g after the mine", m.GetExplodedCount(), g.char.GetHealth())
+	}
+
+	if got := m.GetMark(4, 3); got != tileIDMine || level.GetTile(testObjectLayer, tileIndex(level, 4, 3)) != tileIDEmpty {
+		t.Fatalf("mine mark = %d, want %d over the untouched level", got, tileIDMine)
+	}
+}
+
+func TestMinefieldGenerationIsSeeded(t *testing.T) {
+	def := JSONMinefield{Width: 8, Height: 8, Mines: 10, Seed: 42}
+
+	a, b := NewMinefield(def), NewMinefield(def)
+	a.generate(1, 0)
+	b.generate(2, 0)
+
+	count := 0
+	for i := range a.mines {
+		if a.mines[i] != b.mines[i] {
+			t.Fatal("same seed placed different mines")
+		}
+		if a.mines[i] {
+			count++
+		}
+	}
+
+	if count != def.Mines || a.mines[0] {
+		t.Fatalf("placed %d mines, first tile mined: %v", count, a.mines[0])
+	}
+}
+
+func TestMinefieldSkipsInvalidDefs(t *testing.T) {
+	level := newTestLevel()
+	level.SetEntityDefs(&JSONLevelEntities{Minefields: []JSONMinefield{
+		{Width: -2, Height: 3, Mines: 1},
+		{Width: 0, Height: 0},
+		{Width: 2, Height: 2, Mines: 4},
+		{Width: 2, Height: 2, Mines: -1},
+		{Width: 2, Height: 2, Mines: 3},
+	}})
+
+	if len(level.minefields) != 1 || level.minefields[0].def.Mines != 3 {
+		t.Fatalf("kept %d minefields, want only the valid one", len(level.minefields))
+	}
+}
@@ -10,7 +10,7 @@ func CreateMonobear(g *Game) *Monobear {
 	e._ConstructLivingEntity(g)
 	e.entityClass = langData["entity_monobear"]
 	e.speedModifier = 0.75
-	e.sprite = monobearSprite
+	e.SetSprite(monobearSprite, "monobear")
 	// e.spells = append(e.spells, CreateMonobearExplosion(e))
 	return e
 }
@@ -10,6 +10,6 @@ func CreateMorgen(g *Game) *Morgen {
 	e._ConstructLivingEntity(g)
 	e.entityClass = langData["entity_morgen"]
 	e.speedModifier = 0.75
-	e.sprite = morgenSprite
+	e.SetSprite(morgenSprite, "morgen")
 	return e
 }
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Ticks until a harvested berry bush bears berries again
+const berryRegrowTicks = 60 * 60
+
+// Ticks a planted flower spends in each growth stage
+const flowerGrowTicks = 60 * 30
+
+// Item planted with the interaction key on free grass
+const flowerSeedsItem = "flower_seeds"
+
+// Harvesting and growth behavior of a plant tile
+//
+// Harvesting gives Count of Item and leaves the Harvested tile. A growing
+// tile turns into Next after GrowTicks; only harvested and planted tiles
+// grow, decoration placed in the editor stays as it is.
+type PlantDesc struct {
+	Item      string
+	Count     int
+	Harvested Tile
+	GrowTicks int
+	Next      Tile
+}
+
+func (p PlantDesc) IsHarvestable() bool {
+	return p.Item != ""
+}
+
+var plantDescs = map[Tile]PlantDesc{
+	tileIDBerryBush:    {Item: "berries", Count: 2, Harvested: tileIDBush},
+	tileIDBush:         {GrowTicks: berryRegrowTicks, Next: tileIDBerryBush},
+	tileIDFlowerSprout: {GrowTicks: flowerGrowTicks, Next: tileIDFlower1},
+	tileIDFlower1:      {Item: flowerSeedsItem, Count: 2, Harvested: tileIDEmpty},
+	tileIDBlueRose:     {Item: "blue_rose", Count: 1, Harvested: tileIDEmpty},
+}
+
+type tileStateKey struct {
+	layer   int
+	tilePos int
+}
+
+// Growing tile of a level, saved with it. Timer counts the ticks left.
+type JSONTileState struct {
+	X     int `json:"x"`
+	Y     int `json:"y"`
+	Layer int `json:"layer"`
+	Timer int `json:"timer"`
+}
+
+// Tile metadata of a level, stored next to the level file
+type JSONLevelTiles struct {
+	Growing []JSONTileState `json:"growing,omitempty"`
+}
+
+func LevelTilesPath(levelPath string) string {
+	if IsWorldDir(levelPath) {
+		return filepath.Join(levelPath, "tiles.json")
+	}
+
+	return strings.TrimSuffix(levelPath, filepath.Ext(levelPath)) + ".tiles.json"
+}
+
+func LoadLevelTiles(levelPath string) (*JSONLevelTiles, error) {
+	tiles := new(JSONLevelTiles)
+
+	data, err := os.ReadFile(LevelTilesPath(levelPath))
+	if os.IsNotExist(err) {
+		return tiles, nil
+	} else if err != nil {
+		return tiles, err
+	}
+
+	err = json.Unmarshal(data, tiles)
+	return tiles, err
+}
+
+func SaveLevelTiles(levelPath string, tiles *JSONLevelTiles) error {
+	data, err := json.MarshalIndent(tiles, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(LevelTilesPath(levelPath), data, 0644)
+}
+
+func (lv *Level) SetTileStates(tiles *JSONLevelTiles) {
+	lv.growing = make(map[tileStateKey]int)
+
+	for _, state := range tiles.Growing {
+		if lv.ContainsTile(state.X, state.Y) && state.Layer < lv.GetLayerCount() {
+			lv.growing[tileStateKey{state.Layer, state.Y*lv.width + state.X}] = state.Timer
+		}
+	}
+}
+
+// Returns the growing tiles in a stable order for saving
+func (lv *Level) GetTileStates() *JSONLevelTiles {
+	tiles := new(JSONLevelTiles)
+
+	for key, timer := range lv.growing {
+		tiles.Growing = append(tiles.Growing, JSONTileState{
+			X:     key.tilePos % lv.width,
+			Y:     key.tilePos / lv.width,
+			Layer: key.layer,
+			Timer: timer,
+		})
+	}
+
+	sort.Slice(tiles.Growing, func(i, j int) bool {
+		a, b := tiles.Growing[i], tiles.Growing[j]
+		if a.Layer != b.Layer {
+			return a.Layer < b.Layer
+		}
+		return a.Y*lv.width+a.X < b.Y*lv.width+b.X
+	})
+
+	return tiles
+}
+
+// Places a plant tile and starts its growth timer if it grows
+func (lv *Level) setPlant(layer, tilePos int, tile Tile) {
+	lv.SetTile(layer, tilePos, tile)
+
+	key := tileStateKey{layer, tilePos}
+	if plant, has := plantDescs[tile]; has && plant.GrowTicks > 0 {
+		lv.growing[key] = plant.GrowTicks
+	} else {
+		delete(lv.growing, key)
+	}
+}
+
+// Advances growing tiles of loaded chunks by one tick
+func (g *Game) UpdatePlants() {
+	level := g.level
+
+	for key, timer := range level.growing {
+		if !level.IsTileLoaded(key.tilePos) {
+			continue
+		}
+
+		plant, has := plantDescs[level.GetTile(key.layer, key.tilePos)]
+		if !has || plant.GrowTicks <= 0 {
+			// Replaced in the editor or by something else
+			delete(level.growing, key)
+			continue
+		}
+
+		if timer > 1 {
+			level.growing[key] = timer - 1
+			continue
+		}
+
+		level.setPlant(key.layer, key.tilePos, plant.Next)
+	}
+}
+
+// Harvests the plant in front of the player, or plants seeds on free grass
+func (g *Game) Interact() bool {
+	if g.char == nil {
+		return false
+	}
+
+	e := g.char.GetLivingEntity()
+
+	tilePos, err := e.GetTilePos()
+	if err != nil {
+		return false
+	}
+
+	target, ok := g.neighbourTile(tilePos, e.look)
+	if !ok {
+		return false
+	}
+
+	return g.Harvest(target) || g.PlantSeeds(target)
+}
+
+func (g *Game) Harvest(tilePos int) bool {
+	level := g.level
+
+	for layer := level.GetLayerCount() - 1; layer >= 0; layer-- {
+		tile := level.GetTile(layer, tilePos)
+
+		plant, has := plantDescs[tile]
+		if !has || !plant.IsHarvestable() {
+			continue
+		}
+
+		if left := g.char.AddItem(plant.Item, plant.Count); left > 0 {
+			g.char.TakeItem(plant.Item, plant.Count-left)
+			log.Println("[Plants] Inventory is full")
+			return false
+		}
+
+		level.setPlant(layer, tilePos, plant.Harvested)
+		log.Printf("[Plants] Harvested %d %s", plant.Count, plant.Item)
+		return true
+	}
+
+	return false
+}
+
+// Plants a flower on a grass tile with a free layer above it
+func (g *Game) PlantSeeds(tilePos int) bool {
+	level := g.level
+	layer := level.GetLayerCount() - 1
+
+	if layer < 1 || level.GetTile(0, tilePos) != tileIDGrass || level.GetTile(layer, tilePos) != tileIDEmpty {
+		return false
+	}
+
+	if g.isEntityOnTile(tilePos) || !g.char.TakeItem(flowerSeedsItem, 1) {
+		return false
+	}
+
+	level.setPlant(layer, tilePos, tileIDFlowerSprout)
+	return true
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHarvestBerryBushRegrows(t *testing.T) {
+	level := newTestLevel()
+	bushPos := tileIndex(level, 3, 3)
+	level.SetTile(testObjectLayer, bushPos, tileIDBerryBush)
+
+	sim, input := newTestSimulation(t, level)
+	g := sim.GetGame()
+	sim.Spawn(g.char, 2, 3)
+	g.char.GetLivingEntity().look = LooksRight
+
+	input.Press(0, kbInteract)
+	sim.Step(1)
+
+	if got := g.char.GetItemCount("berries"); got != 2 {
+		t.Fatalf("berries = %d after harvesting, want 2", got)
+	}
+
+	if got := level.GetTile(testObjectLayer, bushPos); got != tileIDBush {
+		t.Fatalf("harvested tile = %d, want %d", got, tileIDBush)
+	}
+
+	// The harvest tick already counts towards regrowth
+	sim.Step(berryRegrowTicks - 2)
+
+	if got := level.GetTile(testObjectLayer, bushPos); got != tileIDBush {
+		t.Fatalf("bush regrew early into %d", got)
+	}
+
+	sim.Step(1)
+
+	if got := level.GetTile(testObjectLayer, bushPos); got != tileIDBerryBush {
+		t.Fatalf("bush tile = %d after regrowth, want %d", got, tileIDBerryBush)
+	}
+
+	if len(level.growing) != 0 {
+		t.Fatalf("%d tiles still growing", len(level.growing))
+	}
+}
+
+func TestHarvestNeedsRoomForWholeYield(t *testing.T) {
+	level := newTestLevel()
+	bushPos := tileIndex(level, 3, 3)
+	level.SetTile(testObjectLayer, bushPos, tileIDBerryBush)
+
+	sim, _ := newTestSimulation(t, level)
+	g := sim.GetGame()
+
+	// One berry fits, the second does not
+	g.char.AddItem("berries", 19)
+	g.char.AddItem("aid", 5*(inventoryColumns*inventoryRows-1))
+
+	if g.Harvest(bushPos) {
+		t.Fatal("bush was harvested into a nearly full inventory")
+	}
+
+	if got := g.char.GetItemCount("berries"); got != 19 || level.GetTile(testObjectLayer, bushPos) != tileIDBerryBush {
+		t.Fatalf("berries = %d and tile %d after the failed harvest", got, level.GetTile(testObjectLayer, bushPos))
+	}
+}
+
+func TestPlantedFlowerGrows(t *testing.T) {
+	level := newTestLevel()
+	flowerPos := tileIndex(level, 3, 3)
+
+	sim, _ := newTestSimulation(t, level)
+	g := sim.GetGame()
+	sim.Spawn(g.char, 2, 3)
+	g.char.GetLivingEntity().look = LooksRight
+
+	if g.Interact() {
+		t.Fatal("planted without seeds")
+	}
+
+	g.char.AddItem(flowerSeedsItem, 1)
+
+	if !g.Interact() || level.GetTile(testObjectLayer, flowerPos) != tileIDFlowerSprout {
+		t.Fatal("seeds were not planted")
+	}
+
+	sim.Step(flowerGrowTicks)
+
+	if got := level.GetTile(testObjectLayer, flowerPos); got != tileIDFlower1 {
+		t.Fatalf("flower tile = %d, want grown %d", got, tileIDFlower1)
+	}
+
+	if !g.Interact() || g.char.GetItemCount(flowerSeedsItem) != 2 {
+		t.Fatalf("seeds = %d after picking the flower, want 2", g.char.GetItemCount(flowerSeedsItem))
+	}
+}
+
+func TestLevelTilesSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "level0.lvl")
+
+	level := newTestLevel()
+	level.setPlant(testObjectLayer, tileIndex(level, 5, 2), tileIDFlowerSprout)
+	level.setPlant(testObjectLayer, tileIndex(level, 1, 7), tileIDBush)
+	level.growing[tileStateKey{testObjectLayer, tileIndex(level, 1, 7)}] = 42
+
+	if err := SaveLevelTiles(path, level.GetTileStates()); err != nil {
+		t.Fatal(err)
+	}
+
+	tiles, err := LoadLevelTiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := newTestLevel()
+	loaded.SetTileStates(tiles)
+
+	if !reflect.DeepEqual(loaded.growing, level.growing) {
+		t.Fatalf("loaded growing tiles = %v, want %v", loaded.growing, level.growing)
+	}
+}
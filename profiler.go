@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"runtime/metrics"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// Number of frames kept for graphs and export
+const profilerHistoryFrames = 240
+
+const (
+	profScopeWorldDraw    = "world draw"
+	profScopeEntityUpdate = "entity update"
+	profScopeGUI          = "gui"
+)
+
+// Measurements of a single frame. Times are in microseconds.
+type ProfilerFrame struct {
+	Frame        int                `json:"frame"`
+	UpdateTime   float64            `json:"update_us"`
+	DrawTime     float64            `json:"draw_us"`
+	DrawCalls    int                `json:"draw_calls"`
+	Glyphs       int                `json:"glyphs"`
+	Entities     int                `json:"entities"`
+	AllocBytes   uint64             `json:"alloc_bytes"`
+	AllocObjects uint64             `json:"alloc_objects"`
+	Scopes       map[string]float64 `json:"scopes_us"`
+}
+
+type Profiler struct {
+	enabled     bool
+	frames      []ProfilerFrame
+	current     ProfilerFrame
+	frameNumber int
+	updateStart time.Time
+	drawStart   time.Time
+	scopeStarts map[string]time.Time
+	allocBytes  uint64
+	allocObjs   uint64
+	samples     []metrics.Sample
+}
+
+var profilerOnce sync.Once
+var profiler *Profiler
+
+func NewProfiler() *Profiler {
+	p := new(Profiler)
+	p.scopeStarts = make(map[string]time.Time)
+	p.current.Scopes = make(map[string]float64)
+	p.samples = []metrics.Sample{
+		{Name: "/gc/heap/allocs:bytes"},
+		{Name: "/gc/heap/allocs:objects"},
+	}
+	return p
+}
+
+func Profiler_GetInstance() *Profiler {
+	profilerOnce.Do(func() {
+		profiler = NewProfiler()
+	})
+
+	return profiler
+}
+
+func (p *Profiler) IsEnabled() bool {
+	return p.enabled
+}
+
+// Starts or stops capturing. Captured frames are kept until the next start.
+func (p *Profiler) SetEnabled(enabled bool) {
+	if enabled && !p.enabled {
+		p.frames = nil
+		p.readAllocs()
+	}
+
+	p.enabled = enabled
+}
+
+func (p *Profiler) Toggle() {
+	p.SetEnabled(!p.enabled)
+}
+
+func (p *Profiler) readAllocs() (uint64, uint64) {
+	metrics.Read(p.samples)
+
+	var bytes, objects uint64
+	if p.samples[0].Value.Kind() == metrics.KindUint64 {
+		bytes = p.samples[0].Value.Uint64()
+	}
+	if p.samples[1].Value.Kind() == metrics.KindUint64 {
+		objects = p.samples[1].Value.Uint64()
+	}
+
+	deltaBytes, deltaObjects := bytes-p.allocBytes, objects-p.allocObjs
+	p.allocBytes, p.allocObjs = bytes, objects
+
+	return deltaBytes, deltaObjects
+}
+
+func (p *Profiler) BeginUpdate() {
+	if p.enabled {
+		p.updateStart = time.Now()
+	}
+}
+
+func (p *Profiler) EndUpdate() {
+	if p.enabled {
+		p.current.UpdateTime += microseconds(time.Since(p.updateStart))
+	}
+}
+
+func (p *Profiler) BeginDraw() {
+	if p.enabled {
+		p.drawStart = time.Now()
+	}
+}
+
+// Closes the frame: the draw time is taken and the frame goes to the history
+func (p *Profiler) EndDraw(entityCount int) {
+	if !p.enabled {
+		p.current = ProfilerFrame{Scopes: p.current.Scopes}
+		return
+	}
+
+	p.current.DrawTime = microseconds(time.Since(p.drawStart))
+	p.current.Entities = entityCount
+	p.current.AllocBytes, p.current.AllocObjects = p.readAllocs()
+	p.current.Frame = p.frameNumber
+	p.frameNumber++
+
+	p.frames = append(p.frames, p.current)
+	if len(p.frames) > profilerHistoryFrames {
+		p.frames = p.frames[1:]
+	}
+
+	p.current = ProfilerFrame{Scopes: make(map[string]float64)}
+}
+
+// Measures a named subsystem; scopes may repeat within a frame and add up
+func (p *Profiler) BeginScope(name string) {
+	if p.enabled {
+		p.scopeStarts[name] = time.Now()
+	}
+}
+
+func (p *Profiler) EndScope(name string) {
+	if p.enabled {
+		p.current.Scopes[name] += microseconds(time.Since(p.scopeStarts[name]))
+	}
+}
+
+func (p *Profiler) CountDrawCall() {
+	p.current.DrawCalls++
+}
+
+func (p *Profiler) CountGlyph() {
+	p.current.Glyphs++
+}
+
+func (p *Profiler) GetFrames() []ProfilerFrame {
+	return p.frames
+}
+
+func microseconds(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1000.0
+}
+
+// Draws an image and counts it for the profiler
+func DrawImage(dst *ebiten.Image, src *ebiten.Image, op *ebiten.DrawImageOptions) {
+	dst.DrawImage(src, op)
+	Profiler_GetInstance().CountDrawCall()
+}
+
+func (p *Profiler) scopeNames() []string {
+	names := make(map[string]bool)
+	for _, frame := range p.frames {
+		for name := range frame.Scopes {
+			names[name] = true
+		}
+	}
+
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+// Writes captured frames to a .csv or .json file, chosen by the extension
+func (p *Profiler) Export(path string) error {
+	if len(p.frames) == 0 {
+		return errors.New("no frames captured, enable the profiler first")
+	}
+
+	fd, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		encoder := json.NewEncoder(fd)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(p.frames)
+
+	case ".csv":
+		scopes := p.scopeNames()
+
+		w := csv.NewWriter(fd)
+		header := []string{"frame", "update_us", "draw_us", "draw_calls", "glyphs", "entities", "alloc_bytes", "alloc_objects"}
+		for _, scope := range scopes {
+			header = append(header, scope+"_us")
+		}
+		w.Write(header)
+
+		for _, frame := range p.frames {
+			record := []string{
+				strconv.Itoa(frame.Frame),
+				strconv.FormatFloat(frame.UpdateTime, 'f', 1, 64),
+				strconv.FormatFloat(frame.DrawTime, 'f', 1, 64),
+				strconv.Itoa(frame.DrawCalls),
+				strconv.Itoa(frame.Glyphs),
+				strconv.Itoa(frame.Entities),
+				strconv.FormatUint(frame.AllocBytes, 10),
+				strconv.FormatUint(frame.AllocObjects, 10),
+			}
+
+			for _, scope := range scopes {
+				record = append(record, strconv.FormatFloat(frame.Scopes[scope], 'f', 1, 64))
+			}
+
+			w.Write(record)
+		}
+
+		w.Flush()
+		return w.Error()
+	}
+
+	return errors.New("unknown export format " + filepath.Ext(path) + ", use .csv or .json")
+}
+
+type profilerGraph struct {
+	title string
+	unit  string
+	value func(f *ProfilerFrame) float64
+	color color.RGBA
+}
+
+var profilerGraphs = []profilerGraph{
+	{"update", "ms", func(f *ProfilerFrame) float64 { return f.UpdateTime / 1000 }, color.RGBA{64, 192, 255, 255}},
+	{"draw", "ms", func(f *ProfilerFrame) float64 { return f.DrawTime / 1000 }, color.RGBA{255, 160, 64, 255}},
+	{"draw calls", "", func(f *ProfilerFrame) float64 { return float64(f.DrawCalls) }, color.RGBA{255, 255, 64, 255}},
+	{"glyphs", "", func(f *ProfilerFrame) float64 { return float64(f.Glyphs) }, color.RGBA{192, 128, 255, 255}},
+	{"entities", "", func(f *ProfilerFrame) float64 { return float64(f.Entities) }, color.RGBA{64, 255, 128, 255}},
+	{"allocs", "KB", func(f *ProfilerFrame) float64 { return float64(f.AllocBytes) / 1024 }, color.RGBA{255, 64, 64, 255}},
+}
+
+const (
+	profilerGraphWidth  = profilerHistoryFrames
+	profilerGraphHeight = 32
+)
+
+// Draws rolling graphs and average scope timings on the left of the screen
+func (p *Profiler) Draw(screen *ebiten.Image, fontRenderer *FontRenderer) {
+	// The overlay itself is not part of the measured frame
+	drawCalls, glyphs := p.current.DrawCalls, p.current.Glyphs
+	defer func() {
+		p.current.DrawCalls, p.current.Glyphs = drawCalls, glyphs
+	}()
+
+	fontRenderer.PushState()
+	fontRenderer.Reset()
+	fontRenderer.SetTextColor(color.White)
+	fontRenderer.EnableShadow(true)
+
+	lineHeight := fontRenderer.GetGlyphSize().Y + 2
+	x, y := 4.0, 4.0
+
+	panelHeight := float64(len(profilerGraphs))*(profilerGraphHeight+lineHeight+4) + lineHeight*float64(len(p.scopeNames())+1) + 8
+	ebitenutil.DrawRect(screen, 0, 0, profilerGraphWidth+8, panelHeight, color.RGBA{0, 0, 0, 160})
+
+	for _, graph := range profilerGraphs {
+		var maxValue, sum, last float64
+		for i := range p.frames {
+			v := graph.value(&p.frames[i])
+			sum += v
+			last = v
+			if v > maxValue {
+				maxValue = v
+			}
+		}
+
+		avg := 0.0
+		if len(p.frames) > 0 {
+			avg = sum / float64(len(p.frames))
+		}
+
+		fontRenderer.DrawTextAt(screen,
+			fmt.Sprintf("%s %.1f%s (avg %.1f, max %.1f)", graph.title, last, graph.unit, avg, maxValue),
+			Vec2f{x, y})
+		y += lineHeight
+
+		ebitenutil.DrawRect(screen, x, y, profilerGraphWidth, profilerGraphHeight, color.RGBA{32, 32, 32, 192})
+
+		if maxValue > 0 {
+			offset := profilerGraphWidth - len(p.frames)
+			for i := range p.frames {
+				h := graph.value(&p.frames[i]) / maxValue * profilerGraphHeight
+				ebitenutil.DrawRect(screen, x+float64(offset+i), y+profilerGraphHeight-h, 1, h, graph.color)
+			}
+		}
+
+		y += profilerGraphHeight + 4
+	}
+
+	fontRenderer.DrawTextAt(screen, "scopes (avg ms):", Vec2f{x, y})
+	y += lineHeight
+
+	for _, name := range p.scopeNames() {
+		var sum float64
+		for _, frame := range p.frames {
+			sum += frame.Scopes[name]
+		}
+
+		fontRenderer.DrawTextAt(screen, fmt.Sprintf("  %s %.3f", name, sum/float64(len(p.frames))/1000), Vec2f{x, y})
+		y += lineHeight
+	}
+
+	fontRenderer.PopState()
+}
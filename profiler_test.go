@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func captureTestFrames(t *testing.T, frames int) *Profiler {
+	t.Helper()
+
+	sim, _ := newTestSimulation(t, newTestLevel())
+	g := sim.GetGame()
+	sim.Spawn(g.char, 2, 2)
+
+	prof := Profiler_GetInstance()
+	prof.SetEnabled(true)
+	t.Cleanup(func() {
+		prof.SetEnabled(false)
+	})
+
+	for i := 0; i < frames; i++ {
+		prof.BeginUpdate()
+		sim.Step(1)
+		prof.EndUpdate()
+
+		prof.BeginDraw()
+		prof.CountDrawCall()
+		prof.CountGlyph()
+		prof.CountGlyph()
+		prof.EndDraw(len(g.entities))
+	}
+
+	return prof
+}
+
+func TestProfilerCapturesFrames(t *testing.T) {
+	prof := captureTestFrames(t, 3)
+	frames := prof.GetFrames()
+
+	if len(frames) != 3 {
+		t.Fatalf("captured %d frames, want 3", len(frames))
+	}
+
+	for _, frame := range frames {
+		if frame.DrawCalls != 1 || frame.Glyphs != 2 || frame.Entities != 1 {
+			t.Fatalf("frame = %+v, want 1 draw call, 2 glyphs, 1 entity", frame)
+		}
+
+		if _, has := frame.Scopes[profScopeEntityUpdate]; !has {
+			t.Fatalf("frame %d has no %q scope", frame.Frame, profScopeEntityUpdate)
+		}
+	}
+}
+
+func TestProfilerHistoryIsBounded(t *testing.T) {
+	prof := captureTestFrames(t, profilerHistoryFrames+10)
+
+	if got := len(prof.GetFrames()); got != profilerHistoryFrames {
+		t.Fatalf("history holds %d frames, want %d", got, profilerHistoryFrames)
+	}
+}
+
+func TestProfilerExport(t *testing.T) {
+	prof := captureTestFrames(t, 5)
+	dir := t.TempDir()
+
+	csvPath := filepath.Join(dir, "frames.csv")
+	if err := prof.Export(csvPath); err != nil {
+		t.Fatalf("CSV export: %v", err)
+	}
+
+	fd, err := os.Open(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	records, err := csv.NewReader(fd).ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV: %v", err)
+	}
+
+	if len(records) != 6 || records[0][0] != "frame" {
+		t.Fatalf("CSV has %d records with header %v, want header and 5 rows", len(records), records[0])
+	}
+
+	jsonPath := filepath.Join(dir, "frames.json")
+	if err := prof.Export(jsonPath); err != nil {
+		t.Fatalf("JSON export: %v", err)
+	}
+
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var frames []ProfilerFrame
+	if err := json.Unmarshal(data, &frames); err != nil || len(frames) != 5 {
+		t.Fatalf("JSON export holds %d frames, err %v", len(frames), err)
+	}
+
+	if err := prof.Export(filepath.Join(dir, "frames.txt")); err == nil {
+		t.Fatal("export to an unknown format succeeded")
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"log"
+	"math"
+	"os"
+)
+
+const replayFormatVersion = 1
+
+// Ticks between two state checksums written to a replay file
+const replayChecksumInterval = 60
+
+type JSONReplayHeader struct {
+	Version          int    `json:"version"`
+	Seed             int64  `json:"seed"`
+	Level            string `json:"level"`
+	ChecksumInterval int    `json:"checksum_interval"`
+}
+
+// A single replay file line. Lines are written only for ticks where
+// something happened: an action changed state, console commands were run
+// before the tick or a checksum was taken.
+type JSONReplayRecord struct {
+	Tick     int       `json:"tick"`
+	Pressed  []KeyBind `json:"pressed,omitempty"`
+	Released []KeyBind `json:"released,omitempty"`
+	Commands []string  `json:"commands,omitempty"`
+	Checksum *uint32   `json:"checksum,omitempty"`
+}
+
+// Hashes the simulation state: tick counter, level tiles and entities
+//
+// Two runs fed with the same seed and input must produce identical sums.
+func (g *Game) StateChecksum() uint32 {
+	h := fnv.New32a()
+	buf := make([]byte, 8)
+
+	writeInt := func(v int64) {
+		binary.LittleEndian.PutUint64(buf, uint64(v))
+		h.Write(buf)
+	}
+
+	writeFloat := func(v float64) {
+		binary.LittleEndian.PutUint64(buf, math.Float64bits(v))
+		h.Write(buf)
+	}
+
+	writeInt(int64(tickCounter))
+
+	if g.level != nil {
+		for i := 0; i < g.level.GetLayerCount(); i++ {
+			for tilePos := 0; tilePos < g.level.width*g.level.height; tilePos++ {
+				h.Write([]byte{byte(g.level.GetTile(i, tilePos))})
+			}
+		}
+	}
+
+	for _, entity := range g.entities {
+		e := entity.GetLivingEntity()
+
+		writeInt(int64(e.id))
+		writeFloat(e.worldPos.X)
+		writeFloat(e.worldPos.Y)
+		writeFloat(e.health)
+		writeInt(int64(e.look))
+
+		if e.walking {
+			writeInt(1)
+		} else {
+			writeInt(0)
+		}
+	}
+
+	return h.Sum32()
+}
+
+// Input source decorator which writes every gameplay tick's actions to a file
+type InputRecorder struct {
+	IInputSource
+	source   IInputSource
+	game     *Game
+	file     *os.File
+	encoder  *json.Encoder
+	tick     int
+	commands []string
+}
+
+func (rec *InputRecorder) Update() {
+	rec.source.Update()
+
+	record := JSONReplayRecord{Tick: rec.tick, Commands: rec.commands}
+	rec.commands = nil
+
+	if rec.tick%replayChecksumInterval == 0 {
+		sum := rec.game.StateChecksum()
+		record.Checksum = &sum
+	}
+
+	for _, kb := range SortedKeyBinds() {
+		if rec.source.IsActionJustPressed(kb) {
+			record.Pressed = append(record.Pressed, kb)
+		}
+
+		if rec.source.IsActionJustReleased(kb) {
+			record.Released = append(record.Released, kb)
+		}
+	}
+
+	if record.Checksum != nil || len(record.Pressed) > 0 || len(record.Released) > 0 || len(record.Commands) > 0 {
+		if err := rec.encoder.Encode(record); err != nil {
+			log.Println("[InputRecorder] Failed to write replay record: " + err.Error())
+		}
+	}
+
+	rec.tick++
+}
+
+func (rec *InputRecorder) IsActionPressed(kb KeyBind) bool {
+	return rec.source.IsActionPressed(kb)
+}
+
+func (rec *InputRecorder) IsActionJustPressed(kb KeyBind) bool {
+	return rec.source.IsActionJustPressed(kb)
+}
+
+func (rec *InputRecorder) IsActionJustReleased(kb KeyBind) bool {
+	return rec.source.IsActionJustReleased(kb)
+}
+
+func (rec *InputRecorder) RecordCommand(line string) {
+	rec.source.RecordCommand(line)
+	rec.commands = append(rec.commands, line)
+}
+
+func (rec *InputRecorder) Close() error {
+	return rec.file.Close()
+}
+
+// Closes the replay file being recorded, if any
+func (g *Game) CloseInput() {
+	if rec, ok := g.input.(*InputRecorder); ok {
+		if err := rec.Close(); err != nil {
+			log.Println("[InputRecorder] Failed to close replay file: " + err.Error())
+		}
+	}
+}
+
+func NewInputRecorder(g *Game, source IInputSource, path string) (*InputRecorder, error) {
+	fd, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := new(InputRecorder)
+	rec.source = source
+	rec.game = g
+	rec.file = fd
+	rec.encoder = json.NewEncoder(fd)
+
+	header := JSONReplayHeader{
+		Version:          replayFormatVersion,
+		Seed:             g.seed,
+		Level:            g.config.levelPath,
+		ChecksumInterval: replayChecksumInterval,
+	}
+
+	if err := rec.encoder.Encode(header); err != nil {
+		fd.Close()
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// Input source feeding actions back from a replay file tick-for-tick
+type ReplayInputSource struct {
+	IInputSource
+	game     *Game
+	header   JSONReplayHeader
+	records  map[int]JSONReplayRecord
+	lastTick int
+	tick     int
+	current  JSONReplayRecord
+	held     map[KeyBind]bool
+	desynced bool
+	finished bool
+}
+
+func (src *ReplayInputSource) Update() {
+	src.current = src.records[src.tick]
+
+	for _, line := range src.current.Commands {
+		src.game.console.Execute(line)
+	}
+
+	if src.current.Checksum != nil && !src.desynced {
+		if sum := src.game.StateChecksum(); sum != *src.current.Checksum {
+			log.Printf("[Replay] Desync at tick %d: expected checksum %08x, got %08x",
+				src.tick, *src.current.Checksum, sum)
+			src.desynced = true
+		}
+	}
+
+	for _, kb := range src.current.Pressed {
+		src.held[kb] = true
+	}
+
+	for _, kb := range src.current.Released {
+		src.held[kb] = false
+	}
+
+	if src.tick > src.lastTick && !src.finished {
+		log.Printf("[Replay] Finished after %d ticks", src.lastTick)
+		src.finished = true
+	}
+
+	src.tick++
+}
+
+func (src *ReplayInputSource) IsActionPressed(kb KeyBind) bool {
+	return src.held[kb]
+}
+
+func (src *ReplayInputSource) IsActionJustPressed(kb KeyBind) bool {
+	for _, pressed := range src.current.Pressed {
+		if pressed == kb {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (src *ReplayInputSource) IsActionJustReleased(kb KeyBind) bool {
+	for _, released := range src.current.Released {
+		if released == kb {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Commands typed during a replay are not part of it
+func (src *ReplayInputSource) RecordCommand(string) {
+
+}
+
+func (src *ReplayInputSource) GetSeed() int64 {
+	return src.header.Seed
+}
+
+func (src *ReplayInputSource) GetLevel() string {
+	return src.header.Level
+}
+
+func (src *ReplayInputSource) IsDesynced() bool {
+	return src.desynced
+}
+
+func LoadReplay(g *Game, path string) (*ReplayInputSource, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	src := new(ReplayInputSource)
+	src.game = g
+	src.records = make(map[int]JSONReplayRecord)
+	src.held = make(map[KeyBind]bool)
+
+	scanner := bufio.NewScanner(fd)
+
+	if !scanner.Scan() {
+		return nil, errors.New("replay file has no header")
+	}
+
+	if err := json.Unmarshal(scanner.Bytes(), &src.header); err != nil {
+		return nil, err
+	}
+
+	if src.header.Version != replayFormatVersion {
+		return nil, errors.New("unsupported replay format version")
+	}
+
+	for scanner.Scan() {
+		var record JSONReplayRecord
+
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, err
+		}
+
+		src.records[record.Tick] = record
+
+		if record.Tick > src.lastTick {
+			src.lastTick = record.Tick
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return src, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func runChecksumSession(seed int64) uint32 {
+	level := newTestLevel()
+	input := NewScriptedInputSource()
+	sim := NewHeadlessSimulation(level, seed, input)
+	g := sim.GetGame()
+
+	sim.Spawn(g.char, 7, 7)
+
+	// Wanderers walk at most four tiles per walk cycle, keep them inside the map
+	for i := 0; i < 3; i++ {
+		sim.Spawn(CreateMichael(g), 5+i*2, 5)
+	}
+
+	input.Hold(10, 30, kbPlayerMoveDown)
+	input.Hold(50, 20, kbPlayerMoveLeft)
+	sim.Step(100)
+
+	return g.StateChecksum()
+}
+
+func TestSameSeedIsDeterministic(t *testing.T) {
+	if a, b := runChecksumSession(42), runChecksumSession(42); a != b {
+		t.Fatalf("checksums differ for the same seed: %08x != %08x", a, b)
+	}
+}
+
+func TestReplayRunsConsoleCommands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.replay")
+
+	input := NewScriptedInputSource()
+	sim := NewHeadlessSimulation(newTestLevel(), 7, input)
+	g := sim.GetGame()
+	sim.Spawn(g.char, 7, 7)
+
+	rec, err := NewInputRecorder(g, input, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.input = rec
+
+	input.Hold(10, 30, kbPlayerMoveDown)
+	sim.Step(70)
+
+	// As typed into the console between two ticks
+	g.console.Execute("give aid 2")
+	rec.RecordCommand("give aid 2")
+	sim.Step(70)
+
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replaySim := NewHeadlessSimulation(newTestLevel(), 7, NewScriptedInputSource())
+	replayed := replaySim.GetGame()
+	replaySim.Spawn(replayed.char, 7, 7)
+
+	replay, err := LoadReplay(replayed, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed.input = replay
+	replaySim.Step(140)
+
+	if replay.IsDesynced() || replayed.StateChecksum() != g.StateChecksum() {
+		t.Fatalf("replay desynced, checksum %08x, recorded %08x", replayed.StateChecksum(), g.StateChecksum())
+	}
+
+	if got := replayed.char.GetItemCount("aid"); got != 2 {
+		t.Fatalf("aid = %d after the replay, want the 2 given from the console", got)
+	}
+}
+
+func TestReplayRunsInspectorEdits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.replay")
+
+	input := NewScriptedInputSource()
+	sim := NewHeadlessSimulation(newTestLevel(), 7, input)
+	g := sim.GetGame()
+	sim.Spawn(g.char, 7, 7)
+	npc, _ := CreateEntity(g, "michael")
+	sim.Spawn(npc, 4, 4)
+
+	rec, err := NewInputRecorder(g, input, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.input = rec
+
+	input.Press(5, kbToggleEntityInspector)
+	sim.Step(10)
+
+	// As the inspector keys and mouse buttons would
+	ins := g.GetEntityInspector()
+	ins.runSelect(npc)
+	sim.Step(3)
+	ins.runCommand("adjust", "health", "-2")
+	ins.runCommand("clone")
+	sim.Step(3)
+	ins.runCommand("teleport", "100.5", "60")
+	sim.Step(60)
+
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.entities) != 3 || npc.GetHealth() != 90 {
+		t.Fatalf("%d entities after the edits, NPC health %.1f", len(g.entities), npc.GetHealth())
+	}
+
+	replaySim := NewHeadlessSimulation(newTestLevel(), 7, NewScriptedInputSource())
+	replayed := replaySim.GetGame()
+	replaySim.Spawn(replayed.char, 7, 7)
+	replayNPC, _ := CreateEntity(replayed, "michael")
+	replaySim.Spawn(replayNPC, 4, 4)
+
+	replay, err := LoadReplay(replayed, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed.input = replay
+	replaySim.Step(76)
+
+	if replay.IsDesynced() || replayed.StateChecksum() != g.StateChecksum() {
+		t.Fatalf("replay desynced, checksum %08x, recorded %08x", replayed.StateChecksum(), g.StateChecksum())
+	}
+}
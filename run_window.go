@@ -15,7 +15,7 @@ func (wnd *RunWindow) Draw(screen *ebiten.Image) {
 
 	op := &ebiten.DrawImageOptions{}
 	op.GeoM.Translate(x, y)
-	screen.DrawImage(wnd.runIcon32, op)
+	DrawImage(screen, wnd.runIcon32, op)
 }
 
 func InitRunWindow(wnd *RunWindow, xps *WinXPScreen) {
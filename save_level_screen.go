@@ -1,6 +1,8 @@
 package main
 
 import (
+	"log"
+
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
@@ -34,7 +36,9 @@ func CreateSaveLevelScreen(gameplayScreen *GameplayScreen) *SaveLevelScreen {
 	s.widgets = append(s.widgets, editBox)
 
 	s.widgets = append(s.widgets, CreateCommonButton(g, I18n("string_verb_save", "Save"), func(g *Game) {
-		g.SaveLevel(s.widgets[0].GetText())
+		if err := g.SaveLevel(s.widgets[0].GetText()); err != nil {
+			log.Println("Failed to save level: " + err.Error())
+		}
 		gameplayScreen.overlayStack.Pop()
 	}))
 
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Shopkeeper opened by the shop screen when none is given
+const defaultShopkeeper = "morshu"
+
+var (
+	errShopNotSold       = errors.New("the shopkeeper does not sell this item")
+	errShopNotBought     = errors.New("the shopkeeper does not buy this item")
+	errShopSoldOut       = errors.New("sold out")
+	errShopNoMoney       = errors.New("not enough money")
+	errShopNoItems       = errors.New("not enough items to sell")
+	errShopInventoryFull = errors.New("the inventory is full")
+)
+
+// Lines the shopkeeper answers failed deals with
+var shopErrorKeys = map[error]string{
+	errShopNotSold:       "string_shop_not_sold",
+	errShopNotBought:     "string_shop_not_bought",
+	errShopSoldOut:       "string_shop_sold_out",
+	errShopNoMoney:       "string_shop_no_money",
+	errShopNoItems:       "string_shop_no_items",
+	errShopInventoryFull: "string_shop_inventory_full",
+}
+
+// Stock entry of a shopkeeper
+//
+// Price is what the player pays for one item, 0 if the shopkeeper does not
+// sell it. SellPrice is what the shopkeeper pays the player, 0 if it does
+// not buy it. A negative Count is an endless supply.
+type JSONShopItem struct {
+	Item      string `json:"item"`
+	Price     int    `json:"price,omitempty"`
+	SellPrice int    `json:"sell_price,omitempty"`
+	Count     int    `json:"count"`
+}
+
+func (item *JSONShopItem) IsSold() bool {
+	return item.Price > 0
+}
+
+func (item *JSONShopItem) IsBought() bool {
+	return item.SellPrice > 0
+}
+
+func (item *JSONShopItem) IsInStock() bool {
+	return item.Count != 0
+}
+
+// Shopkeeper data, stored as shop.json next to its VN character. Name and
+// Greeting are language keys. Dialogue is played when the shop opens; its
+// "buy", "sell" and "leave" events work like the shop menu.
+type JSONShopkeeper struct {
+	Name      string         `json:"name"`
+	Character string         `json:"character"`
+	Greeting  string         `json:"greeting"`
+	Dialogue  string         `json:"dialogue,omitempty"`
+	Stock     []JSONShopItem `json:"stock"`
+}
+
+func ShopkeeperPath(name string) string {
+	return filepath.Join("assets", "shop", name, "shop.json")
+}
+
+func LoadShopkeeper(path string) (*JSONShopkeeper, error) {
+	keeper := new(JSONShopkeeper)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return keeper, err
+	}
+
+	err = json.Unmarshal(data, keeper)
+	return keeper, err
+}
+
+// Running shop of one shopkeeper. Stock counts change with every deal and
+// reset when the shop is opened again.
+type Shop struct {
+	keeper *JSONShopkeeper
+	stock  []JSONShopItem
+}
+
+func NewShop(keeper *JSONShopkeeper) *Shop {
+	shop := new(Shop)
+	shop.keeper = keeper
+	shop.stock = append(shop.stock, keeper.Stock...)
+	return shop
+}
+
+func (shop *Shop) GetKeeper() *JSONShopkeeper {
+	return shop.keeper
+}
+
+func (shop *Shop) GetItem(id string) *JSONShopItem {
+	for i := range shop.stock {
+		if shop.stock[i].Item == id {
+			return &shop.stock[i]
+		}
+	}
+
+	return nil
+}
+
+// Returns the stock entries the shopkeeper sells, sold out ones included
+func (shop *Shop) GetWares() []*JSONShopItem {
+	var wares []*JSONShopItem
+	for i := range shop.stock {
+		if shop.stock[i].IsSold() {
+			wares = append(wares, &shop.stock[i])
+		}
+	}
+
+	return wares
+}
+
+// Returns the stock entries the shopkeeper buys and the customer carries
+func (shop *Shop) GetBuyList(customer ICharacter) []*JSONShopItem {
+	var items []*JSONShopItem
+	for i := range shop.stock {
+		if item := &shop.stock[i]; item.IsBought() && customer.GetItemCount(item.Item) > 0 {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// Sells items to the customer, or leaves everything as it was on failure
+func (shop *Shop) Buy(customer ICharacter, id string, count int) error {
+	item := shop.GetItem(id)
+	if item == nil || !item.IsSold() {
+		return errShopNotSold
+	}
+
+	if item.Count >= 0 && item.Count < count {
+		return errShopSoldOut
+	}
+
+	price := item.Price * count
+	if customer.GetMoney() < price {
+		return errShopNoMoney
+	}
+
+	if left := customer.AddItem(id, count); left > 0 {
+		customer.TakeItem(id, count-left)
+		return errShopInventoryFull
+	}
+
+	customer.SpendMoney(price)
+	if item.Count >= 0 {
+		item.Count -= count
+	}
+
+	return nil
+}
+
+// Buys items from the customer, putting them into a limited stock
+func (shop *Shop) Sell(customer ICharacter, id string, count int) error {
+	item := shop.GetItem(id)
+	if item == nil || !item.IsBought() {
+		return errShopNotBought
+	}
+
+	if !customer.TakeItem(id, count) {
+		return errShopNoItems
+	}
+
+	customer.AddMoney(item.SellPrice * count)
+	if item.Count >= 0 {
+		item.Count += count
+	}
+
+	return nil
+}
+
+// Returns the shopkeeper's answer to a failed deal
+func ShopErrorText(err error) string {
+	return I18n(shopErrorKeys[err], err.Error())
+}
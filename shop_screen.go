@@ -1,205 +1,320 @@
 package main
 
 import (
-	"encoding/json"
+	"fmt"
 	"image/color"
-	"io/ioutil"
-	"math"
-	"path/filepath"
+	"log"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
-type IDialogueBox interface {
-	Draw(*ebiten.Image)
+const (
+	shopModeMenu = iota
+	shopModeBuy
+	shopModeSell
+	shopModeConfirm
+	shopModeMessage
+	shopModeDialogue
+)
+
+// Shop of one shopkeeper, bought from and sold to through the dialogue box
+//
+// The shopkeeper's dialogue plays first, then the shop menu. Arrows pick
+// answers and wares, Enter confirms, Escape goes back and leaves to the
+// screen the shop was opened from. Tab shows the backlog, the entity
+// inspector key the expression editor of the shopkeeper.
+type ShopScreen struct {
+	Screen
+	backgroundImage *ebiten.Image
+	tableImage      *ebiten.Image
+	keeperName      string
+	keeper          *VNCharacter
+	shop            *Shop
+	editor          IScreen
+	dialogueBox     IDialogueBox
+	backlog         IScreen
+	runner          *DialogueRunner
+	mode            int
+	listMode        int
+	cursor          int
+	pending         *JSONShopItem
+	prevScreen      IScreen
 }
 
-type DialogueBox struct {
-	IDialogueBox
-	game *Game
-	name string
-	font *Font
+func (s *ShopScreen) GetShop() *Shop {
+	return s.shop
 }
 
-func (dialogueBox *DialogueBox) Draw(screen *ebiten.Image) {
-	game := dialogueBox.game
-	game.DrawHerbGUIFrame(screen, 0, 0, 128, 128)
+func (s *ShopScreen) GetMode() int {
+	return s.mode
+}
 
-	fontRenderer := game.fontRenderer
-	fontRenderer.PushState()
+// Returns the wares in the buy list, or the customer's items the
+// shopkeeper buys in the sell list
+func (s *ShopScreen) GetList() []*JSONShopItem {
+	if s.listMode == shopModeBuy {
+		return s.shop.GetWares()
+	}
 
-	fontRenderer.SetFont(dialogueBox.font)
-	fontRenderer.DrawTextAt(screen, dialogueBox.name, Vec2f{16.0, 16.0})
+	if s.game.char == nil {
+		return nil
+	}
 
-	fontRenderer.PopState()
+	return s.shop.GetBuyList(s.game.char)
 }
 
-func InitDialogueBox(dialogueBox *DialogueBox, game *Game) {
-	dialogueBox.game = game
+func (s *ShopScreen) say(text string, choices ...string) {
+	s.dialogueBox.SetSpeaker(I18n(s.shop.GetKeeper().Name, s.keeperName))
+	s.dialogueBox.SetText(text)
+	s.dialogueBox.SetChoices(choices)
 }
 
-func NewDialogueBox(game *Game) *DialogueBox {
-	dialogueBox := new(DialogueBox)
-	InitDialogueBox(dialogueBox, game)
+// Plays the shopkeeper's dialogue, or shows the menu if there is none
+func (s *ShopScreen) StartDialogue() {
+	if s.runner == nil {
+		s.ShowMenu()
+		return
+	}
 
-	return dialogueBox
+	s.mode = shopModeDialogue
+	s.runner.Start()
+	s.finishDialogue()
 }
 
-type IVNSpriteMenu interface {
-	Draw(*ebiten.Image)
-	SetCharacter(*VNCharacter)
-	GetCharacter() *VNCharacter
+// Shows the menu after a dialogue that ended without opening a list
+func (s *ShopScreen) finishDialogue() {
+	if s.mode == shopModeDialogue && s.runner.IsFinished() {
+		s.ShowMenu()
+	}
 }
 
-type VNSpriteMenu struct {
-	IVNSpriteMenu
-	game        *Game
-	vnCharacter *VNCharacter
-	font        *Font
+func (s *ShopScreen) handleDialogueEvent(event string) {
+	switch event {
+	case "buy":
+		s.OpenList(shopModeBuy)
+	case "sell":
+		s.OpenList(shopModeSell)
+	case "leave":
+		s.Leave()
+	default:
+		log.Println("[Shop] Unknown dialogue event " + event)
+	}
 }
 
-var editgroup = 0
-var vnEditTileSize float64 = 48
-
-func (menu *VNSpriteMenu) Draw(screen *ebiten.Image) {
-	menu.game.DrawHerbGUIFrame(screen, screenWidth-vnEditTileSize*5, 0, vnEditTileSize*5, screenHeight)
-
-	var i int
-
-	e := menu.vnCharacter.sprites[editgroup].sprites
+func (s *ShopScreen) ShowMenu() {
+	s.mode = shopModeMenu
+	s.say(I18n(s.shop.GetKeeper().Greeting, "Welcome!"),
+		I18n("string_shop_buy", "Buy"),
+		I18n("string_shop_sell", "Sell"),
+		I18n("string_shop_leave", "Leave"))
+}
 
-	fontRenderer := menu.game.fontRenderer
+func (s *ShopScreen) OpenList(mode int) {
+	s.listMode = mode
 
-	fontRenderer.PushState()
-
-	fontRenderer.SetFont(menu.font)
-	fontRenderer.SetScale(2.0)
+	items := s.GetList()
+	if len(items) == 0 && mode == shopModeBuy {
+		s.ShowMessage(I18n("string_shop_sold_out", "Sold out, come back later."))
+		return
+	} else if len(items) == 0 {
+		s.ShowMessage(I18n("string_shop_nothing_to_sell", "You have nothing I would buy."))
+		return
+	}
 
-	assetGroupTitle := menu.vnCharacter.sprites[editgroup].name
+	s.mode = mode
+	if s.cursor >= len(items) {
+		s.cursor = len(items) - 1
+	}
 
-	dim := fontRenderer.GetStringDimensions(assetGroupTitle)
-	fontRenderer.DrawTextAt(screen, assetGroupTitle, Vec2f{screenWidth - vnEditTileSize*5, dim.Y})
+	if mode == shopModeBuy {
+		s.say(I18n("string_shop_pick_buy", "What would you like?"))
+	} else {
+		s.say(I18n("string_shop_pick_sell", "What will you sell?"))
+	}
+}
 
-	screenTranslate := ebiten.GeoM{}
-	screenTranslate.Translate(screenWidth-vnEditTileSize*5, dim.Y*3)
+func (s *ShopScreen) ShowMessage(text string) {
+	s.mode = shopModeMessage
+	s.say(text)
+}
 
-	fontRenderer.PopState()
+// Asks to confirm a deal for the item under the cursor
+func (s *ShopScreen) Offer() {
+	items := s.GetList()
+	if s.cursor >= len(items) {
+		return
+	}
 
-	for _, spriteVariant := range e {
-		op := &ebiten.DrawImageOptions{}
+	item := items[s.cursor]
+	name := item.Item
+	if def := GetItemDef(item.Item); def != nil {
+		name = def.GetName()
+	}
 
-		imgWidth, imgHeight := spriteVariant.image.Size()
-		op.GeoM.Translate(-(float64(imgWidth) / 2), -(float64(imgHeight) / 2))
+	if s.listMode == shopModeBuy && !item.IsInStock() {
+		s.ShowMessage(ShopErrorText(errShopSoldOut))
+		return
+	}
 
-		scale := float64(vnEditTileSize) / math.Max(float64(imgWidth), float64(imgHeight))
-		op.GeoM.Scale(scale, scale)
-		op.GeoM.Translate(vnEditTileSize/2, vnEditTileSize/2)
+	var text string
+	if s.listMode == shopModeBuy {
+		text = fmt.Sprintf(I18n("string_shop_confirm_buy", "Buy %s for %d rupees?"), name, item.Price)
+	} else {
+		text = fmt.Sprintf(I18n("string_shop_confirm_sell", "Sell %s for %d rupees?"), name, item.SellPrice)
+	}
 
-		sx := float64(i%5) * float64(vnEditTileSize)
-		sy := float64(i/5) * float64(vnEditTileSize)
+	s.pending = item
+	s.mode = shopModeConfirm
+	s.say(text, I18n("string_yes", "Yes"), I18n("string_no", "No"))
+}
 
-		op.GeoM.Translate(sx, sy)
+// Makes the pending deal if the customer agreed
+func (s *ShopScreen) Confirm() {
+	customer := s.game.char
+	if s.dialogueBox.GetChoice() != 0 || s.pending == nil || customer == nil {
+		s.OpenList(s.listMode)
+		return
+	}
 
-		op.GeoM.Concat(screenTranslate)
+	var err error
+	if s.listMode == shopModeBuy {
+		err = s.shop.Buy(customer, s.pending.Item, 1)
+	} else {
+		err = s.shop.Sell(customer, s.pending.Item, 1)
+	}
 
-		if menu.vnCharacter.sprites[editgroup].current == spriteVariant {
-			x, y := op.GeoM.Apply(1, 1)
-			ebitenutil.DrawRect(screen, x, y, vnEditTileSize, vnEditTileSize, color.RGBA{0, 255, 0, 128})
-		}
+	s.pending = nil
+	if err != nil {
+		s.ShowMessage(ShopErrorText(err))
+		return
+	}
 
-		screen.DrawImage(spriteVariant.image, op)
+	s.ShowMessage(I18n("string_shop_thanks", "Thank you!"))
+}
 
-		i++
+// Returns to the screen the shop was opened from
+func (s *ShopScreen) Leave() {
+	if s.prevScreen != nil {
+		s.game.SetScreen(s.prevScreen)
+	} else {
+		s.game.SetScreen(CreateMainMenu(s.game))
 	}
 }
 
-func (menu *VNSpriteMenu) GetCharacter() *VNCharacter {
-	return menu.vnCharacter
+// Opens the expression editor for the shopkeeper
+func (s *ShopScreen) OpenEditor() {
+	if s.keeper == nil {
+		return
+	}
+
+	s.editor = NewVNExpressionEditor(s.game, s.keeper, func() {
+		s.editor = nil
+	})
 }
 
-func (menu *VNSpriteMenu) SetCharacter(char *VNCharacter) {
-	menu.vnCharacter = char
+// Shows the lines said so far over the shop, which waits meanwhile
+func (s *ShopScreen) OpenBacklog() {
+	s.backlog = NewDialogueBacklogScreen(s.game, s.dialogueBox, func() {
+		s.backlog = nil
+	})
 }
 
-func InitVNSpriteMenu(menu *VNSpriteMenu, g *Game) {
-	rm := ResourceManager_GetInstance()
+func (s *ShopScreen) ProcessKeyEvents() bool {
+	if s.backlog != nil {
+		return s.backlog.ProcessKeyEvents()
+	}
 
-	menu.game = g
-	menu.font = rm.LoadFontJSON("font/font_fantasy.json")
-}
+	if s.game.input.IsActionJustPressed(kbBacklog) {
+		s.OpenBacklog()
+		return true
+	}
 
-func NewVNSpriteMenu(g *Game) *VNSpriteMenu {
-	menu := new(VNSpriteMenu)
-	InitVNSpriteMenu(menu, g)
-	return menu
-}
+	// Enter completes the typed text before it picks anything
+	if s.mode != shopModeDialogue && s.dialogueBox.IsTyping() && inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		s.dialogueBox.Skip()
+		return true
+	}
 
-type ShopScreen struct {
-	Screen
-	backgroundImage *ebiten.Image
-	tableImage      *ebiten.Image
-	endou           *VNCharacter
-	morshu          *VNCharacter
-	spriteMenu      IVNSpriteMenu
-	dialogueBox     IDialogueBox
-}
+	if s.editor != nil {
+		return s.editor.ProcessKeyEvents()
+	}
 
-var le = 0
+	if inpututil.IsKeyJustPressed(keyBinds[kbToggleEntityInspector]) {
+		s.OpenEditor()
+		return true
+	}
 
-func (s *ShopScreen) ProcessKeyEvents() bool {
-	if inpututil.IsKeyJustPressed(ebiten.KeyQ) {
-		facial := s.spriteMenu.GetCharacter().sprites[editgroup]
+	switch s.mode {
+	case shopModeDialogue:
+		switch {
+		case repeatingKeyPressed(ebiten.KeyLeft) || repeatingKeyPressed(ebiten.KeyUp):
+			s.dialogueBox.MoveChoice(-1)
 
-		var j int
-		for i, e := range facial.sprites {
-			if facial.current == e {
-				j = i
-			}
-		}
+		case repeatingKeyPressed(ebiten.KeyRight) || repeatingKeyPressed(ebiten.KeyDown):
+			s.dialogueBox.MoveChoice(1)
 
-		if j-1 < 0 {
-			j = len(facial.sprites) - 1
-		} else {
-			j--
+		case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+			s.runner.Advance()
+			s.finishDialogue()
+
+		case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+			s.ShowMenu()
 		}
 
-		facial.current = facial.sprites[j]
-	}
+	case shopModeMenu, shopModeConfirm:
+		switch {
+		case repeatingKeyPressed(ebiten.KeyLeft) || repeatingKeyPressed(ebiten.KeyUp):
+			s.dialogueBox.MoveChoice(-1)
+
+		case repeatingKeyPressed(ebiten.KeyRight) || repeatingKeyPressed(ebiten.KeyDown):
+			s.dialogueBox.MoveChoice(1)
+
+		case inpututil.IsKeyJustPressed(ebiten.KeyEscape) && s.mode == shopModeMenu:
+			s.Leave()
+
+		case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+			s.OpenList(s.listMode)
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyW) {
-		facial := s.spriteMenu.GetCharacter().sprites[editgroup]
+		case inpututil.IsKeyJustPressed(ebiten.KeyEnter) && s.mode == shopModeConfirm:
+			s.Confirm()
 
-		var j int
-		for i, e := range facial.sprites {
-			if facial.current == e {
-				j = i
+		case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+			switch s.dialogueBox.GetChoice() {
+			case 0:
+				s.OpenList(shopModeBuy)
+			case 1:
+				s.OpenList(shopModeSell)
+			default:
+				s.Leave()
 			}
 		}
 
-		if j+1 > len(facial.sprites)-1 {
-			j = 0
-		} else {
-			j++
-		}
+	case shopModeBuy, shopModeSell:
+		count := len(s.GetList())
 
-		facial.current = facial.sprites[j]
-	}
+		switch {
+		case repeatingKeyPressed(ebiten.KeyUp) && count > 0:
+			s.cursor = (s.cursor + count - 1) % count
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyZ) {
-		if editgroup-1 < 0 {
-			editgroup = len(s.endou.sprites) - 1
-		} else {
-			editgroup--
+		case repeatingKeyPressed(ebiten.KeyDown) && count > 0:
+			s.cursor = (s.cursor + 1) % count
+
+		case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+			s.Offer()
+
+		case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+			s.ShowMenu()
 		}
-	}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyX) {
-		if editgroup+1 > len(s.endou.sprites)-1 {
-			editgroup = 0
-		} else {
-			editgroup++
+	case shopModeMessage:
+		if inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			if len(s.GetList()) > 0 {
+				s.OpenList(s.listMode)
+			} else {
+				s.ShowMenu()
+			}
 		}
 	}
 
@@ -207,16 +322,25 @@ func (s *ShopScreen) ProcessKeyEvents() bool {
 }
 
 func (s *ShopScreen) Update() {
-	s.endou.matTransform.Reset()
-	s.endou.matTransform.Translate(-(float64(s.endou.width) / 2), -(float64(s.endou.height) / 2))
-
-	scale := 0.975 + math.Sin(float64(s.game.appTicker)/72.0)*0.025
-	jump := math.Sin(float64(s.game.appTicker) / 16.0)
-	s.endou.matTransform.Translate(0, jump*10)
-	s.endou.matTransform.Scale(scale, scale)
-	s.endou.matTransform.Rotate(math.Sin(float64(s.game.appTicker)/48.0) * 0.05)
-	s.endou.matTransform.Translate(float64(s.endou.width)/2, float64(s.endou.height)/2)
+	// The shopkeeper says every line of the box
+	if s.keeper != nil {
+		s.keeper.GetAnimator().SetTalking(s.dialogueBox.IsTyping())
+		s.keeper.Update()
+	}
+
 	s.IScreen.ProcessKeyEvents()
+
+	if s.backlog != nil {
+		return
+	}
+
+	s.dialogueBox.SetFastForward(ebiten.IsKeyPressed(ebiten.KeyControl))
+	if s.mode == shopModeDialogue && s.runner != nil {
+		s.runner.Update()
+		s.finishDialogue()
+	} else {
+		s.dialogueBox.Update()
+	}
 }
 
 func (s *ShopScreen) LoadResources() {
@@ -225,9 +349,15 @@ func (s *ShopScreen) LoadResources() {
 	s.backgroundImage = rm.LoadImage("assets/shop/background.png")
 	s.tableImage = rm.LoadImage("assets/shop/table.png")
 
-	s.endou = NewVNCharacterFromJSON("assets/shop/test/endou/endou.json", "Endou", g_endouMapping)
-	s.morshu = NewVNCharacterFromJSON("assets/shop/morshu/morshu.json", "Morshu", g_morshuMapping)
-	s.spriteMenu.SetCharacter(s.morshu)
+	keeper := s.shop.GetKeeper()
+	s.keeper = NewVNCharacterFromJSON(keeper.Character, I18n(keeper.Name, s.keeperName))
+
+	if s.runner != nil {
+		s.runner.AddCharacter(s.keeperName, s.keeper)
+	}
+
+	// Expression actions need the shopkeeper loaded
+	s.StartDialogue()
 }
 
 func DrawStretchedImage(screen *ebiten.Image, img *ebiten.Image, rc Rect) {
@@ -287,234 +417,148 @@ func DrawStretchedImage(screen *ebiten.Image, img *ebiten.Image, rc Rect) {
 	screen.DrawTriangles(vs, []uint16{0, 1, 2, 1, 2, 3}, img, triOp)
 }
 
-func (s *ShopScreen) Draw(screen *ebiten.Image) {
+// Draws the wares or the customer's items with their prices
+func (s *ShopScreen) drawList(screen *ebiten.Image) {
+	items := s.GetList()
+	if len(items) == 0 {
+		return
+	}
 
-	DrawStretchedImage(screen, s.backgroundImage, Rect{0, 0, screenWidth, screenHeight})
+	g := s.game
+	fontRenderer := g.fontRenderer
+	fontRenderer.PushState()
+	fontRenderer.Reset()
+	fontRenderer.SetScale(2.0)
+	fontRenderer.EnableShadow(true)
 
-	op := &ebiten.DrawImageOptions{}
-	imgWidth, imgHeight := s.tableImage.Size()
-	op.GeoM.Translate(-float64(imgWidth/2), -float64(imgHeight/2))
-	op.GeoM.Translate(screenWidth/2, float64(screenHeight-imgHeight/2))
+	rowHeight := 24.0
+	width := 288.0
+	left := screenWidth - width - 16
+	top := 16.0
+	g.DrawHerbGUIFrame(screen, left, top, width, rowHeight*float64(len(items))+32)
 
-	s.morshu.Draw(screen)
+	for i, item := range items {
+		y := top + 16 + rowHeight*float64(i)
 
-	screen.DrawImage(s.tableImage, op)
+		if i == s.cursor {
+			ebitenutil.DrawRect(screen, left+8, y-4, width-16, rowHeight, color.RGBA{255, 255, 255, 64})
+		}
 
-	s.spriteMenu.Draw(screen)
+		name := item.Item
+		if def := GetItemDef(item.Item); def != nil {
+			name = def.GetName()
 
-	s.dialogueBox.Draw(screen)
-}
+			if tilesImage != nil {
+				op := &ebiten.DrawImageOptions{}
+				op.GeoM.Translate(left+12, y-2)
+				DrawImage(screen, GetTileSprite(tilesImage, tileXNum, tileSize, def.Icon), op)
+			}
+		}
 
-func InitShopScreen(s *ShopScreen, g *Game) {
-	s.game = g
-	s.spriteMenu = NewVNSpriteMenu(g)
-	s.dialogueBox = NewDialogueBox(g)
-}
+		price := item.Price
+		if s.listMode == shopModeSell {
+			price = item.SellPrice
+		}
 
-func NewShopScreen(g *Game) *ShopScreen {
-	s := new(ShopScreen)
-	InitShopScreen(s, g)
-	s.IScreen = s
-	return s
-}
+		fontRenderer.SetTextColor(color.White)
+		if s.listMode == shopModeBuy && !item.IsInStock() {
+			fontRenderer.SetTextColor(color.RGBA{128, 128, 128, 255})
+		}
+		fontRenderer.DrawTextAt(screen, name, Vec2f{left + 36, y})
 
-type JSONLayeredImage struct {
-	Width  uint                    `json:"width"`
-	Height uint                    `json:"height"`
-	Layers []JSONLayeredImageEntry `json:"layers"`
-}
+		label := fmt.Sprintf("%d", price)
+		if s.listMode == shopModeSell && g.char != nil {
+			label = fmt.Sprintf("x%d  %d", g.char.GetItemCount(item.Item), price)
+		} else if item.Count > 0 {
+			label = fmt.Sprintf("x%d  %d", item.Count, price)
+		}
+		dim := fontRenderer.GetStringDimensions(label)
+		fontRenderer.DrawTextAt(screen, label, Vec2f{left + width - 16 - dim.X, y})
+	}
 
-type JSONLayeredImageEntry struct {
-	Name    string `json:"name"`
-	Index   uint   `json:"index"`
-	OffsetX uint   `json:"offset_x"`
-	OffsetY uint   `json:"offset_y"`
-	Width   uint   `json:"width"`
-	Height  uint   `json:"height"`
-	image   *ebiten.Image
+	fontRenderer.PopState()
 }
 
-type IVNCharacter interface {
-	Draw(*ebiten.Image)
-	SetExpression(string)
-	SetBody(string)
-}
+func (s *ShopScreen) drawMoney(screen *ebiten.Image) {
+	g := s.game
+	if g.char == nil {
+		return
+	}
 
-type VNCharacterSprite struct {
-	pos   Vec2f
-	image *ebiten.Image
-}
+	fontRenderer := g.fontRenderer
+	fontRenderer.PushState()
+	fontRenderer.Reset()
+	fontRenderer.SetScale(2.0)
+	fontRenderer.EnableShadow(true)
+	fontRenderer.SetTextColor(color.RGBA{255, 224, 64, 255})
 
-func (sprite *VNCharacterSprite) Draw(screen *ebiten.Image) {
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Translate(sprite.pos.X, sprite.pos.Y)
+	fontRenderer.DrawTextAt(screen, fmt.Sprintf("%s: %d", I18n("string_currency", "Rupees"), g.char.GetMoney()), Vec2f{16, 16})
 
-	screen.DrawImage(sprite.image, op)
+	fontRenderer.PopState()
 }
 
-type VNCharacter struct {
-	IVNCharacter
-	sprites []*VNSpriteListCategory
+func (s *ShopScreen) Draw(screen *ebiten.Image) {
 
-	width        uint
-	height       uint
-	name         string
-	matTransform ebiten.GeoM
-}
+	DrawStretchedImage(screen, s.backgroundImage, Rect{0, 0, screenWidth, screenHeight})
 
-func (char *VNCharacter) Draw(screen *ebiten.Image) {
 	op := &ebiten.DrawImageOptions{}
+	imgWidth, imgHeight := s.tableImage.Size()
+	op.GeoM.Translate(-float64(imgWidth/2), -float64(imgHeight/2))
+	op.GeoM.Translate(screenWidth/2, float64(screenHeight-imgHeight/2))
 
-	for i := 0; i < len(char.sprites); i++ {
-		sprite := char.sprites[i].current
-
-		op.GeoM.Reset()
-		op.GeoM.Translate(sprite.pos.X, sprite.pos.Y)
-		op.GeoM.Concat(char.matTransform)
-		screen.DrawImage(sprite.image, op)
+	if s.keeper != nil {
+		s.keeper.Draw(screen)
 	}
-}
-
-type VNAssetGroupMapping struct {
-	name       string
-	assetNames []string
-}
-
-var g_morshuMapping = []VNAssetGroupMapping{
-	{
-		name: "body",
-		assetNames: []string{
-			"morshu_090.png",
-		},
-	},
-	{
-		name: "facial",
-		assetNames: []string{
-			"morshu_001.png",
-			"morshu_002.png",
-		},
-	},
-}
-
-var g_endouMapping = []VNAssetGroupMapping{
-	{
-		name: "body:",
-		assetNames: []string{
-			"endou_044.png",
-			"endou_047.png",
-			"endou_050.png",
-			"endou_053.png",
-		},
-	},
-	{
-		name: "facial",
-		assetNames: []string{
-			"endou_001.png",
-			"endou_002.png",
-			"endou_003.png",
-			"endou_004.png",
-			"endou_005.png",
-			"endou_006.png",
-			"endou_007.png",
-			"endou_008.png",
-			"endou_009.png",
-			"endou_010.png",
-			"endou_011.png",
-			"endou_012.png",
-			"endou_013.png",
-			"endou_014.png",
-			"endou_015.png",
-			"endou_016.png",
-			"endou_017.png",
-			"endou_018.png",
-			"endou_019.png",
-			"endou_020.png",
-			"endou_021.png",
-			"endou_022.png",
-			"endou_023.png",
-			"endou_024.png",
-			"endou_025.png",
-			"endou_026.png",
-			"endou_027.png",
-			"endou_028.png",
-			"endou_029.png",
-			"endou_030.png",
-			"endou_031.png",
-			"endou_032.png",
-			"endou_033.png",
-			"endou_034.png",
-			"endou_035.png",
-			"endou_036.png",
-			"endou_037.png",
-			"endou_038.png",
-			"endou_039.png",
-			"endou_040.png",
-		},
-	},
-}
-
-func LoadGroupAssets(char *VNCharacter, j JSONLayeredImage, coherence []VNAssetGroupMapping, dir string) {
 
-	rm := ResourceManager_GetInstance()
+	DrawImage(screen, s.tableImage, op)
 
-	for _, coh := range coherence {
-		list := new(VNSpriteListCategory)
-		for _, e := range j.Layers {
-			for _, z := range coh.assetNames {
-				if e.Name == z {
-					sp := new(VNCharacterSprite)
-					sp.image = rm.LoadImage(dir + e.Name)
-					sp.pos.X = float64(e.OffsetX)
-					sp.pos.Y = float64(e.OffsetY)
-
-					list.sprites = append(list.sprites, sp)
-					break
-				}
-			}
-		}
-		list.name = coh.name
-		list.current = list.sprites[0]
-		char.sprites = append(char.sprites, list)
+	if s.editor != nil {
+		s.editor.Draw(screen)
+	} else if s.mode != shopModeMenu && s.mode != shopModeDialogue {
+		s.drawList(screen)
 	}
 
-	char.width = j.Width
-	char.height = j.Height
-}
-
-func InitVNCharacter(char *VNCharacter, name string) {
-	char.name = name
-}
+	s.drawMoney(screen)
+	s.dialogueBox.Draw(screen)
 
-func NewVNCharacter(name string) *VNCharacter {
-	char := new(VNCharacter)
-	InitVNCharacter(char, name)
-	return char
+	if s.backlog != nil {
+		s.backlog.Draw(screen)
+	}
 }
 
-func NewVNCharacterFromJSON(path string, name string, assetGroupMapping []VNAssetGroupMapping) *VNCharacter {
-	char := new(VNCharacter)
-	InitVNCharacter(char, name)
-
-	assetPath, _ := filepath.Split(path)
+func InitShopScreen(s *ShopScreen, g *Game, keeperName string) {
+	s.game = g
+	s.prevScreen = g.currentScreen
+	s.dialogueBox = NewDialogueBox(g)
 
-	var layeredImage JSONLayeredImage
-	bytes, err := ioutil.ReadFile(path)
+	keeper, err := LoadShopkeeper(ShopkeeperPath(keeperName))
 	if err != nil {
-		return nil
+		log.Println("[Shop] Failed to load shopkeeper " + keeperName + ": " + err.Error())
 	}
 
-	err = json.Unmarshal(bytes, &layeredImage)
-	if err != nil {
-		return nil
-	}
+	s.keeperName = keeperName
+	s.shop = NewShop(keeper)
 
-	LoadGroupAssets(char, layeredImage, assetGroupMapping, assetPath)
+	if keeper.Dialogue != "" {
+		script, err := LoadDialogue(keeper.Dialogue)
+		if err != nil {
+			log.Println("[Shop] Failed to load dialogue " + keeper.Dialogue + ": " + err.Error())
+		} else {
+			s.runner = NewDialogueRunner(g, script, s.dialogueBox)
+			s.runner.SetEventHandler(s.handleDialogueEvent)
+		}
+	}
+}
 
-	return char
+// Opens the shop of the default shopkeeper
+func NewShopScreen(g *Game) *ShopScreen {
+	return NewShopkeeperScreen(g, defaultShopkeeper)
 }
 
-type VNSpriteListCategory struct {
-	name    string
-	sprites []*VNCharacterSprite
-	current *VNCharacterSprite
+// Opens the shop of a shopkeeper stored in assets/shop/<name>/shop.json
+func NewShopkeeperScreen(g *Game, keeperName string) *ShopScreen {
+	s := new(ShopScreen)
+	InitShopScreen(s, g, keeperName)
+	s.IScreen = s
+	return s
 }
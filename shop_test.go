@@ -0,0 +1,132 @@
+package main
+
+import "testing"
+
+func newTestShop() *Shop {
+	return NewShop(&JSONShopkeeper{
+		Name: "test",
+		Stock: []JSONShopItem{
+			{Item: "aid", Price: 20, Count: 1},
+			{Item: flowerSeedsItem, Price: 5, Count: -1},
+			{Item: "berries", SellPrice: 2},
+		},
+	})
+}
+
+func TestShopBuy(t *testing.T) {
+	sim, _ := newTestSimulation(t, newTestLevel())
+	customer := sim.GetGame().char
+	shop := newTestShop()
+
+	if err := shop.Buy(customer, "aid", 1); err != errShopNoMoney {
+		t.Fatalf("buying without money: %v", err)
+	}
+
+	customer.AddMoney(50)
+
+	if err := shop.Buy(customer, "aid", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if customer.GetMoney() != 30 || customer.GetItemCount("aid") != 1 {
+		t.Fatalf("money = %d, aid = %d after buying", customer.GetMoney(), customer.GetItemCount("aid"))
+	}
+
+	if err := shop.Buy(customer, "aid", 1); err != errShopSoldOut {
+		t.Fatalf("buying a sold out item: %v", err)
+	}
+
+	if err := shop.Buy(customer, "berries", 1); err != errShopNotSold {
+		t.Fatalf("buying an item that is not sold: %v", err)
+	}
+
+	if err := shop.Buy(customer, flowerSeedsItem, 6); err != nil || shop.GetItem(flowerSeedsItem).Count != -1 {
+		t.Fatalf("buying endless stock: %v, count %d", err, shop.GetItem(flowerSeedsItem).Count)
+	}
+}
+
+func TestShopBuyIntoFullInventory(t *testing.T) {
+	sim, _ := newTestSimulation(t, newTestLevel())
+	customer := sim.GetGame().char
+	shop := newTestShop()
+
+	customer.AddMoney(1000)
+	customer.AddItem("poop", (inventoryColumns*inventoryRows-1)*10)
+	customer.AddItem(flowerSeedsItem, 19)
+
+	if err := shop.Buy(customer, flowerSeedsItem, 2); err != errShopInventoryFull {
+		t.Fatalf("buying into a full inventory: %v", err)
+	}
+
+	if customer.GetMoney() != 1000 || customer.GetItemCount(flowerSeedsItem) != 19 {
+		t.Fatalf("failed deal left money %d and %d seeds", customer.GetMoney(), customer.GetItemCount(flowerSeedsItem))
+	}
+}
+
+func TestShopSell(t *testing.T) {
+	sim, _ := newTestSimulation(t, newTestLevel())
+	customer := sim.GetGame().char
+	shop := newTestShop()
+
+	if len(shop.GetBuyList(customer)) != 0 {
+		t.Fatal("buy list is not empty for an empty inventory")
+	}
+
+	customer.AddItem("berries", 3)
+	customer.AddItem("aid", 1)
+
+	if list := shop.GetBuyList(customer); len(list) != 1 || list[0].Item != "berries" {
+		t.Fatalf("buy list = %v, want berries only", list)
+	}
+
+	if err := shop.Sell(customer, "aid", 1); err != errShopNotBought {
+		t.Fatalf("selling an item the keeper does not buy: %v", err)
+	}
+
+	if err := shop.Sell(customer, "berries", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if customer.GetMoney() != 6 || shop.GetItem("berries").Count != 3 {
+		t.Fatalf("money = %d, keeper stock = %d after selling", customer.GetMoney(), shop.GetItem("berries").Count)
+	}
+
+	if err := shop.Sell(customer, "berries", 1); err != errShopNoItems {
+		t.Fatalf("selling missing items: %v", err)
+	}
+}
+
+func TestShopScreenEmptyLists(t *testing.T) {
+	sim, _ := newTestSimulation(t, newTestLevel())
+	s := NewShopkeeperScreen(sim.GetGame(), "test")
+	s.shop = NewShop(&JSONShopkeeper{Name: "test", Stock: []JSONShopItem{{Item: "berries", SellPrice: 2}}})
+
+	for mode, want := range map[int]string{
+		shopModeBuy:  I18n("string_shop_sold_out", "Sold out, come back later."),
+		shopModeSell: I18n("string_shop_nothing_to_sell", "You have nothing I would buy."),
+	} {
+		s.OpenList(mode)
+
+		backlog := s.dialogueBox.GetBacklog()
+		if s.GetMode() != shopModeMessage || backlog[len(backlog)-1].text != want {
+			t.Errorf("empty list %d says %q", mode, backlog[len(backlog)-1].text)
+		}
+	}
+}
+
+func TestLoadShopkeepers(t *testing.T) {
+	keeper, err := LoadShopkeeper(ShopkeeperPath(defaultShopkeeper))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, item := range keeper.Stock {
+		if GetItemDef(item.Item) == nil {
+			t.Errorf("%s stocks unknown item %s", defaultShopkeeper, item.Item)
+		}
+
+		if !item.IsSold() && !item.IsBought() {
+			t.Errorf("%s neither sells nor buys %s", defaultShopkeeper, item.Item)
+		}
+	}
+}
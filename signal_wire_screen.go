@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Editor dialog wiring an emitter or receiver tile to a channel
+//
+// The field holds the channel number; a receiver reacting to a low channel
+// is written with a leading "!".
+type SignalWireScreen struct {
+	GenericWidgetContainerScreen
+	gameplayScreen *GameplayScreen
+	x, y           int
+}
+
+func (s *SignalWireScreen) ProcessKeyEvents() bool {
+	if s.GenericWidgetContainerScreen.ProcessKeyEvents() {
+		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			s.gameplayScreen.overlayStack.Pop()
+		}
+	}
+
+	return false
+}
+
+// Parses a channel field: "3" or, for inverted receivers, "!3"
+func ParseSignalChannel(text string) (channel int, invert bool, err error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "!") {
+		invert = true
+		text = text[1:]
+	}
+
+	channel, err = strconv.Atoi(text)
+	if err == nil && channel < 0 {
+		err = errors.New("channel must not be negative")
+	}
+
+	return channel, invert, err
+}
+
+func (s *SignalWireScreen) Apply() error {
+	channel, invert, err := ParseSignalChannel(s.widgets[0].GetText())
+	if err != nil {
+		return err
+	}
+
+	level := s.game.level
+	if invert && level.findSignalTile(s.y*level.width+s.x, isReceiverTile) < 0 {
+		return errors.New("only receivers can be inverted")
+	}
+
+	return level.SetWire(JSONSignalWire{X: s.x, Y: s.y, Channel: channel, Invert: invert})
+}
+
+func CreateSignalWireScreen(gameplayScreen *GameplayScreen, x, y int) *SignalWireScreen {
+	s := new(SignalWireScreen)
+	InititalizeGenericWidgetContainerScreen(&s.GenericWidgetContainerScreen)
+
+	s.gameplayScreen = gameplayScreen
+	s.game = gameplayScreen.game
+	s.x = x
+	s.y = y
+	g := gameplayScreen.game
+	s.title = I18n("string_signal_wire", "Signal channel (! inverts a receiver)")
+
+	text := "0"
+	if wire := g.level.signals.GetEmitter(x, y); wire != nil {
+		text = strconv.Itoa(wire.Channel)
+	} else if wire := g.level.signals.GetReceiver(x, y); wire != nil {
+		text = strconv.Itoa(wire.Channel)
+		if wire.Invert {
+			text = "!" + text
+		}
+	}
+
+	editBox := CreateCommonEditBox(g, func(g *Game) {})
+	editBox.SetText(text)
+	s.widgets = append(s.widgets, editBox)
+
+	s.widgets = append(s.widgets, CreateCommonButton(g, I18n("string_verb_save", "Save"), func(g *Game) {
+		if err := s.Apply(); err != nil {
+			log.Println("[Signals] Failed to wire tile: " + err.Error())
+			return
+		}
+		gameplayScreen.overlayStack.Pop()
+	}))
+
+	s.widgets = append(s.widgets, CreateCommonButton(g, I18n("string_verb_remove", "Remove"), func(g *Game) {
+		g.level.RemoveWire(x, y)
+		gameplayScreen.overlayStack.Pop()
+	}))
+
+	s.SetInitialFocus()
+	return s
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Tile wired to a channel. Emitters drive the channel, receivers follow it;
+// an inverted receiver reacts to a low channel instead.
+type JSONSignalWire struct {
+	X       int  `json:"x"`
+	Y       int  `json:"y"`
+	Channel int  `json:"channel"`
+	Invert  bool `json:"invert,omitempty"`
+}
+
+const (
+	gateAnd    = "and"
+	gateOr     = "or"
+	gateToggle = "toggle"
+	gateTimer  = "timer"
+)
+
+// Logic gate driving its output channel from input channels
+//
+// "and" and "or" combine inputs, "toggle" flips on every rising edge of any
+// input and "timer" stays high for Ticks after one.
+type JSONSignalGate struct {
+	Type   string `json:"type"`
+	Inputs []int  `json:"inputs"`
+	Output int    `json:"output"`
+	Ticks  int    `json:"ticks,omitempty"`
+}
+
+// Logic circuit of a level, stored next to the level file
+//
+// ConveyorSpeed is in pixels per tick, zero means defaultConveyorSpeed.
+type JSONLevelSignals struct {
+	Emitters      []JSONSignalWire `json:"emitters,omitempty"`
+	Receivers     []JSONSignalWire `json:"receivers,omitempty"`
+	Gates         []JSONSignalGate `json:"gates,omitempty"`
+	ConveyorSpeed float64          `json:"conveyor_speed,omitempty"`
+}
+
+func LevelSignalsPath(levelPath string) string {
+	if IsWorldDir(levelPath) {
+		return filepath.Join(levelPath, "signals.json")
+	}
+
+	return strings.TrimSuffix(levelPath, filepath.Ext(levelPath)) + ".signals.json"
+}
+
+// Reads the level's circuit; a level without one has no wiring
+func LoadLevelSignals(levelPath string) (*JSONLevelSignals, error) {
+	signals := new(JSONLevelSignals)
+
+	data, err := os.ReadFile(LevelSignalsPath(levelPath))
+	if os.IsNotExist(err) {
+		return signals, nil
+	} else if err != nil {
+		return signals, err
+	}
+
+	err = json.Unmarshal(data, signals)
+	return signals, err
+}
+
+func SaveLevelSignals(levelPath string, signals *JSONLevelSignals) error {
+	data, err := json.MarshalIndent(signals, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(LevelSignalsPath(levelPath), data, 0644)
+}
+
+func (s *JSONLevelSignals) IsEmpty() bool {
+	return len(s.Emitters) == 0 && len(s.Receivers) == 0 && len(s.Gates) == 0
+}
+
+func findWire(wires []JSONSignalWire, x, y int) int {
+	for i, wire := range wires {
+		if wire.X == x && wire.Y == y {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (s *JSONLevelSignals) GetEmitter(x, y int) *JSONSignalWire {
+	if i := findWire(s.Emitters, x, y); i >= 0 {
+		return &s.Emitters[i]
+	}
+
+	return nil
+}
+
+func (s *JSONLevelSignals) GetReceiver(x, y int) *JSONSignalWire {
+	if i := findWire(s.Receivers, x, y); i >= 0 {
+		return &s.Receivers[i]
+	}
+
+	return nil
+}
+
+func setWire(wires []JSONSignalWire, wire JSONSignalWire) []JSONSignalWire {
+	if i := findWire(wires, wire.X, wire.Y); i >= 0 {
+		wires[i] = wire
+		return wires
+	}
+
+	return append(wires, wire)
+}
+
+func removeWire(wires []JSONSignalWire, x, y int) []JSONSignalWire {
+	if i := findWire(wires, x, y); i >= 0 {
+		return append(wires[:i], wires[i+1:]...)
+	}
+
+	return wires
+}
+
+// Wires an emitter or receiver tile, depending on what the tile is
+func (lv *Level) SetWire(wire JSONSignalWire) error {
+	tilePos := wire.Y*lv.width + wire.X
+
+	switch {
+	case lv.findSignalTile(tilePos, isEmitterTile) >= 0:
+		lv.signals.Emitters = setWire(lv.signals.Emitters, wire)
+	case lv.findSignalTile(tilePos, isReceiverTile) >= 0:
+		lv.signals.Receivers = setWire(lv.signals.Receivers, wire)
+	default:
+		return errors.New("tile is neither an emitter nor a receiver")
+	}
+
+	return nil
+}
+
+func (lv *Level) RemoveWire(x, y int) {
+	lv.signals.Emitters = removeWire(lv.signals.Emitters, x, y)
+	lv.signals.Receivers = removeWire(lv.signals.Receivers, x, y)
+}
+
+func (lv *Level) HasWiring() bool {
+	return lv != nil && lv.signals != nil && !lv.signals.IsEmpty()
+}
+
+func (lv *Level) SetSignals(signals *JSONLevelSignals) {
+	lv.signals = signals
+	lv.circuit = NewSignalCircuit()
+}
+
+func isEmitterTile(tile Tile) bool {
+	switch tile {
+	case tileIDSwitch, tileIDSwitchActive, tileIDButton, tileIDButtonPushed, tileIDWeigth, tileIDTarget:
+		return true
+	}
+
+	return false
+}
+
+func isReceiverTile(tile Tile) bool {
+	switch tile {
+	case tileIDThorns, tileIDThornsActive,
+		tileIDPoppingBarrier, tileIDPoppingBarrierPushed, tileIDPoppingBarrierActive,
+		tileIDDoor, tileIDElevator,
+		tileIDArrowRight, tileIDArrowLeft, tileIDArrowUp, tileIDArrowDown:
+		return true
+	}
+
+	return false
+}
+
+// Returns the layer of the first tile matching the filter, or -1
+func (lv *Level) findSignalTile(tilePos int, filter func(Tile) bool) int {
+	for i := 0; i < lv.GetLayerCount(); i++ {
+		if filter(lv.GetTile(i, tilePos)) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Runtime state of a level circuit
+type SignalCircuit struct {
+	channels map[int]bool
+	// Previous input level and output of stateful gates, by gate index
+	gateInputs  map[int]bool
+	gateOutputs map[int]bool
+	timers      map[int]int
+}
+
+func NewSignalCircuit() *SignalCircuit {
+	c := new(SignalCircuit)
+	c.channels = make(map[int]bool)
+	c.gateInputs = make(map[int]bool)
+	c.gateOutputs = make(map[int]bool)
+	c.timers = make(map[int]int)
+	return c
+}
+
+func (c *SignalCircuit) GetChannel(channel int) bool {
+	return c.channels[channel]
+}
+
+// Returns whether an entity or a box is on the tile
+func (g *Game) isTileOccupied(tilePos int, occupied map[int]bool) bool {
+	return occupied[tilePos] || g.findBoxLayer(tilePos) >= 0
+}
+
+// Returns the emitter level and updates button tiles to show it
+func (g *Game) readEmitter(wire JSONSignalWire, occupied map[int]bool) bool {
+	level := g.level
+	tilePos := wire.Y*level.width + wire.X
+
+	layer := level.findSignalTile(tilePos, isEmitterTile)
+	if layer < 0 {
+		return false
+	}
+
+	switch level.GetTile(layer, tilePos) {
+	case tileIDSwitchActive:
+		return true
+
+	case tileIDButton, tileIDButtonPushed:
+		pressed := g.isTileOccupied(tilePos, occupied)
+		if pressed {
+			level.SetTile(layer, tilePos, tileIDButtonPushed)
+		} else {
+			level.SetTile(layer, tilePos, tileIDButton)
+		}
+		return pressed
+
+	case tileIDWeigth:
+		return g.isTileOccupied(tilePos, occupied)
+
+	case tileIDTarget:
+		return g.findBoxLayer(tilePos) >= 0 || g.isScoredBallAt(tilePos)
+	}
+
+	return false
+}
+
+// Sets a receiver tile to its powered or unpowered look
+func (g *Game) driveReceiver(wire JSONSignalWire, powered bool) {
+	level := g.level
+	tilePos := wire.Y*level.width + wire.X
+
+	layer := level.findSignalTile(tilePos, isReceiverTile)
+	if layer < 0 {
+		return
+	}
+
+	switch level.GetTile(layer, tilePos) {
+	case tileIDThorns, tileIDThornsActive:
+		if powered {
+			level.SetTile(layer, tilePos, tileIDThorns)
+		} else {
+			level.SetTile(layer, tilePos, tileIDThornsActive)
+		}
+
+	case tileIDPoppingBarrier, tileIDPoppingBarrierPushed, tileIDPoppingBarrierActive:
+		if powered {
+			level.SetTile(layer, tilePos, tileIDPoppingBarrierPushed)
+		} else {
+			level.SetTile(layer, tilePos, tileIDPoppingBarrierActive)
+		}
+	}
+}
+
+func (c *SignalCircuit) evaluateGate(index int, gate JSONSignalGate) bool {
+	any, all := false, len(gate.Inputs) > 0
+	for _, input := range gate.Inputs {
+		any = any || c.channels[input]
+		all = all && c.channels[input]
+	}
+
+	rising := any && !c.gateInputs[index]
+	c.gateInputs[index] = any
+
+	switch gate.Type {
+	case gateAnd:
+		return all
+
+	case gateOr:
+		return any
+
+	case gateToggle:
+		if rising {
+			c.gateOutputs[index] = !c.gateOutputs[index]
+		}
+		return c.gateOutputs[index]
+
+	case gateTimer:
+		if rising {
+			c.timers[index] = gate.Ticks
+		} else if c.timers[index] > 0 {
+			c.timers[index]--
+		}
+		return c.timers[index] > 0
+	}
+
+	return false
+}
+
+// Runs the level circuit for one tick: reads emitters, evaluates gates in
+// file order and drives receivers. Levels without wiring are skipped.
+func (g *Game) UpdateSignals() {
+	level := g.level
+	if !level.HasWiring() {
+		return
+	}
+
+	c := level.circuit
+
+	occupied := make(map[int]bool)
+	for _, entity := range g.entities {
+		if tilePos, err := entity.GetTilePos(); err == nil {
+			occupied[tilePos] = true
+		}
+	}
+
+	channels := make(map[int]bool)
+	for _, wire := range level.signals.Emitters {
+		if g.readEmitter(wire, occupied) {
+			channels[wire.Channel] = true
+		}
+	}
+	c.channels = channels
+
+	for i, gate := range level.signals.Gates {
+		if c.evaluateGate(i, gate) {
+			c.channels[gate.Output] = true
+		}
+	}
+
+	for _, wire := range level.signals.Receivers {
+		g.driveReceiver(wire, c.channels[wire.Channel] != wire.Invert)
+	}
+}
+
+// Returns whether a wired door, elevator or conveyor is powered; unwired
+// ones always are
+func (g *Game) IsReceiverPowered(tilePos int) bool {
+	level := g.level
+	if !level.HasWiring() {
+		return true
+	}
+
+	wire := level.signals.GetReceiver(tilePos%level.width, tilePos/level.width)
+	if wire == nil {
+		return true
+	}
+
+	return level.circuit.GetChannel(wire.Channel) != wire.Invert
+}
+
+// Flips a wired switch the player stepped on
+func (g *Game) toggleSwitch(tilePos int) {
+	level := g.level
+
+	for i := 0; i < level.GetLayerCount(); i++ {
+		switch level.GetTile(i, tilePos) {
+		case tileIDSwitch:
+			level.SetTile(i, tilePos, tileIDSwitchActive)
+		case tileIDSwitchActive:
+			level.SetTile(i, tilePos, tileIDSwitch)
+		}
+	}
+}
+
+// Describes the wiring for the editor and the console, one line per wire
+func (s *JSONLevelSignals) Describe() []string {
+	var lines []string
+
+	for _, wire := range s.Emitters {
+		lines = append(lines, fmt.Sprintf("emitter %d,%d -> %d", wire.X, wire.Y, wire.Channel))
+	}
+
+	for _, wire := range s.Receivers {
+		invert := ""
+		if wire.Invert {
+			invert = "!"
+		}
+		lines = append(lines, fmt.Sprintf("receiver %d,%d <- %s%d", wire.X, wire.Y, invert, wire.Channel))
+	}
+
+	for i, gate := range s.Gates {
+		var inputs []string
+		for _, input := range gate.Inputs {
+			inputs = append(inputs, fmt.Sprint(input))
+		}
+
+		line := fmt.Sprintf("gate %d: %s(%s) -> %d", i, gate.Type, strings.Join(inputs, ","), gate.Output)
+		if gate.Type == gateTimer {
+			line += fmt.Sprintf(" for %d ticks", gate.Ticks)
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func IsGateType(name string) bool {
+	switch name {
+	case gateAnd, gateOr, gateToggle, gateTimer:
+		return true
+	}
+
+	return false
+}
+
+// Returns the channels used by the circuit in ascending order
+func (s *JSONLevelSignals) GetChannels() []int {
+	used := make(map[int]bool)
+
+	for _, wire := range s.Emitters {
+		used[wire.Channel] = true
+	}
+	for _, wire := range s.Receivers {
+		used[wire.Channel] = true
+	}
+	for _, gate := range s.Gates {
+		used[gate.Output] = true
+		for _, input := range gate.Inputs {
+			used[input] = true
+		}
+	}
+
+	var channels []int
+	for channel := range used {
+		channels = append(channels, channel)
+	}
+	sort.Ints(channels)
+
+	return channels
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWiredButtonOpensBarrier(t *testing.T) {
+	level := newTestLevel()
+	buttonPos := tileIndex(level, 3, 3)
+	barrierPos := tileIndex(level, 3, 5)
+	thornsPos := tileIndex(level, 7, 7)
+	level.SetTile(testObjectLayer, buttonPos, tileIDButton)
+	level.SetTile(testObjectLayer, barrierPos, tileIDPoppingBarrierActive)
+	level.SetTile(testObjectLayer, thornsPos, tileIDThornsActive)
+
+	level.SetSignals(&JSONLevelSignals{
+		Emitters:  []JSONSignalWire{{X: 3, Y: 3, Channel: 1}},
+		Receivers: []JSONSignalWire{{X: 3, Y: 5, Channel: 1}, {X: 7, Y: 7, Channel: 1, Invert: true}},
+	})
+
+	sim, input := newTestSimulation(t, level)
+	sim.Spawn(sim.GetGame().char, 2, 3)
+	sim.Step(1)
+
+	if got := level.GetTile(testObjectLayer, barrierPos); got != tileIDPoppingBarrierActive {
+		t.Fatalf("barrier tile = %d before the button, want %d", got, tileIDPoppingBarrierActive)
+	}
+
+	if got := level.GetTile(testObjectLayer, thornsPos); got != tileIDThorns {
+		t.Fatalf("inverted thorns tile = %d, want %d", got, tileIDThorns)
+	}
+
+	input.Hold(input.NextTick(), tileSize, kbPlayerMoveRight)
+	sim.Step(tileSize + 1)
+
+	if got := level.GetTile(testObjectLayer, buttonPos); got != tileIDButtonPushed {
+		t.Fatalf("button tile = %d, want %d", got, tileIDButtonPushed)
+	}
+
+	if got := level.GetTile(testObjectLayer, barrierPos); got != tileIDPoppingBarrierPushed {
+		t.Fatalf("barrier tile = %d on the button, want %d", got, tileIDPoppingBarrierPushed)
+	}
+
+	if got := level.GetTile(testObjectLayer, thornsPos); got != tileIDThornsActive {
+		t.Fatalf("inverted thorns tile = %d, want %d", got, tileIDThornsActive)
+	}
+
+	// Stepping off releases the button and raises the barrier again
+	input.Hold(input.NextTick(), tileSize, kbPlayerMoveRight)
+	sim.Step(tileSize + 1)
+
+	if got := level.GetTile(testObjectLayer, buttonPos); got != tileIDButton {
+		t.Fatalf("button tile = %d, want released %d", got, tileIDButton)
+	}
+
+	if got := level.GetTile(testObjectLayer, barrierPos); got != tileIDPoppingBarrierActive {
+		t.Fatalf("barrier tile = %d after the button, want %d", got, tileIDPoppingBarrierActive)
+	}
+}
+
+func TestSignalGates(t *testing.T) {
+	c := NewSignalCircuit()
+	and := JSONSignalGate{Type: gateAnd, Inputs: []int{1, 2}}
+	toggle := JSONSignalGate{Type: gateToggle, Inputs: []int{1}}
+	timer := JSONSignalGate{Type: gateTimer, Inputs: []int{1}, Ticks: 3}
+
+	steps := []struct {
+		channels               map[int]bool
+		and, toggle, timerHigh bool
+	}{
+		{map[int]bool{1: true}, false, true, true},
+		{map[int]bool{1: true, 2: true}, true, true, true},
+		{map[int]bool{}, false, true, true},
+		{map[int]bool{}, false, true, false},
+		{map[int]bool{1: true}, false, false, true},
+	}
+
+	for i, step := range steps {
+		c.channels = step.channels
+
+		if got := c.evaluateGate(0, and); got != step.and {
+			t.Errorf("step %d: and = %v, want %v", i, got, step.and)
+		}
+
+		if got := c.evaluateGate(1, toggle); got != step.toggle {
+			t.Errorf("step %d: toggle = %v, want %v", i, got, step.toggle)
+		}
+
+		if got := c.evaluateGate(2, timer); got != step.timerHigh {
+			t.Errorf("step %d: timer = %v, want %v", i, got, step.timerHigh)
+		}
+	}
+}
+
+func TestLevelSignalsSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "level0.lvl")
+
+	signals := &JSONLevelSignals{
+		Emitters:  []JSONSignalWire{{X: 1, Y: 2, Channel: 3}},
+		Receivers: []JSONSignalWire{{X: 4, Y: 5, Channel: 3, Invert: true}},
+		Gates:     []JSONSignalGate{{Type: gateTimer, Inputs: []int{3}, Output: 4, Ticks: 60}},
+	}
+
+	if err := SaveLevelSignals(path, signals); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadLevelSignals(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(loaded, signals) {
+		t.Fatalf("loaded signals = %+v, want %+v", loaded, signals)
+	}
+
+	if got := loaded.GetChannels(); !reflect.DeepEqual(got, []int{3, 4}) {
+		t.Fatalf("channels = %v, want [3 4]", got)
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"log"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Ticks the player has to lean into a box before it moves
+const sokobanPushDelay = 8
+
+// Pushes kept for undo
+const sokobanMaxUndo = 64
+
+// A box fallen into a pit turns it into walkable ground; there is no
+// dedicated sprite for it
+const tileIDFilledPit = tileIDPavedRoad
+
+// Ticks the level complete banner stays on screen
+const sokobanCompleteBannerTicks = 180
+
+type SokobanEventKind int
+
+const (
+	sokobanBoxOnTarget SokobanEventKind = iota
+	sokobanBoxOffTarget
+	sokobanBoxOnWeight
+	sokobanBoxOffWeight
+	sokobanPitFilled
+	sokobanLevelComplete
+)
+
+type SokobanEvent struct {
+	kind    SokobanEventKind
+	tilePos int
+}
+
+type SokobanListener func(g *Game, event SokobanEvent)
+
+type sokobanTileChange struct {
+	layer   int
+	tilePos int
+	tile    Tile
+}
+
+// Tiles overwritten by a push and where the player stood before it
+type sokobanMove struct {
+	changes   []sokobanTileChange
+	playerPos Vec2f
+}
+
+// Box pushing state of the current level
+type Sokoban struct {
+	pushTicks    int
+	lastPushTick int
+	undo         []sokobanMove
+	covered      map[int]Tile
+	complete     bool
+	completeTick int
+	listeners    []SokobanListener
+}
+
+// Creates the box state with the default weight behavior
+func NewSokoban() *Sokoban {
+	s := new(Sokoban)
+	s.covered = make(map[int]Tile)
+	s.lastPushTick = -1
+	s.AddListener(sokobanWeightListener)
+	return s
+}
+
+// Adds a function called on every box event
+func (s *Sokoban) AddListener(listener SokobanListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+// Forgets pushes and box state of the previous level and takes the state of
+// the current one
+func (g *Game) ResetSokoban() {
+	if g.sokoban == nil {
+		g.sokoban = NewSokoban()
+	}
+
+	s := g.sokoban
+	s.undo = nil
+	s.covered = make(map[int]Tile)
+	s.pushTicks = 0
+	s.complete = false
+
+	if g.level != nil {
+		g.UpdateSokobanState()
+	}
+}
+
+func (s *Sokoban) IsComplete() bool {
+	return s.complete
+}
+
+func (s *Sokoban) GetUndoCount() int {
+	return len(s.undo)
+}
+
+func (g *Game) emitSokobanEvent(kind SokobanEventKind, tilePos int) {
+	event := SokobanEvent{kind, tilePos}
+
+	for _, listener := range g.sokoban.listeners {
+		listener(g, event)
+	}
+}
+
+// Returns the layer holding a box at the tile, or -1
+func (g *Game) findBoxLayer(tilePos int) int {
+	for i := 0; i < g.level.GetLayerCount(); i++ {
+		if g.level.GetTile(i, tilePos) == tileIDSokobanBox {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (g *Game) hasTileAt(tilePos int, tile Tile) int {
+	for i := 0; i < g.level.GetLayerCount(); i++ {
+		if g.level.GetTile(i, tilePos) == tile {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Returns the tile position next to the given one in the look direction
+func (g *Game) neighbourTile(tilePos int, look LookDirection) (int, bool) {
+	x, y := tilePos%g.level.width, tilePos/g.level.width
+
+	switch look {
+	case LooksRight:
+		x++
+	case LooksLeft:
+		x--
+	case LooksUp:
+		y--
+	case LooksDown:
+		y++
+	}
+
+	if !g.level.ContainsTile(x, y) || !g.level.IsTileLoaded(y*g.level.width+x) {
+		return 0, false
+	}
+
+	return y*g.level.width + x, true
+}
+
+func (g *Game) isEntityOnTile(tilePos int) bool {
+	for _, entity := range g.entities {
+		if entityPos, err := entity.GetTilePos(); err == nil && entityPos == tilePos {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Called when an entity walks into a solid tile; the player pushes boxes
+//
+// The box moves after the player leaned into it for sokobanPushDelay
+// ticks, see moveBox.
+func (g *Game) TryPushBox(e *LivingEntity, worldX, worldY float64) bool {
+	if g.char == nil || g.char.GetLivingEntity() != e {
+		return false
+	}
+
+	boxPos, err := g.WorldPosToTilePos(worldX, worldY)
+	if err != nil {
+		return false
+	}
+
+	boxLayer := g.findBoxLayer(boxPos)
+	if boxLayer < 0 {
+		return false
+	}
+
+	s := g.sokoban
+	if s.lastPushTick != tickCounter-1 {
+		s.pushTicks = 0
+	}
+	s.lastPushTick = tickCounter
+	s.pushTicks++
+
+	if s.pushTicks < sokobanPushDelay {
+		return false
+	}
+	s.pushTicks = 0
+
+	move := sokobanMove{playerPos: e.worldPos}
+	if !g.moveBox(boxLayer, boxPos, e.look, &move) {
+		return false
+	}
+
+	s.undo = append(s.undo, move)
+	if len(s.undo) > sokobanMaxUndo {
+		s.undo = s.undo[1:]
+	}
+
+	g.UpdateSokobanState()
+	return true
+}
+
+// Moves a box one tile in the look direction, recording overwritten tiles
+// in the move
+//
+// The destination must be free: no solid tile, no other box and no entity.
+// A box moved into a pit fills it.
+func (g *Game) moveBox(boxLayer, boxPos int, look LookDirection, move *sokobanMove) bool {
+	dest, ok := g.neighbourTile(boxPos, look)
+	if !ok || g.findBoxLayer(dest) >= 0 || g.isEntityOnTile(dest) {
+		return false
+	}
+
+	pitLayer := g.hasTileAt(dest, tileIDPit)
+
+	if pitLayer < 0 {
+		if g.level.GetTile(boxLayer, dest) != tileIDEmpty {
+			return false
+		}
+
+		for i := 0; i < g.level.GetLayerCount(); i++ {
+			if !tileDescStorage[g.level.GetTile(i, dest)].Walkable {
+				return false
+			}
+		}
+	}
+
+	set := func(layer, tilePos int, tile Tile) {
+		move.changes = append(move.changes, sokobanTileChange{layer, tilePos, g.level.GetTile(layer, tilePos)})
+		g.level.SetTile(layer, tilePos, tile)
+	}
+
+	set(boxLayer, boxPos, tileIDEmpty)
+
+	if pitLayer >= 0 {
+		set(pitLayer, dest, tileIDFilledPit)
+		g.emitSokobanEvent(sokobanPitFilled, dest)
+	} else {
+		set(boxLayer, dest, tileIDSokobanBox)
+	}
+
+	return true
+}
+
+// Reverts the last push and puts the player back where they stood
+func (g *Game) UndoPush() bool {
+	s := g.sokoban
+	if len(s.undo) == 0 {
+		return false
+	}
+
+	move := s.undo[len(s.undo)-1]
+	s.undo = s.undo[:len(s.undo)-1]
+
+	for i := len(move.changes) - 1; i >= 0; i-- {
+		change := move.changes[i]
+		g.level.SetTile(change.layer, change.tilePos, change.tile)
+	}
+
+	if g.char != nil {
+		e := g.char.GetLivingEntity()
+		e.worldPos = move.playerPos
+		e.prevTilePos, _ = e.GetTilePos()
+	}
+
+	g.UpdateSokobanState()
+	return true
+}
+
+// Compares boxes on targets and weights with the previous state, emitting
+// events for the differences, and checks for level completion
+func (g *Game) UpdateSokobanState() {
+	s := g.sokoban
+	covered := make(map[int]Tile)
+	targets, coveredTargets := 0, 0
+
+	for _, chunk := range g.level.GetLoadedChunks() {
+		for y := chunk.y * levelChunkSize; y < (chunk.y+1)*levelChunkSize && y < g.level.height; y++ {
+			for x := chunk.x * levelChunkSize; x < (chunk.x+1)*levelChunkSize && x < g.level.width; x++ {
+				tilePos := y*g.level.width + x
+				box := g.findBoxLayer(tilePos) >= 0
+
+				if g.hasTileAt(tilePos, tileIDTarget) >= 0 {
+					targets++
+					if box {
+						coveredTargets++
+						covered[tilePos] = tileIDTarget
+					}
+				} else if box && g.hasTileAt(tilePos, tileIDWeigth) >= 0 {
+					covered[tilePos] = tileIDWeigth
+				}
+			}
+		}
+	}
+
+	previous := s.covered
+	s.covered = covered
+
+	// Listeners change tiles, so events go out in a fixed order
+	for _, tilePos := range sortedTileKeys(previous) {
+		if tile := previous[tilePos]; covered[tilePos] != tile {
+			if tile == tileIDTarget {
+				g.emitSokobanEvent(sokobanBoxOffTarget, tilePos)
+			} else {
+				g.emitSokobanEvent(sokobanBoxOffWeight, tilePos)
+			}
+		}
+	}
+
+	for _, tilePos := range sortedTileKeys(covered) {
+		if tile := covered[tilePos]; previous[tilePos] != tile {
+			if tile == tileIDTarget {
+				g.emitSokobanEvent(sokobanBoxOnTarget, tilePos)
+			} else {
+				g.emitSokobanEvent(sokobanBoxOnWeight, tilePos)
+			}
+		}
+	}
+
+	complete := targets > 0 && coveredTargets == targets
+	if complete && !s.complete {
+		s.completeTick = tickCounter
+		log.Println("[Sokoban] Level complete")
+		g.emitSokobanEvent(sokobanLevelComplete, -1)
+	}
+	s.complete = complete
+}
+
+func sortedTileKeys(tiles map[int]Tile) []int {
+	var keys []int
+	for tilePos := range tiles {
+		keys = append(keys, tilePos)
+	}
+	sort.Ints(keys)
+
+	return keys
+}
+
+// Default reaction to weights: a box on a weight holds thorns down like a
+// switch, lifting the last box raises them again. Wired levels use the
+// circuit instead.
+func sokobanWeightListener(g *Game, event SokobanEvent) {
+	if g.level.HasWiring() {
+		return
+	}
+
+	switch event.kind {
+	case sokobanBoxOnWeight:
+		g.level.ReplaceAll(tileIDThornsActive, tileIDThorns)
+
+	case sokobanBoxOffWeight:
+		for _, tile := range g.sokoban.covered {
+			if tile == tileIDWeigth {
+				return
+			}
+		}
+
+		g.level.ReplaceAll(tileIDThorns, tileIDThornsActive)
+	}
+}
+
+func (g *Game) DrawSokobanBanner(screen *ebiten.Image) {
+	s := g.sokoban
+	if !s.complete || tickCounter-s.completeTick > sokobanCompleteBannerTicks {
+		return
+	}
+
+	g.DrawBanner(screen, I18n("string_level_complete", "Level complete"))
+}
@@ -0,0 +1,107 @@
+package main
+
+import "testing"
+
+// Level with the player at 2,3 and a box at 4,3, walking right for the
+// given number of ticks: 24 to reach the box, 8 more to push it
+func newSokobanTest(t *testing.T, floor Tile, object Tile, ticks int) (*HeadlessSimulation, *Level) {
+	t.Helper()
+
+	level := newTestLevel()
+	level.SetTile(testObjectLayer, tileIndex(level, 4, 3), tileIDSokobanBox)
+	if floor != tileIDEmpty {
+		level.SetTile(testFloorLayer, tileIndex(level, 5, 3), floor)
+	}
+	if object != tileIDEmpty {
+		level.SetTile(testObjectLayer, tileIndex(level, 5, 3), object)
+	}
+
+	sim, input := newTestSimulation(t, level)
+	g := sim.GetGame()
+	g.ResetSokoban()
+	sim.Spawn(g.char, 2, 3)
+
+	input.Hold(0, ticks, kbPlayerMoveRight)
+	sim.Step(ticks + 1)
+
+	return sim, level
+}
+
+func TestPushBoxAndUndo(t *testing.T) {
+	sim, level := newSokobanTest(t, tileIDEmpty, tileIDEmpty, 40)
+	g := sim.GetGame()
+
+	if level.GetTile(testObjectLayer, tileIndex(level, 5, 3)) != tileIDSokobanBox ||
+		level.GetTile(testObjectLayer, tileIndex(level, 4, 3)) != tileIDEmpty {
+		t.Fatal("box was not pushed one tile right")
+	}
+
+	if !g.UndoPush() || g.sokoban.GetUndoCount() != 0 {
+		t.Fatal("undo did not consume the push")
+	}
+
+	if level.GetTile(testObjectLayer, tileIndex(level, 4, 3)) != tileIDSokobanBox ||
+		level.GetTile(testObjectLayer, tileIndex(level, 5, 3)) != tileIDEmpty {
+		t.Fatal("undo did not put the box back")
+	}
+
+	if tilePos, _ := g.char.GetTilePos(); tilePos != tileIndex(level, 3, 3) {
+		t.Fatal("undo did not put the player back in front of the box")
+	}
+}
+
+func TestBoxBlockedBySolidTile(t *testing.T) {
+	_, level := newSokobanTest(t, tileIDEmpty, tileIDRock, 60)
+
+	if level.GetTile(testObjectLayer, tileIndex(level, 4, 3)) != tileIDSokobanBox {
+		t.Fatal("box moved into a rock")
+	}
+}
+
+func TestBoxOnTargetCompletesLevel(t *testing.T) {
+	sim, _ := newSokobanTest(t, tileIDTarget, tileIDEmpty, 40)
+
+	if !sim.GetGame().sokoban.IsComplete() {
+		t.Fatal("covering the only target did not complete the level")
+	}
+}
+
+func TestBoxFillsPit(t *testing.T) {
+	_, level := newSokobanTest(t, tileIDPit, tileIDEmpty, 40)
+
+	if level.GetTile(testFloorLayer, tileIndex(level, 5, 3)) != tileIDFilledPit ||
+		level.GetTile(testObjectLayer, tileIndex(level, 5, 3)) != tileIDEmpty {
+		t.Fatal("box did not fall into the pit")
+	}
+}
+
+func TestBoxOnWeightLowersThorns(t *testing.T) {
+	level := newTestLevel()
+	thornsPos := tileIndex(level, 10, 10)
+	level.SetTile(testObjectLayer, thornsPos, tileIDThornsActive)
+	level.SetTile(testObjectLayer, tileIndex(level, 4, 3), tileIDSokobanBox)
+	level.SetTile(testFloorLayer, tileIndex(level, 5, 3), tileIDWeigth)
+
+	sim, input := newTestSimulation(t, level)
+	g := sim.GetGame()
+	g.ResetSokoban()
+	sim.Spawn(g.char, 2, 3)
+
+	var events []SokobanEventKind
+	g.sokoban.AddListener(func(g *Game, event SokobanEvent) {
+		events = append(events, event.kind)
+	})
+
+	input.Hold(0, 40, kbPlayerMoveRight)
+	sim.Step(41)
+
+	if level.GetTile(testObjectLayer, thornsPos) != tileIDThorns || len(events) != 1 || events[0] != sokobanBoxOnWeight {
+		t.Fatalf("events %v, thorns %d", events, level.GetTile(testObjectLayer, thornsPos))
+	}
+
+	g.UndoPush()
+
+	if level.GetTile(testObjectLayer, thornsPos) != tileIDThornsActive || events[len(events)-1] != sokobanBoxOffWeight {
+		t.Fatal("lifting the box off the weight did not raise the thorns")
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"log"
+	"math"
+)
+
+// Level object keeping up to MaxAlive entities of a class alive
+//
+// A missing entity is replaced after Interval ticks, as long as the
+// condition holds. Radius is used by distance conditions, in tiles.
+type JSONSpawner struct {
+	JSONEntitySpawn
+	Interval  int     `json:"interval"`
+	MaxAlive  int     `json:"max_alive"`
+	Condition string  `json:"condition,omitempty"`
+	Radius    float64 `json:"radius,omitempty"`
+}
+
+type SpawnCondition func(g *Game, s *Spawner) bool
+
+var spawnConditions map[string]SpawnCondition
+
+func RegisterSpawnCondition(name string, condition SpawnCondition) {
+	if spawnConditions == nil {
+		spawnConditions = make(map[string]SpawnCondition)
+	}
+
+	spawnConditions[name] = condition
+}
+
+// Default distance for the player_near and player_away conditions, in tiles
+const defaultSpawnRadius = 8
+
+func init() {
+	RegisterSpawnCondition("always", func(g *Game, s *Spawner) bool {
+		return true
+	})
+
+	RegisterSpawnCondition("player_near", func(g *Game, s *Spawner) bool {
+		return s.GetPlayerDistance(g) <= s.GetRadius()
+	})
+
+	// Keeps entities from appearing in front of the player
+	RegisterSpawnCondition("player_away", func(g *Game, s *Spawner) bool {
+		return s.GetPlayerDistance(g) > s.GetRadius()
+	})
+}
+
+type Spawner struct {
+	def     JSONSpawner
+	timer   int
+	spawned []ILivingEntity
+}
+
+// Creates a spawner; the first entity comes on the first update
+func NewSpawner(def JSONSpawner) *Spawner {
+	s := new(Spawner)
+	s.def = def
+	s.timer = def.Interval
+
+	if s.def.MaxAlive <= 0 {
+		s.def.MaxAlive = 1
+	}
+
+	if _, has := spawnConditions[def.Condition]; def.Condition != "" && !has {
+		log.Println("[Spawner] Unknown condition " + def.Condition + ", the spawner stays idle")
+	}
+
+	return s
+}
+
+func (s *Spawner) GetRadius() float64 {
+	if s.def.Radius > 0 {
+		return s.def.Radius
+	}
+
+	return defaultSpawnRadius
+}
+
+// Returns the distance between the player and the spawn point in tiles
+func (s *Spawner) GetPlayerDistance(g *Game) float64 {
+	if g.char == nil {
+		return math.Inf(1)
+	}
+
+	pos := g.char.GetWorldPos()
+	dx := pos.X/tileSize - (float64(s.def.X) + 0.5)
+	dy := pos.Y/tileSize - (float64(s.def.Y) + 0.5)
+
+	return math.Sqrt(dx*dx + dy*dy)
+}
+
+func (s *Spawner) GetAliveCount() int {
+	return len(s.spawned)
+}
+
+// Forgets entities that died or left the world
+func (s *Spawner) prune(g *Game) {
+	alive := s.spawned[:0]
+
+	for _, spawned := range s.spawned {
+		if spawned.GetHealth() <= 0 {
+			continue
+		}
+
+		for _, entity := range g.entities {
+			if entity == spawned {
+				alive = append(alive, spawned)
+				break
+			}
+		}
+	}
+
+	s.spawned = alive
+}
+
+func (s *Spawner) Update(g *Game) {
+	s.prune(g)
+
+	if len(s.spawned) >= s.def.MaxAlive {
+		s.timer = 0
+		return
+	}
+
+	condition, has := spawnConditions[s.def.Condition]
+	if s.def.Condition != "" && (!has || !condition(g, s)) {
+		return
+	}
+
+	if s.timer < s.def.Interval {
+		s.timer++
+		return
+	}
+
+	s.timer = 0
+
+	entity, err := s.def.Create(g, g.level)
+	if err != nil {
+		log.Println("[Spawner] " + err.Error())
+		return
+	}
+	s.spawned = append(s.spawned, entity)
+
+	g.entityListMutex.Lock()
+	g.entities = append(g.entities, entity)
+	g.entityListMutex.Unlock()
+}
+
+func (g *Game) UpdateSpawners() {
+	for _, spawner := range g.level.spawners {
+		spawner.Update(g)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Directory of the sprite sheet descriptors, named after their sheets
+const spriteClipsDir = "assets/sprites"
+
+// Descriptor used by sheets without their own, laid out like char2.png
+const defaultSpriteClips = "character"
+
+// Idle, walk and attack clips are named with a look direction suffix,
+// e.g. "attack_left"
+const (
+	clipIdle   = "idle"
+	clipWalk   = "walk"
+	clipAttack = "attack"
+	clipDeath  = "death"
+)
+
+// Clip name suffixes by look direction
+var lookDirectionClipNames = [...]string{
+	LooksRight: "right",
+	LooksLeft:  "left",
+	LooksUp:    "up",
+	LooksDown:  "down",
+}
+
+// Rect of a sprite sheet shown for Duration ticks. Width, Height and
+// Duration fall back to the sheet frame size and the clip frame ticks.
+type JSONSpriteFrame struct {
+	X        int `json:"x"`
+	Y        int `json:"y"`
+	Width    int `json:"w,omitempty"`
+	Height   int `json:"h,omitempty"`
+	Duration int `json:"duration,omitempty"`
+}
+
+type JSONSpriteClip struct {
+	Frames     []JSONSpriteFrame `json:"frames"`
+	FrameTicks int               `json:"frame_ticks,omitempty"`
+	Loop       bool              `json:"loop,omitempty"`
+}
+
+// Named clips of a sprite sheet, e.g. "walk_left" or "explode"
+type JSONSpriteClipSet struct {
+	FrameWidth  int                        `json:"frame_width"`
+	FrameHeight int                        `json:"frame_height"`
+	Clips       map[string]*JSONSpriteClip `json:"clips"`
+}
+
+var spriteClipSets = make(map[string]*JSONSpriteClipSet)
+
+func SpriteClipsPath(sheet string) string {
+	return filepath.Join(spriteClipsDir, sheet+".json")
+}
+
+func LoadSpriteClips(path string) (*JSONSpriteClipSet, error) {
+	set := new(JSONSpriteClipSet)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return set, err
+	}
+
+	err = json.Unmarshal(data, set)
+	return set, err
+}
+
+// Returns the clips of a sprite sheet, or the default ones if it has no
+// descriptor
+func GetSpriteClips(sheet string) *JSONSpriteClipSet {
+	if set, has := spriteClipSets[sheet]; has {
+		return set
+	}
+
+	path := SpriteClipsPath(sheet)
+	if _, err := os.Stat(path); err != nil && sheet != defaultSpriteClips {
+		set := GetSpriteClips(defaultSpriteClips)
+		spriteClipSets[sheet] = set
+		return set
+	}
+
+	set, err := LoadSpriteClips(path)
+	if err != nil {
+		log.Println("[Sprites] Failed to load " + path + ": " + err.Error())
+	}
+
+	spriteClipSets[sheet] = set
+	return set
+}
+
+func (set *JSONSpriteClipSet) GetClip(name string) *JSONSpriteClip {
+	return set.Clips[name]
+}
+
+func (set *JSONSpriteClipSet) GetFrameRect(frame *JSONSpriteFrame) image.Rectangle {
+	width, height := frame.Width, frame.Height
+	if width <= 0 {
+		width = set.FrameWidth
+	}
+	if height <= 0 {
+		height = set.FrameHeight
+	}
+
+	return image.Rect(frame.X, frame.Y, frame.X+width, frame.Y+height)
+}
+
+func (clip *JSONSpriteClip) GetFrameDuration(i int) int {
+	if d := clip.Frames[i].Duration; d > 0 {
+		return d
+	}
+
+	if clip.FrameTicks > 0 {
+		return clip.FrameTicks
+	}
+
+	return 1
+}
+
+func (clip *JSONSpriteClip) GetDuration() int {
+	duration := 0
+	for i := range clip.Frames {
+		duration += clip.GetFrameDuration(i)
+	}
+
+	return duration
+}
+
+// Returns the frame shown at a time in ticks, holding the last one once a
+// clip that does not loop is over
+func (clip *JSONSpriteClip) FrameAt(time float64) *JSONSpriteFrame {
+	if len(clip.Frames) == 0 {
+		return nil
+	}
+
+	tick := int(time)
+	if duration := clip.GetDuration(); clip.Loop {
+		tick %= duration
+	}
+
+	for i := range clip.Frames {
+		tick -= clip.GetFrameDuration(i)
+		if tick < 0 {
+			return &clip.Frames[i]
+		}
+	}
+
+	return &clip.Frames[len(clip.Frames)-1]
+}
+
+// Plays clips of a sprite sheet one at a time
+type SpriteClipPlayer struct {
+	set  *JSONSpriteClipSet
+	name string
+	clip *JSONSpriteClip
+	time float64
+}
+
+func NewSpriteClipPlayer(set *JSONSpriteClipSet) *SpriteClipPlayer {
+	player := new(SpriteClipPlayer)
+	player.set = set
+	return player
+}
+
+func (player *SpriteClipPlayer) SetClips(set *JSONSpriteClipSet) {
+	player.set = set
+	player.name = ""
+	player.clip = nil
+}
+
+func (player *SpriteClipPlayer) GetClips() *JSONSpriteClipSet {
+	return player.set
+}
+
+func (player *SpriteClipPlayer) GetClipName() string {
+	return player.name
+}
+
+// Switches to a clip, starting it over unless it is already playing.
+// Returns false if the sheet has no such clip.
+func (player *SpriteClipPlayer) Play(name string) bool {
+	if name == player.name && player.clip != nil {
+		return true
+	}
+
+	clip := player.set.GetClip(name)
+	if clip == nil {
+		return false
+	}
+
+	player.name = name
+	player.clip = clip
+	player.time = 0
+	return true
+}
+
+func (player *SpriteClipPlayer) Restart() {
+	player.time = 0
+}
+
+// Advances the clip by a number of ticks, fractions for slowed down clips
+func (player *SpriteClipPlayer) Update(ticks float64) {
+	player.time += ticks
+}
+
+func (player *SpriteClipPlayer) IsFinished() bool {
+	return player.clip == nil || !player.clip.Loop && player.time >= float64(player.clip.GetDuration())
+}
+
+// Returns the part of the sheet to draw, false without a clip
+func (player *SpriteClipPlayer) GetFrameRect() (image.Rectangle, bool) {
+	if player.clip == nil {
+		return image.Rectangle{}, false
+	}
+
+	frame := player.clip.FrameAt(player.time)
+	if frame == nil {
+		return image.Rectangle{}, false
+	}
+
+	return player.set.GetFrameRect(frame), true
+}
+
+// Returns the current frame of a sheet image, nil without one
+func (player *SpriteClipPlayer) GetFrame(sheet *ebiten.Image) *ebiten.Image {
+	rect, ok := player.GetFrameRect()
+	if !ok || sheet == nil {
+		return nil
+	}
+
+	return sheet.SubImage(rect).(*ebiten.Image)
+}
+
+// One-shot clip drawn in the world, e.g. the death of an entity
+type SpriteEffect struct {
+	sheet  *ebiten.Image
+	player *SpriteClipPlayer
+	pos    Vec2f
+}
+
+// Plays a clip of a sprite sheet at a world position until it is over.
+// Looping clips can not be played as effects.
+func (g *Game) PlayEffect(sheet *ebiten.Image, set *JSONSpriteClipSet, clip string, pos Vec2f) bool {
+	player := NewSpriteClipPlayer(set)
+	if !player.Play(clip) || player.clip.Loop {
+		return false
+	}
+
+	g.effects = append(g.effects, &SpriteEffect{sheet, player, pos})
+	return true
+}
+
+func (g *Game) UpdateEffects() {
+	playing := g.effects[:0]
+	for _, effect := range g.effects {
+		effect.player.Update(1)
+		if !effect.player.IsFinished() {
+			playing = append(playing, effect)
+		}
+	}
+
+	for i := len(playing); i < len(g.effects); i++ {
+		g.effects[i] = nil
+	}
+	g.effects = playing
+}
+
+func (g *Game) DrawEffects(screen *ebiten.Image) {
+	cameraZoom := g.camera.GetZoom()
+
+	for _, effect := range g.effects {
+		frame := effect.player.GetFrame(effect.sheet)
+		if frame == nil {
+			continue
+		}
+
+		width, height := frame.Size()
+		pos := g.camera.WorldToScreen2(effect.pos)
+
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(-float64(width)/2, -float64(height)/2)
+		op.GeoM.Scale(cameraZoom, cameraZoom)
+		op.GeoM.Translate(pos.X, pos.Y)
+		DrawImage(screen, frame, op)
+	}
+}
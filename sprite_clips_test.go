@@ -0,0 +1,156 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+var testClipSet = &JSONSpriteClipSet{
+	FrameWidth:  16,
+	FrameHeight: 16,
+	Clips: map[string]*JSONSpriteClip{
+		"idle_right": {Frames: []JSONSpriteFrame{{X: 0, Y: 0}}},
+		"attack": {FrameTicks: 2, Frames: []JSONSpriteFrame{
+			{X: 0, Y: 64}, {X: 16, Y: 64, Duration: 4}, {X: 32, Y: 64, Width: 32},
+		}},
+		clipDeath: {FrameTicks: 3, Frames: []JSONSpriteFrame{{X: 0, Y: 80}, {X: 16, Y: 80}}},
+	},
+}
+
+func TestSpriteClipFrames(t *testing.T) {
+	clip := testClipSet.GetClip("attack")
+	if clip.GetDuration() != 8 {
+		t.Fatalf("attack lasts %d ticks, want 8", clip.GetDuration())
+	}
+
+	for time, wantX := range map[float64]int{0: 0, 1.5: 0, 2: 16, 5: 16, 6: 32, 100: 32} {
+		if frame := clip.FrameAt(time); frame.X != wantX {
+			t.Errorf("frame at %.1f starts at x %d, want %d", time, frame.X, wantX)
+		}
+	}
+
+	if rect := testClipSet.GetFrameRect(&clip.Frames[2]); rect != image.Rect(32, 64, 64, 80) {
+		t.Errorf("frame rect %v does not use its own width", rect)
+	}
+
+	walk := GetSpriteClips(defaultSpriteClips).GetClip("walk_down")
+	if walk == nil || !walk.Loop {
+		t.Fatal("character sheet has no looping walk_down clip")
+	}
+
+	if frame := walk.FrameAt(float64(walk.GetDuration() + 4)); frame.X != 16 || frame.Y != 48 {
+		t.Errorf("looped walk_down frame at %d,%d", frame.X, frame.Y)
+	}
+
+	character := GetSpriteClips(defaultSpriteClips)
+	for _, name := range []string{clipDeath, "attack_right", "attack_left", "attack_up", "attack_down"} {
+		if clip := character.GetClip(name); clip == nil || clip.Loop {
+			t.Errorf("character sheet has no one-shot %s clip", name)
+		}
+	}
+
+	if GetSpriteClips("no_such_sheet") != GetSpriteClips(defaultSpriteClips) {
+		t.Error("sheet without a descriptor does not use the default clips")
+	}
+}
+
+func TestLivingEntityClips(t *testing.T) {
+	sim, _ := newTestSimulation(t, newTestLevel())
+	g := sim.GetGame()
+	sim.Spawn(g.char, 3, 3)
+	e := g.char.GetLivingEntity()
+
+	e.look = LooksLeft
+	e.UpdateClip()
+	if e.clips.GetClipName() != "idle_left" {
+		t.Fatalf("standing entity plays %s", e.clips.GetClipName())
+	}
+
+	e.StartWalk(LooksUp)
+	e.baseSpeed = 0.5
+	e.UpdateClip()
+	tickCounter += 16
+	e.UpdateClip()
+
+	if rect, _ := e.clips.GetFrameRect(); e.clips.GetClipName() != "walk_up" || rect.Min != image.Pt(32, 32) {
+		t.Fatalf("walking at half speed shows %s at %v", e.clips.GetClipName(), rect.Min)
+	}
+	e.EndWalk()
+
+	e.clips.SetClips(testClipSet)
+	if e.PlayClip("dance") || !e.PlayClip("attack") {
+		t.Fatal("one-shot clips did not play by name")
+	}
+
+	e.look = LooksRight
+	tickCounter += 4
+	e.UpdateClip()
+	if e.clips.GetClipName() != "attack" {
+		t.Fatalf("attack was cut off by %s", e.clips.GetClipName())
+	}
+
+	tickCounter += 4
+	e.UpdateClip()
+	if e.clips.GetClipName() != "idle_right" {
+		t.Fatalf("entity plays %s after the attack", e.clips.GetClipName())
+	}
+}
+
+func TestClipsAdvanceWithoutDrawing(t *testing.T) {
+	sim, _ := newTestSimulation(t, newTestLevel())
+	g := sim.GetGame()
+	sim.Spawn(g.char, 3, 3)
+	e := g.char.GetLivingEntity()
+
+	sim.Step(10)
+	e.clips.SetClips(testClipSet)
+	if !e.PlayClip("attack") {
+		t.Fatal("attack clip did not play")
+	}
+
+	if rect, _ := e.clips.GetFrameRect(); rect.Min != image.Pt(0, 64) {
+		t.Fatalf("fresh attack starts at frame %v", rect.Min)
+	}
+
+	// The frames are shown after the tick the clip started in and the
+	// ones following it
+	sim.Step(testClipSet.GetClip("attack").GetDuration())
+	if rect, _ := e.clips.GetFrameRect(); e.action == "" || rect.Min != image.Pt(32, 64) {
+		t.Fatalf("attack ended early, showing %v", rect.Min)
+	}
+
+	sim.Step(1)
+	if e.action != "" {
+		t.Fatalf("attack action %s was not cleared by the simulation", e.action)
+	}
+}
+
+func TestDeathEffect(t *testing.T) {
+	sim, _ := newTestSimulation(t, newTestLevel())
+	g := sim.GetGame()
+
+	npc := CreateMichael(g)
+	sim.Spawn(npc, 4, 4)
+	npc.clips.SetClips(testClipSet)
+	npc.health = 0
+
+	sim.Step(1)
+	if len(g.effects) != 1 || g.effects[0].pos != npc.worldPos {
+		t.Fatalf("%d effects after the NPC died", len(g.effects))
+	}
+
+	sim.Step(testClipSet.GetClip(clipDeath).GetDuration())
+	if len(g.effects) != 0 {
+		t.Fatal("death effect did not end")
+	}
+
+	explosion := CreateMonobearExplosion(g.char)
+	first, _ := explosion.clips.GetFrameRect()
+	for i := 0; i < 4; i++ {
+		explosion.Update()
+	}
+
+	if next, _ := explosion.clips.GetFrameRect(); next == first {
+		t.Fatal("explosion did not move on to its next frame")
+	}
+}
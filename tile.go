@@ -88,4 +88,7 @@ const (
 	tileIDColorDigit2          Tile = 94
 	tileIDColorDigit3          Tile = 95
 	tileIDPoop                 Tile = 96
+	tileIDArrowUp              Tile = 97
+	tileIDArrowDown            Tile = 98
+	tileIDFlowerSprout         Tile = 99
 )
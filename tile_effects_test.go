@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestSwitchDisarmsThorns(t *testing.T) {
+	level := newTestLevel()
+	switchPos := tileIndex(level, 3, 3)
+	thornsPos := tileIndex(level, 7, 3)
+	level.SetTile(testObjectLayer, switchPos, tileIDSwitch)
+	level.SetTile(testObjectLayer, thornsPos, tileIDThornsActive)
+
+	sim, input := newTestSimulation(t, level)
+	player := sim.GetGame().char
+	sim.Spawn(player, 2, 3)
+
+	// Step onto the switch
+	input.Hold(0, tileSize, kbPlayerMoveRight)
+	sim.Step(tileSize + 1)
+
+	if got := level.GetTile(testObjectLayer, switchPos); got != tileIDSwitchActive {
+		t.Fatalf("switch tile = %d, want %d", got, tileIDSwitchActive)
+	}
+
+	if got := level.GetTile(testObjectLayer, thornsPos); got != tileIDThorns {
+		t.Fatalf("thorns tile = %d, want disarmed %d", got, tileIDThorns)
+	}
+
+	// Walk over the disarmed thorns
+	input.Hold(input.NextTick(), tileSize*4, kbPlayerMoveRight)
+	sim.Step(tileSize*4 + 1)
+
+	if tilePos, _ := player.GetTilePos(); tilePos != thornsPos {
+		t.Fatalf("player tile = %d, want %d", tilePos, thornsPos)
+	}
+
+	if player.GetHealth() != 100 {
+		t.Fatalf("health = %f after disarmed thorns, want 100", player.GetHealth())
+	}
+
+	// Leaving the thorns arms them again and releases the switch
+	input.Hold(input.NextTick(), tileSize, kbPlayerMoveRight)
+	sim.Step(tileSize + 1)
+
+	if got := level.GetTile(testObjectLayer, thornsPos); got != tileIDThornsActive {
+		t.Fatalf("thorns tile = %d, want armed %d", got, tileIDThornsActive)
+	}
+
+	if got := level.GetTile(testObjectLayer, switchPos); got != tileIDSwitch {
+		t.Fatalf("switch tile = %d, want released %d", got, tileIDSwitch)
+	}
+}
+
+func TestButtonGetsPushed(t *testing.T) {
+	level := newTestLevel()
+	buttonPos := tileIndex(level, 3, 3)
+	level.SetTile(testObjectLayer, buttonPos, tileIDButton)
+
+	sim, input := newTestSimulation(t, level)
+	sim.Spawn(sim.GetGame().char, 2, 3)
+
+	input.Hold(0, tileSize, kbPlayerMoveRight)
+	sim.Step(tileSize + 1)
+
+	if got := level.GetTile(testObjectLayer, buttonPos); got != tileIDButtonPushed {
+		t.Fatalf("button tile = %d, want %d", got, tileIDButtonPushed)
+	}
+}
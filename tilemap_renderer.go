@@ -0,0 +1,264 @@
+package main
+
+import (
+	"math"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Side of a cached render chunk in tiles
+const renderChunkSize = 16
+
+// Chunk images kept in memory; invisible chunks above this count are freed
+const maxCachedRenderChunks = 64
+
+func (lv *Level) renderChunkIndex(tilePos int) int {
+	chunksX := (lv.width + renderChunkSize - 1) / renderChunkSize
+	x := tilePos % lv.width / renderChunkSize
+	y := tilePos / lv.width / renderChunkSize
+
+	return y*chunksX + x
+}
+
+func (lv *Level) MarkTileDirty(tilePos int) {
+	if lv.chunkRevisions == nil {
+		lv.chunkRevisions = make(map[int]uint32)
+	}
+
+	lv.chunkRevisions[lv.renderChunkIndex(tilePos)]++
+
+	if chunk, _ := lv.chunkOf(tilePos); chunk != nil {
+		chunk.modified = true
+	}
+}
+
+// Invalidates the render chunks covering a storage chunk after it was
+// loaded, unloaded or changed in bulk
+func (lv *Level) markChunkDirty(chunk *LevelChunk) {
+	if lv.chunkRevisions == nil {
+		lv.chunkRevisions = make(map[int]uint32)
+	}
+
+	for y := chunk.y * levelChunkSize; y < (chunk.y+1)*levelChunkSize && y < lv.height; y += renderChunkSize {
+		for x := chunk.x * levelChunkSize; x < (chunk.x+1)*levelChunkSize && x < lv.width; x += renderChunkSize {
+			lv.chunkRevisions[lv.renderChunkIndex(y*lv.width+x)]++
+		}
+	}
+}
+
+// Invalidates every render chunk, e.g. after bulk changes
+func (lv *Level) Invalidate() {
+	lv.revision++
+}
+
+func (lv *Level) getChunkRevision(chunkIndex int) (uint32, uint32) {
+	return lv.revision, lv.chunkRevisions[chunkIndex]
+}
+
+type TilemapChunk struct {
+	image         *ebiten.Image
+	x, y          int
+	levelRevision uint32
+	chunkRevision uint32
+	lastDrawn     int
+}
+
+// Draws level tiles through cached chunk images
+//
+// Only chunks intersecting the camera view are drawn, each with a single
+// DrawImage call. A chunk image is redrawn when its tiles were changed.
+type TilemapRenderer struct {
+	level        *Level
+	chunks       map[int]*TilemapChunk
+	chunksX      int
+	chunksY      int
+	frame        int
+	redrawnTotal int
+}
+
+func NewTilemapRenderer(level *Level) *TilemapRenderer {
+	r := new(TilemapRenderer)
+	r.level = level
+	r.chunks = make(map[int]*TilemapChunk)
+	r.chunksX = (level.width + renderChunkSize - 1) / renderChunkSize
+	r.chunksY = (level.height + renderChunkSize - 1) / renderChunkSize
+	return r
+}
+
+func (r *TilemapRenderer) GetLevel() *Level {
+	return r.level
+}
+
+func (r *TilemapRenderer) GetCachedChunkCount() int {
+	return len(r.chunks)
+}
+
+// Returns how many times chunk images were (re)rendered since creation
+func (r *TilemapRenderer) GetRedrawCount() int {
+	return r.redrawnTotal
+}
+
+// Returns the range of chunks intersecting the screen, inclusive
+func (r *TilemapRenderer) VisibleChunks(camera *Camera, screenW, screenH int) (int, int, int, int) {
+	topLeft := camera.ScreenToWorld(Vec2f{0, 0})
+	bottomRight := camera.ScreenToWorld(Vec2f{float64(screenW), float64(screenH)})
+
+	chunkWorldSize := float64(renderChunkSize * tileSize)
+
+	x0 := int(math.Max(0, math.Floor(topLeft.X/chunkWorldSize)))
+	y0 := int(math.Max(0, math.Floor(topLeft.Y/chunkWorldSize)))
+	x1 := int(math.Min(float64(r.chunksX-1), math.Floor(bottomRight.X/chunkWorldSize)))
+	y1 := int(math.Min(float64(r.chunksY-1), math.Floor(bottomRight.Y/chunkWorldSize)))
+
+	return x0, y0, x1, y1
+}
+
+func (r *TilemapRenderer) renderChunk(chunk *TilemapChunk) {
+	level := r.level
+
+	if chunk.image == nil {
+		w := int(math.Min(renderChunkSize, float64(level.width-chunk.x*renderChunkSize)))
+		h := int(math.Min(renderChunkSize, float64(level.height-chunk.y*renderChunkSize)))
+		chunk.image = ebiten.NewImage(w*tileSize, h*tileSize)
+	} else {
+		chunk.image.Clear()
+	}
+
+	w, h := chunk.image.Size()
+	w /= tileSize
+	h /= tileSize
+
+	op := &ebiten.DrawImageOptions{}
+
+	for layer := 0; layer < level.GetLayerCount(); layer++ {
+		for ty := 0; ty < h; ty++ {
+			for tx := 0; tx < w; tx++ {
+				tilePos := (chunk.y*renderChunkSize+ty)*level.width + chunk.x*renderChunkSize + tx
+				t := level.GetTile(layer, tilePos)
+
+				if t == tileIDEmpty {
+					continue
+				}
+
+				op.GeoM.Reset()
+				op.GeoM.Translate(float64(tx*tileSize), float64(ty*tileSize))
+				DrawImage(chunk.image, GetTileSprite(tilesImage, tileXNum, tileSize, t), op)
+			}
+		}
+	}
+
+	chunk.levelRevision, chunk.chunkRevision = level.getChunkRevision(chunk.y*r.chunksX + chunk.x)
+	r.redrawnTotal++
+}
+
+func (r *TilemapRenderer) Draw(screen *ebiten.Image, camera *Camera) {
+	r.frame++
+
+	screenW, screenH := screen.Size()
+	x0, y0, x1, y1 := r.VisibleChunks(camera, screenW, screenH)
+
+	zoom := camera.GetZoom()
+	op := &ebiten.DrawImageOptions{}
+
+	for cy := y0; cy <= y1; cy++ {
+		for cx := x0; cx <= x1; cx++ {
+			index := cy*r.chunksX + cx
+
+			chunk, has := r.chunks[index]
+			if !has {
+				chunk = &TilemapChunk{x: cx, y: cy}
+				r.chunks[index] = chunk
+				r.renderChunk(chunk)
+			} else if levelRev, chunkRev := r.level.getChunkRevision(index); levelRev != chunk.levelRevision || chunkRev != chunk.chunkRevision {
+				r.renderChunk(chunk)
+			}
+
+			chunk.lastDrawn = r.frame
+
+			pos := camera.WorldToScreen2(Vec2f{
+				float64(cx * renderChunkSize * tileSize),
+				float64(cy * renderChunkSize * tileSize),
+			})
+
+			op.GeoM.Reset()
+			op.GeoM.Scale(zoom, zoom)
+			op.GeoM.Translate(pos.X, pos.Y)
+			DrawImage(screen, chunk.image, op)
+		}
+	}
+
+	r.evict()
+}
+
+// Frees least recently drawn chunk images above the cache limit
+func (r *TilemapRenderer) evict() {
+	if len(r.chunks) <= maxCachedRenderChunks {
+		return
+	}
+
+	var stale []int
+	for index, chunk := range r.chunks {
+		if chunk.lastDrawn != r.frame {
+			stale = append(stale, index)
+		}
+	}
+
+	sort.Slice(stale, func(i, j int) bool {
+		return r.chunks[stale[i]].lastDrawn < r.chunks[stale[j]].lastDrawn
+	})
+
+	for _, index := range stale {
+		if len(r.chunks) <= maxCachedRenderChunks {
+			break
+		}
+
+		r.chunks[index].image.Dispose()
+		delete(r.chunks, index)
+	}
+}
+
+// Tiles without a sprite of their own, drawn as another tile turned
+// clockwise by quarter turns and shrunk towards the bottom edge
+var derivedTileSprites = map[Tile]struct {
+	source Tile
+	turns  int
+	scale  float64
+}{
+	tileIDArrowUp:      {tileIDArrowRight, 3, 1},
+	tileIDArrowDown:    {tileIDArrowRight, 1, 1},
+	tileIDFlowerSprout: {tileIDFlower1, 0, 0.5},
+}
+
+var derivedTileCache map[Tile]*ebiten.Image
+var derivedTileCacheSet *ebiten.Image
+
+// Returns the derived sprite of the tile, or nil if it has its own
+func getDerivedTileSprite(tileSet *ebiten.Image, tileSetWidth int, size int, tile Tile) *ebiten.Image {
+	derived, has := derivedTileSprites[tile]
+	if !has {
+		return nil
+	}
+
+	if derivedTileCacheSet != tileSet {
+		derivedTileCache = make(map[Tile]*ebiten.Image)
+		derivedTileCacheSet = tileSet
+	}
+
+	if sprite, has := derivedTileCache[tile]; has {
+		return sprite
+	}
+
+	sprite := ebiten.NewImage(size, size)
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(-float64(size)/2, -float64(size)/2)
+	op.GeoM.Rotate(float64(derived.turns) * math.Pi / 2)
+	op.GeoM.Translate(0, -float64(size)/2)
+	op.GeoM.Scale(derived.scale, derived.scale)
+	op.GeoM.Translate(float64(size)/2, float64(size))
+	sprite.DrawImage(GetTileSprite(tileSet, tileSetWidth, size, derived.source), op)
+
+	derivedTileCache[tile] = sprite
+	return sprite
+}
@@ -0,0 +1,135 @@
+//go:build gpu
+// +build gpu
+
+package main
+
+import (
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Reference renderer drawing every tile of every layer on its own
+func drawTilesDirect(screen *ebiten.Image, level *Level, camera *Camera) {
+	op := &ebiten.DrawImageOptions{}
+	zoom := camera.GetZoom()
+
+	for layer := 0; layer < level.GetLayerCount(); layer++ {
+		for i := 0; i < level.width*level.height; i++ {
+			t := level.GetTile(layer, i)
+			if t == tileIDEmpty {
+				continue
+			}
+
+			pos := camera.WorldToScreen2(Vec2f{
+				float64(i % level.width * tileSize),
+				float64(i / level.width * tileSize),
+			})
+
+			op.GeoM.Reset()
+			op.GeoM.Scale(zoom, zoom)
+			op.GeoM.Translate(pos.X, pos.Y)
+			DrawImage(screen, GetTileSprite(tilesImage, tileXNum, tileSize, t), op)
+		}
+	}
+}
+
+func assertSameImage(t *testing.T, got, want *ebiten.Image) {
+	t.Helper()
+
+	if diff, _ := diffImages(imageToRGBA(got), imageToRGBA(want), goldenTolerance{}); diff > 0 {
+		t.Fatalf("%d pixels differ from the direct render", diff)
+	}
+}
+
+func TestTilemapRendererMatchesDirectDraw(t *testing.T) {
+	useTestTileAtlas(t)
+
+	level := newPatternLevel(40, 40)
+	camera := newTestCamera(300, 260, 2)
+	renderer := NewTilemapRenderer(level)
+
+	got := ebiten.NewImage(screenWidth, screenHeight)
+	renderer.Draw(got, camera)
+
+	want := ebiten.NewImage(screenWidth, screenHeight)
+	drawTilesDirect(want, level, camera)
+
+	assertSameImage(t, got, want)
+}
+
+func TestTilemapRendererInvalidatesChangedChunk(t *testing.T) {
+	useTestTileAtlas(t)
+
+	level := newPatternLevel(40, 40)
+	camera := newTestCamera(320, 320, 1)
+	renderer := NewTilemapRenderer(level)
+
+	screen := ebiten.NewImage(screenWidth, screenHeight)
+	renderer.Draw(screen, camera)
+	redraws := renderer.GetRedrawCount()
+
+	level.SetTile(1, 20*level.width+20, 42)
+	screen.Clear()
+	renderer.Draw(screen, camera)
+
+	if renderer.GetRedrawCount() != redraws+1 {
+		t.Fatalf("redraw count = %d, want exactly one chunk redrawn", renderer.GetRedrawCount()-redraws)
+	}
+
+	want := ebiten.NewImage(screenWidth, screenHeight)
+	drawTilesDirect(want, level, camera)
+
+	assertSameImage(t, screen, want)
+}
+
+func benchmarkLevel(b *testing.B, size int) (*Level, *Camera, *ebiten.Image) {
+	useTestTileAtlas(b)
+
+	level := newPatternLevel(size, size)
+	camera := newTestCamera(float64(size*tileSize/2), float64(size*tileSize/2), 2)
+
+	return level, camera, ebiten.NewImage(screenWidth, screenHeight)
+}
+
+func BenchmarkDrawTilesDirect64(b *testing.B) {
+	level, camera, screen := benchmarkLevel(b, 64)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		drawTilesDirect(screen, level, camera)
+	}
+}
+
+func BenchmarkTilemapRenderer64(b *testing.B) {
+	level, camera, screen := benchmarkLevel(b, 64)
+	renderer := NewTilemapRenderer(level)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		renderer.Draw(screen, camera)
+	}
+}
+
+func BenchmarkTilemapRenderer1024(b *testing.B) {
+	level, camera, screen := benchmarkLevel(b, 1024)
+	renderer := NewTilemapRenderer(level)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		renderer.Draw(screen, camera)
+	}
+}
+
+// Every frame one tile changes, as when switches toggle thorns
+func BenchmarkTilemapRendererWithEdits1024(b *testing.B) {
+	level, camera, screen := benchmarkLevel(b, 1024)
+	renderer := NewTilemapRenderer(level)
+	center := 512*level.width + 512
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		level.SetTile(1, center, Tile(1+i%2))
+		renderer.Draw(screen, camera)
+	}
+}
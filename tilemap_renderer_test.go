@@ -0,0 +1,93 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Replaces the tile atlas with one where every tile is a distinct flat color
+func useTestTileAtlas(t testing.TB) {
+	t.Helper()
+
+	saved := tilesImage
+	t.Cleanup(func() {
+		tilesImage = saved
+	})
+
+	const rows = 32
+	tilesImage = ebiten.NewImage(tileXNum*tileSize, rows*tileSize)
+
+	for i := 1; i < tileXNum*rows; i++ {
+		sprite := GetTileSprite(tilesImage, tileXNum, tileSize, Tile(i))
+		sprite.Fill(color.RGBA{uint8(i * 37), uint8(i * 91), uint8(i), 255})
+	}
+}
+
+func newPatternLevel(width, height int) *Level {
+	level := NewLevel(width, height, 2)
+
+	for i := 0; i < width*height; i++ {
+		level.SetTile(0, i, Tile(1+i%7))
+		if i%5 == 0 {
+			level.SetTile(1, i, Tile(10+i%3))
+		}
+	}
+
+	return level
+}
+
+func newTestCamera(x, y, zoom float64) *Camera {
+	camera := new(Camera)
+	camera.SetZoom(zoom)
+	camera.TargetPosition(Vec2f{x, y})
+	camera.Update()
+	return camera
+}
+
+func TestTilemapRendererCullsInvisibleChunks(t *testing.T) {
+	useTestTileAtlas(t)
+
+	level := newPatternLevel(256, 256)
+	camera := newTestCamera(2000, 2000, 4)
+	renderer := NewTilemapRenderer(level)
+
+	x0, y0, x1, y1 := renderer.VisibleChunks(camera, screenWidth, screenHeight)
+	visible := (x1 - x0 + 1) * (y1 - y0 + 1)
+
+	prof := Profiler_GetInstance()
+	prof.current.DrawCalls = 0
+
+	screen := ebiten.NewImage(screenWidth, screenHeight)
+	renderer.Draw(screen, camera)
+	firstFrameCalls := prof.current.DrawCalls
+
+	prof.current.DrawCalls = 0
+	renderer.Draw(screen, camera)
+
+	if prof.current.DrawCalls != visible {
+		t.Fatalf("cached frame issued %d draw calls, want one per visible chunk (%d)", prof.current.DrawCalls, visible)
+	}
+
+	if renderer.GetCachedChunkCount() != visible || firstFrameCalls >= 256*256 {
+		t.Fatalf("rendered %d chunks with %d draw calls, want only the %d visible ones",
+			renderer.GetCachedChunkCount(), firstFrameCalls, visible)
+	}
+}
+
+func TestTilemapRendererEvictsChunks(t *testing.T) {
+	useTestTileAtlas(t)
+
+	level := newPatternLevel(512, 64)
+	renderer := NewTilemapRenderer(level)
+	screen := ebiten.NewImage(screenWidth, screenHeight)
+
+	for x := 0.0; x < float64(level.width*tileSize); x += screenWidth {
+		renderer.Draw(screen, newTestCamera(x, 400, 1))
+	}
+
+	if renderer.GetCachedChunkCount() > maxCachedRenderChunks {
+		t.Fatalf("%d chunks cached, limit is %d", renderer.GetCachedChunkCount(), maxCachedRenderChunks)
+	}
+}
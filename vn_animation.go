@@ -0,0 +1,402 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Named tweens VN characters can play
+const vnTweensPath = "assets/vn/tweens.json"
+
+const (
+	vnTweenX        = "x"
+	vnTweenY        = "y"
+	vnTweenScale    = "scale"
+	vnTweenRotation = "rotation"
+)
+
+// Ticks between blinks when a character does not set them
+const (
+	vnBlinkMinInterval = 120
+	vnBlinkMaxInterval = 360
+)
+
+// Value of a tween track at a tick
+type JSONTweenKeyframe struct {
+	Time  int     `json:"time"`
+	Value float64 `json:"value"`
+}
+
+// Property of a VN character changing over time: "x" and "y" offsets in
+// pixels, "scale" around the center or "rotation" in radians. Ease "sine"
+// smooths the moves between keyframes, which are linear otherwise.
+type JSONTweenTrack struct {
+	Property  string              `json:"property"`
+	Ease      string              `json:"ease,omitempty"`
+	Keyframes []JSONTweenKeyframe `json:"keyframes"`
+}
+
+// Animation moving a whole VN character, e.g. "bob" or "jump". Looping
+// tweens play until stopped, others once.
+type JSONTween struct {
+	Loop   bool             `json:"loop,omitempty"`
+	Tracks []JSONTweenTrack `json:"tracks"`
+}
+
+// Layers of a group shown one after another, each for FrameTicks. Blinks
+// come every MinInterval to MaxInterval ticks.
+type JSONVNLayerCycle struct {
+	Group       string   `json:"group"`
+	Layers      []string `json:"layers"`
+	FrameTicks  int      `json:"frame_ticks"`
+	MinInterval int      `json:"min_interval,omitempty"`
+	MaxInterval int      `json:"max_interval,omitempty"`
+}
+
+// Animation section of a layered image JSON. Idle tweens play all the time,
+// Blink cycles the eye layers now and then, LipSync the mouth layers while
+// the character talks.
+type JSONVNAnimation struct {
+	Idle    []string          `json:"idle,omitempty"`
+	Blink   *JSONVNLayerCycle `json:"blink,omitempty"`
+	LipSync *JSONVNLayerCycle `json:"lip_sync,omitempty"`
+}
+
+var vnTweens map[string]*JSONTween
+
+func LoadVNTweens(path string) (map[string]*JSONTween, error) {
+	tweens := make(map[string]*JSONTween)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return tweens, err
+	}
+
+	err = json.Unmarshal(data, &tweens)
+	return tweens, err
+}
+
+// Returns a named tween, loading the tweens on first use
+func GetVNTween(name string) *JSONTween {
+	if vnTweens == nil {
+		tweens, err := LoadVNTweens(vnTweensPath)
+		if err != nil {
+			log.Println("[VN] Failed to load tweens: " + err.Error())
+		}
+		vnTweens = tweens
+	}
+
+	return vnTweens[name]
+}
+
+func (track *JSONTweenTrack) GetDuration() int {
+	if len(track.Keyframes) == 0 {
+		return 0
+	}
+
+	return track.Keyframes[len(track.Keyframes)-1].Time
+}
+
+// Returns the value at a tick, holding the last keyframe after the end
+func (track *JSONTweenTrack) ValueAt(tick int) float64 {
+	frames := track.Keyframes
+	if len(frames) == 0 {
+		return 0
+	}
+
+	for i := 1; i < len(frames); i++ {
+		from, to := frames[i-1], frames[i]
+		if tick >= to.Time {
+			continue
+		}
+
+		if tick <= from.Time || to.Time == from.Time {
+			return from.Value
+		}
+
+		t := float64(tick-from.Time) / float64(to.Time-from.Time)
+		if track.Ease == "sine" {
+			t = (1 - math.Cos(t*math.Pi)) / 2
+		}
+
+		return from.Value + (to.Value-from.Value)*t
+	}
+
+	return frames[len(frames)-1].Value
+}
+
+func (tween *JSONTween) GetDuration() int {
+	duration := 0
+	for i := range tween.Tracks {
+		if d := tween.Tracks[i].GetDuration(); d > duration {
+			duration = d
+		}
+	}
+
+	return duration
+}
+
+type vnTweenPlayback struct {
+	name  string
+	tween *JSONTween
+	tick  int
+}
+
+// Layers of a group cycled by the animator, putting back the layer that
+// was shown before once they stop
+type vnLayerCycle struct {
+	config  *JSONVNLayerCycle
+	list    *VNSpriteListCategory
+	frames  []*VNCharacterSprite
+	frame   int
+	ticks   int
+	restore *VNCharacterSprite
+}
+
+func newVNLayerCycle(char *VNCharacter, config *JSONVNLayerCycle) *vnLayerCycle {
+	if config == nil {
+		return nil
+	}
+
+	cycle := &vnLayerCycle{config: config, frame: -1}
+	for _, name := range config.Layers {
+		list, sprite := char.findGroupSprite(config.Group, name)
+		if sprite == nil {
+			log.Printf("[VN] %s: no layer %s in group %s to animate", char.name, name, config.Group)
+			continue
+		}
+
+		cycle.list = list
+		cycle.frames = append(cycle.frames, sprite)
+	}
+
+	if len(cycle.frames) == 0 {
+		return nil
+	}
+
+	return cycle
+}
+
+func (cycle *vnLayerCycle) IsRunning() bool {
+	return cycle.frame >= 0
+}
+
+func (cycle *vnLayerCycle) Start() {
+	if cycle.IsRunning() {
+		return
+	}
+
+	cycle.restore = cycle.list.current
+	cycle.frame = 0
+	cycle.ticks = 0
+	cycle.list.current = cycle.frames[0]
+}
+
+func (cycle *vnLayerCycle) Stop() {
+	if !cycle.IsRunning() {
+		return
+	}
+
+	if cycle.list.current == cycle.frames[cycle.frame] {
+		cycle.list.current = cycle.restore
+	}
+	cycle.frame = -1
+}
+
+// Shows the next layer when it is time, starting over if looping
+func (cycle *vnLayerCycle) Step(loop bool) {
+	if !cycle.IsRunning() {
+		return
+	}
+
+	// Someone else picked a layer, e.g. an expression preset
+	if cycle.list.current != cycle.frames[cycle.frame] {
+		cycle.frame = -1
+		return
+	}
+
+	cycle.ticks++
+	if cycle.ticks < cycle.config.FrameTicks {
+		return
+	}
+
+	cycle.ticks = 0
+	cycle.frame++
+	if cycle.frame >= len(cycle.frames) {
+		if !loop {
+			cycle.frame--
+			cycle.Stop()
+			return
+		}
+		cycle.frame = 0
+	}
+
+	cycle.list.current = cycle.frames[cycle.frame]
+}
+
+// Plays the tweens, blinks and lip-sync of a VN character
+type VNAnimator struct {
+	char       *VNCharacter
+	tweens     []*vnTweenPlayback
+	blink      *vnLayerCycle
+	blinkTimer int
+	lipSync    *vnLayerCycle
+	talking    bool
+	rng        *rand.Rand
+}
+
+func NewVNAnimator(char *VNCharacter, config JSONVNAnimation) *VNAnimator {
+	a := new(VNAnimator)
+	a.char = char
+	a.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	a.blink = newVNLayerCycle(char, config.Blink)
+	a.lipSync = newVNLayerCycle(char, config.LipSync)
+	a.resetBlinkTimer()
+
+	for _, name := range config.Idle {
+		a.Play(name)
+	}
+
+	return a
+}
+
+func (a *VNAnimator) resetBlinkTimer() {
+	if a.blink == nil {
+		return
+	}
+
+	min, max := a.blink.config.MinInterval, a.blink.config.MaxInterval
+	if min <= 0 {
+		min = vnBlinkMinInterval
+	}
+	if max <= min {
+		max = min + vnBlinkMaxInterval - vnBlinkMinInterval
+	}
+
+	a.blinkTimer = min + a.rng.Intn(max-min+1)
+}
+
+// Starts a named tween over, returns false if there is no such tween
+func (a *VNAnimator) Play(name string) bool {
+	tween := GetVNTween(name)
+	if tween == nil {
+		log.Println("[VN] Unknown tween " + name)
+		return false
+	}
+
+	a.Stop(name)
+	a.tweens = append(a.tweens, &vnTweenPlayback{name, tween, 0})
+	return true
+}
+
+func (a *VNAnimator) Stop(name string) {
+	for i, playback := range a.tweens {
+		if playback.name == name {
+			a.tweens = append(a.tweens[:i], a.tweens[i+1:]...)
+			return
+		}
+	}
+}
+
+func (a *VNAnimator) IsPlaying(name string) bool {
+	for _, playback := range a.tweens {
+		if playback.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Flaps the mouth while talking
+func (a *VNAnimator) SetTalking(talking bool) {
+	a.talking = talking
+}
+
+func (a *VNAnimator) IsBlinking() bool {
+	return a.blink != nil && a.blink.IsRunning()
+}
+
+// Returns the offset, scale and rotation of the playing tweens
+func (a *VNAnimator) GetPose() (offset Vec2f, scale float64, rotation float64) {
+	scale = 1.0
+
+	for _, playback := range a.tweens {
+		for i := range playback.tween.Tracks {
+			track := &playback.tween.Tracks[i]
+			value := track.ValueAt(playback.tick)
+
+			switch track.Property {
+			case vnTweenX:
+				offset.X += value
+			case vnTweenY:
+				offset.Y += value
+			case vnTweenScale:
+				scale *= value
+			case vnTweenRotation:
+				rotation += value
+			}
+		}
+	}
+
+	return offset, scale, rotation
+}
+
+// Scales and rotates the character around its center, then moves it
+func (a *VNAnimator) GetTransform() ebiten.GeoM {
+	offset, scale, rotation := a.GetPose()
+	width, height := float64(a.char.width), float64(a.char.height)
+
+	var m ebiten.GeoM
+	m.Translate(-width/2, -height/2)
+	m.Scale(scale, scale)
+	m.Rotate(rotation)
+	m.Translate(width/2+offset.X, height/2+offset.Y)
+	return m
+}
+
+// Advances the animation by a tick
+func (a *VNAnimator) Update() {
+	playing := a.tweens[:0]
+	for _, playback := range a.tweens {
+		playback.tick++
+
+		duration := playback.tween.GetDuration()
+		if playback.tween.Loop && duration > 0 {
+			playback.tick %= duration
+		} else if playback.tick > duration {
+			continue
+		}
+
+		playing = append(playing, playback)
+	}
+	a.tweens = playing
+
+	a.char.matTransform = a.GetTransform()
+
+	if a.blink != nil {
+		if a.blink.IsRunning() {
+			a.blink.Step(false)
+			if !a.blink.IsRunning() {
+				a.resetBlinkTimer()
+			}
+		} else if a.blinkTimer--; a.blinkTimer <= 0 {
+			a.blink.Start()
+		}
+	}
+
+	if a.lipSync != nil {
+		if a.talking {
+			a.lipSync.Start()
+			a.lipSync.Step(true)
+		} else {
+			a.lipSync.Stop()
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func newTestAnimatedCharacter(t *testing.T, animation JSONVNAnimation) *VNCharacter {
+	path := writeTestLayeredImage(t, JSONLayeredImage{
+		Width:  4,
+		Height: 4,
+		Layers: []JSONLayeredImageEntry{
+			{Name: "eyes_open.png"}, {Name: "eyes_shut.png"}, {Name: "mouth_shut.png"}, {Name: "mouth_open.png"},
+		},
+		Groups: []JSONLayerGroup{
+			{Name: "eyes", Layers: []string{"eyes_open.png", "eyes_shut.png"}},
+			{Name: "mouth", Layers: []string{"mouth_shut.png", "mouth_open.png"}},
+		},
+		Animation: animation,
+	}, "eyes_open.png", "eyes_shut.png", "mouth_shut.png", "mouth_open.png")
+
+	char := NewVNCharacterFromJSON(path, "Test")
+	if char == nil {
+		t.Fatal("character did not load")
+	}
+
+	return char
+}
+
+func TestTweenTrackValues(t *testing.T) {
+	track := JSONTweenTrack{Property: vnTweenY, Keyframes: []JSONTweenKeyframe{{0, 0}, {10, 10}, {20, 0}}}
+
+	for tick, want := range map[int]float64{0: 0, 5: 5, 10: 10, 15: 5, 20: 0, 30: 0} {
+		if got := track.ValueAt(tick); got != want {
+			t.Errorf("linear value at %d = %.2f, want %.2f", tick, got, want)
+		}
+	}
+
+	track.Ease = "sine"
+	if got := track.ValueAt(2); got >= 2 || math.Abs(track.ValueAt(5)-5) > 1e-9 {
+		t.Errorf("sine ease does not start slow: %.2f at tick 2", got)
+	}
+}
+
+func TestVNAnimatorTweens(t *testing.T) {
+	char := newTestAnimatedCharacter(t, JSONVNAnimation{Idle: []string{"bob"}})
+	animator := char.GetAnimator()
+
+	if !animator.IsPlaying("bob") || !animator.Play("jump") || animator.Play("moonwalk") {
+		t.Fatal("tweens from the library did not play")
+	}
+
+	duration := GetVNTween("jump").GetDuration()
+	moved := false
+	for i := 0; i <= duration; i++ {
+		char.Update()
+		if offset, _, _ := animator.GetPose(); offset.Y < -10 {
+			moved = true
+		}
+	}
+
+	if !moved || animator.IsPlaying("jump") {
+		t.Fatalf("jump moved %t, still playing %t", moved, animator.IsPlaying("jump"))
+	}
+
+	for i := 0; i < GetVNTween("bob").GetDuration()*2; i++ {
+		char.Update()
+	}
+
+	if !animator.IsPlaying("bob") {
+		t.Fatal("looping tween stopped")
+	}
+}
+
+func TestVNAnimatorBlinkAndLipSync(t *testing.T) {
+	char := newTestAnimatedCharacter(t, JSONVNAnimation{
+		Blink:   &JSONVNLayerCycle{Group: "eyes", Layers: []string{"eyes_shut.png"}, FrameTicks: 3, MinInterval: 10, MaxInterval: 20},
+		LipSync: &JSONVNLayerCycle{Group: "mouth", Layers: []string{"mouth_open.png", "mouth_shut.png"}, FrameTicks: 2},
+	})
+	animator := char.GetAnimator()
+	animator.rng = rand.New(rand.NewSource(1))
+	animator.resetBlinkTimer()
+
+	eyes, mouth := char.sprites[0], char.sprites[1]
+
+	blinked := false
+	for i := 0; i < 30; i++ {
+		char.Update()
+		blinked = blinked || eyes.current.name == "eyes_shut.png"
+	}
+
+	if !blinked {
+		t.Fatal("did not blink within the longest interval")
+	}
+
+	for animator.IsBlinking() {
+		char.Update()
+	}
+
+	if eyes.current.name != "eyes_open.png" {
+		t.Fatalf("eyes left at %s after blinking", eyes.current.name)
+	}
+
+	animator.SetTalking(true)
+	flaps := 0
+	for i := 0; i < 8; i++ {
+		char.Update()
+		if mouth.current.name == "mouth_open.png" {
+			flaps++
+		}
+	}
+
+	if flaps == 0 || flaps == 8 {
+		t.Fatalf("mouth was open %d of 8 ticks while talking", flaps)
+	}
+
+	animator.SetTalking(false)
+	char.Update()
+
+	if mouth.current.name != "mouth_shut.png" {
+		t.Fatalf("mouth left at %s after talking", mouth.current.name)
+	}
+}
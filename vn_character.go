@@ -0,0 +1,416 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Preset applied when a VN character is loaded, if it has one
+const vnDefaultPreset = "default"
+
+// Layered image of a VN character, as exported next to its layer images
+//
+// Groups are drawn in the order they are declared, showing one of their
+// layers each, e.g. "body" below "facial". A preset names the layer to show
+// for some of the groups, e.g. {"facial": "morshu_002.png"}.
+type JSONLayeredImage struct {
+	Width     uint                            `json:"width"`
+	Height    uint                            `json:"height"`
+	Layers    []JSONLayeredImageEntry         `json:"layers"`
+	Groups    []JSONLayerGroup                `json:"groups"`
+	Presets   map[string]JSONExpressionPreset `json:"presets,omitempty"`
+	Animation JSONVNAnimation                 `json:"animation"`
+}
+
+type JSONLayeredImageEntry struct {
+	Name    string `json:"name"`
+	Index   uint   `json:"index"`
+	OffsetX uint   `json:"offset_x"`
+	OffsetY uint   `json:"offset_y"`
+	Width   uint   `json:"width"`
+	Height  uint   `json:"height"`
+	image   *ebiten.Image
+}
+
+// Named set of layers of which one is shown at a time, the first one
+// unless a preset says otherwise
+type JSONLayerGroup struct {
+	Name   string   `json:"name"`
+	Layers []string `json:"layers"`
+}
+
+// Layer names by group name
+type JSONExpressionPreset map[string]string
+
+type IVNCharacter interface {
+	Draw(*ebiten.Image)
+	SetExpression(string)
+	SetBody(string)
+}
+
+type VNCharacterSprite struct {
+	name  string
+	pos   Vec2f
+	image *ebiten.Image
+}
+
+func (sprite *VNCharacterSprite) Draw(screen *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(sprite.pos.X, sprite.pos.Y)
+
+	DrawImage(screen, sprite.image, op)
+}
+
+type VNCharacter struct {
+	IVNCharacter
+	sprites  []*VNSpriteListCategory
+	presets  map[string]JSONExpressionPreset
+	path     string
+	animator *VNAnimator
+
+	width        uint
+	height       uint
+	name         string
+	matTransform ebiten.GeoM
+}
+
+func (char *VNCharacter) Draw(screen *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+
+	for i := 0; i < len(char.sprites); i++ {
+		sprite := char.sprites[i].current
+
+		op.GeoM.Reset()
+		op.GeoM.Translate(sprite.pos.X, sprite.pos.Y)
+		op.GeoM.Concat(char.matTransform)
+		DrawImage(screen, sprite.image, op)
+	}
+}
+
+// Returns the animator of the character, creating an empty one if needed
+func (char *VNCharacter) GetAnimator() *VNAnimator {
+	if char.animator == nil {
+		char.animator = NewVNAnimator(char, JSONVNAnimation{})
+	}
+
+	return char.animator
+}
+
+func (char *VNCharacter) Update() {
+	if char.animator != nil {
+		char.animator.Update()
+	}
+}
+
+func (char *VNCharacter) findGroupSprite(group string, name string) (*VNSpriteListCategory, *VNCharacterSprite) {
+	for _, list := range char.sprites {
+		if list.name != group {
+			continue
+		}
+
+		for _, sprite := range list.sprites {
+			if sprite.name == name {
+				return list, sprite
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// Shows the named layer of a group, e.g. "morshu_002.png" of "facial"
+func (char *VNCharacter) SetGroupSprite(group string, name string) bool {
+	list, sprite := char.findGroupSprite(group, name)
+	if sprite == nil {
+		return false
+	}
+
+	list.current = sprite
+	return true
+}
+
+func (char *VNCharacter) SetExpression(name string) {
+	char.SetGroupSprite("facial", name)
+}
+
+func (char *VNCharacter) SetBody(name string) {
+	char.SetGroupSprite("body", name)
+}
+
+// Shows the layers of a named expression preset
+func (char *VNCharacter) ApplyPreset(name string) bool {
+	preset, has := char.presets[name]
+	if !has {
+		return false
+	}
+
+	applied := true
+	for group, layer := range preset {
+		applied = char.SetGroupSprite(group, layer) && applied
+	}
+
+	return applied
+}
+
+// Returns the expression preset names in alphabetical order
+func (char *VNCharacter) GetPresetNames() []string {
+	var names []string
+	for name := range char.presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Returns the layer shown by every group
+func (char *VNCharacter) CapturePreset() JSONExpressionPreset {
+	preset := make(JSONExpressionPreset)
+	for _, list := range char.sprites {
+		preset[list.name] = list.current.name
+	}
+
+	return preset
+}
+
+func (char *VNCharacter) SetPreset(name string, preset JSONExpressionPreset) {
+	if char.presets == nil {
+		char.presets = make(map[string]JSONExpressionPreset)
+	}
+
+	char.presets[name] = preset
+}
+
+func (char *VNCharacter) RemovePreset(name string) {
+	delete(char.presets, name)
+}
+
+// Writes the presets into the layered image JSON the character was loaded
+// from, keeping everything else in it
+func (char *VNCharacter) SavePresets() error {
+	if char.path == "" {
+		return fmt.Errorf("%s was not loaded from a file", char.name)
+	}
+
+	data, err := ioutil.ReadFile(char.path)
+	if err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	if len(char.presets) > 0 {
+		presets, err := json.Marshal(char.presets)
+		if err != nil {
+			return err
+		}
+		fields["presets"] = presets
+	} else {
+		delete(fields, "presets")
+	}
+
+	data, err = json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(char.path, data, 0644)
+}
+
+// Loads the layers of every group. Groups, layers and presets that can not
+// be shown are left out and reported in the returned error.
+func LoadGroupAssets(char *VNCharacter, j JSONLayeredImage, dir string) error {
+	rm := ResourceManager_GetInstance()
+
+	layers := make(map[string]*JSONLayeredImageEntry)
+	for i := range j.Layers {
+		layers[j.Layers[i].Name] = &j.Layers[i]
+	}
+
+	var problems []string
+	if len(j.Groups) == 0 {
+		problems = append(problems, "no layer groups")
+	}
+
+	for _, group := range j.Groups {
+		list := new(VNSpriteListCategory)
+		list.name = group.Name
+
+		for _, name := range group.Layers {
+			e, has := layers[name]
+			if !has {
+				problems = append(problems, fmt.Sprintf("group %s: missing layer %s", group.Name, name))
+				continue
+			}
+
+			sp := new(VNCharacterSprite)
+			sp.name = e.Name
+			sp.image = rm.LoadImage(filepath.Join(dir, e.Name))
+			sp.pos.X = float64(e.OffsetX)
+			sp.pos.Y = float64(e.OffsetY)
+
+			if sp.image == nil {
+				problems = append(problems, fmt.Sprintf("group %s: can not load layer %s", group.Name, name))
+				continue
+			}
+
+			list.sprites = append(list.sprites, sp)
+		}
+
+		if len(list.sprites) == 0 {
+			problems = append(problems, fmt.Sprintf("group %s has no layers", group.Name))
+			continue
+		}
+
+		list.current = list.sprites[0]
+		char.sprites = append(char.sprites, list)
+	}
+
+	char.presets = j.Presets
+	for _, name := range char.GetPresetNames() {
+		for group, layer := range char.presets[name] {
+			if _, sprite := char.findGroupSprite(group, layer); sprite == nil {
+				problems = append(problems, fmt.Sprintf("preset %s: no layer %s in group %s", name, layer, group))
+			}
+		}
+	}
+
+	char.width = j.Width
+	char.height = j.Height
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// Fields to add to layered images exported before they had them, by path
+const vnMigrationsPath = "assets/vn/migrations.json"
+
+// Adds the fields the migrations file has for a layered image JSON and that
+// the file does not have yet, e.g. its layer groups. Returns whether the
+// file was changed; once it was, there is nothing left to add to it.
+func MigrateLayeredImage(path string, migrationsPath string) (bool, error) {
+	data, err := ioutil.ReadFile(migrationsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var migrations map[string]map[string]json.RawMessage
+	if err = json.Unmarshal(data, &migrations); err != nil {
+		return false, err
+	}
+
+	migration, has := migrations[filepath.ToSlash(path)]
+	if !has {
+		return false, nil
+	}
+
+	// Missing layered images are reported by whoever loads them
+	data, err = ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return false, err
+	}
+
+	changed := false
+	for name, value := range migration {
+		if old, has := fields[name]; has && string(old) != "null" {
+			continue
+		}
+
+		fields[name] = value
+		changed = true
+	}
+
+	if !changed {
+		return false, nil
+	}
+
+	data, err = json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return false, err
+	}
+
+	return true, ioutil.WriteFile(path, data, 0644)
+}
+
+func InitVNCharacter(char *VNCharacter, name string) {
+	char.name = name
+}
+
+func NewVNCharacter(name string) *VNCharacter {
+	char := new(VNCharacter)
+	InitVNCharacter(char, name)
+	return char
+}
+
+// Loads a VN character from its layered image JSON, or returns nil if the
+// file can not be read or has nothing to show
+func NewVNCharacterFromJSON(path string, name string) *VNCharacter {
+	char := new(VNCharacter)
+	InitVNCharacter(char, name)
+
+	assetPath, _ := filepath.Split(path)
+
+	if migrated, err := MigrateLayeredImage(path, vnMigrationsPath); err != nil {
+		log.Println("[VN] Failed to migrate " + path + ": " + err.Error())
+	} else if migrated {
+		log.Println("[VN] Migrated " + path)
+	}
+
+	var layeredImage JSONLayeredImage
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Println("[VN] Failed to load " + path + ": " + err.Error())
+		return nil
+	}
+
+	err = json.Unmarshal(bytes, &layeredImage)
+	if err != nil {
+		log.Println("[VN] Failed to parse " + path + ": " + err.Error())
+		return nil
+	}
+
+	char.path = path
+	if err = LoadGroupAssets(char, layeredImage, assetPath); err != nil {
+		log.Println("[VN] " + path + ": " + err.Error())
+	}
+
+	if len(char.sprites) == 0 {
+		return nil
+	}
+
+	char.ApplyPreset(vnDefaultPreset)
+	char.animator = NewVNAnimator(char, layeredImage.Animation)
+
+	return char
+}
+
+type VNSpriteListCategory struct {
+	name    string
+	sprites []*VNCharacterSprite
+	current *VNCharacterSprite
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Writes a layered image with a blank PNG for every layer name
+func writeTestLayeredImage(t *testing.T, layered JSONLayeredImage, images ...string) string {
+	dir := t.TempDir()
+
+	for _, name := range images {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = png.Encode(f, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := json.Marshal(layered)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "char.json")
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestVNCharacterGroupsAndPresets(t *testing.T) {
+	path := writeTestLayeredImage(t, JSONLayeredImage{
+		Width:  4,
+		Height: 4,
+		Layers: []JSONLayeredImageEntry{{Name: "body.png"}, {Name: "smile.png"}, {Name: "frown.png"}},
+		Groups: []JSONLayerGroup{
+			{Name: "body", Layers: []string{"body.png"}},
+			{Name: "facial", Layers: []string{"smile.png", "frown.png"}},
+		},
+		Presets: map[string]JSONExpressionPreset{
+			vnDefaultPreset: {"facial": "frown.png"},
+			"happy":         {"facial": "smile.png"},
+		},
+	}, "body.png", "smile.png", "frown.png")
+
+	char := NewVNCharacterFromJSON(path, "Test")
+	if char == nil {
+		t.Fatal("character did not load")
+	}
+
+	if len(char.sprites) != 2 || char.sprites[0].name != "body" || char.sprites[1].name != "facial" {
+		t.Fatalf("groups loaded as %v", char.sprites)
+	}
+
+	if char.sprites[1].current.name != "frown.png" {
+		t.Fatalf("default preset not applied, showing %s", char.sprites[1].current.name)
+	}
+
+	if !char.ApplyPreset("happy") || char.sprites[1].current.name != "smile.png" {
+		t.Fatal("happy preset not applied")
+	}
+
+	if char.ApplyPreset("angry") {
+		t.Fatal("applied a preset that does not exist")
+	}
+}
+
+func TestVNCharacterMissingLayers(t *testing.T) {
+	layered := JSONLayeredImage{
+		Layers: []JSONLayeredImageEntry{{Name: "body.png"}, {Name: "lost.png"}},
+		Groups: []JSONLayerGroup{
+			{Name: "body", Layers: []string{"body.png", "arm.png"}},
+			{Name: "facial", Layers: []string{"lost.png"}},
+		},
+		Presets: map[string]JSONExpressionPreset{"sad": {"facial": "lost.png"}},
+	}
+	path := writeTestLayeredImage(t, layered, "body.png")
+
+	char := NewVNCharacter("Test")
+	err := LoadGroupAssets(char, layered, filepath.Dir(path))
+	if err == nil {
+		t.Fatal("missing layers were not reported")
+	}
+
+	for _, missing := range []string{"arm.png", "lost.png", "sad"} {
+		if !strings.Contains(err.Error(), missing) {
+			t.Errorf("%q does not mention %s", err, missing)
+		}
+	}
+
+	if len(char.sprites) != 1 || len(char.sprites[0].sprites) != 1 {
+		t.Fatalf("loaded %d groups, want only the body", len(char.sprites))
+	}
+
+	if NewVNCharacterFromJSON(writeTestLayeredImage(t, JSONLayeredImage{}), "Empty") != nil {
+		t.Fatal("character without layer groups loaded")
+	}
+}
+
+func TestVNCharacterMigration(t *testing.T) {
+	layers := []string{"morshu_090.png", "morshu_001.png", "morshu_002.png"}
+
+	var entries []JSONLayeredImageEntry
+	for _, name := range layers {
+		entries = append(entries, JSONLayeredImageEntry{Name: name})
+	}
+
+	path := writeTestLayeredImage(t, JSONLayeredImage{Width: 4, Height: 4, Layers: entries}, layers...)
+
+	migrations, err := json.Marshal(map[string]JSONLayeredImage{
+		filepath.ToSlash(path): {
+			Groups: []JSONLayerGroup{
+				{Name: "body", Layers: []string{"morshu_090.png"}},
+				{Name: "facial", Layers: []string{"morshu_001.png", "morshu_002.png"}},
+			},
+			Presets: map[string]JSONExpressionPreset{"talk": {"facial": "morshu_002.png"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrationsPath := filepath.Join(t.TempDir(), "migrations.json")
+	if err = os.WriteFile(migrationsPath, migrations, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if migrated, err := MigrateLayeredImage(path, migrationsPath); err != nil || !migrated {
+		t.Fatalf("layered image without groups not migrated: %v", err)
+	}
+
+	if migrated, err := MigrateLayeredImage(path, migrationsPath); err != nil || migrated {
+		t.Fatalf("migrated layered image changed again: %v", err)
+	}
+
+	char := NewVNCharacterFromJSON(path, "Morshu")
+	if char == nil {
+		t.Fatal("migrated layered image did not load")
+	}
+
+	if len(char.sprites) != 2 || len(char.sprites[1].sprites) != 2 {
+		t.Fatalf("loaded %d groups, want body and facial", len(char.sprites))
+	}
+
+	if !char.ApplyPreset("talk") || char.sprites[1].current.name != "morshu_002.png" {
+		t.Fatal("migrated preset not applied")
+	}
+}
+
+func TestVNMigrationsParse(t *testing.T) {
+	data, err := os.ReadFile(vnMigrationsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var migrations map[string]JSONLayeredImage
+	if err = json.Unmarshal(data, &migrations); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, migration := range migrations {
+		if len(migration.Groups) == 0 {
+			t.Errorf("%s: migration adds no layer groups", path)
+		}
+
+		groups := make(map[string][]string)
+		for _, group := range migration.Groups {
+			groups[group.Name] = group.Layers
+		}
+
+		for _, cycle := range []*JSONVNLayerCycle{migration.Animation.Blink, migration.Animation.LipSync} {
+			if cycle == nil {
+				continue
+			}
+
+			for _, layer := range cycle.Layers {
+				found := false
+				for _, name := range groups[cycle.Group] {
+					found = found || name == layer
+				}
+
+				if !found {
+					t.Errorf("%s: animated layer %s not in group %s", path, layer, cycle.Group)
+				}
+			}
+		}
+	}
+
+	morshu := migrations["assets/shop/morshu/morshu.json"].Animation
+	if morshu.Blink == nil || morshu.LipSync == nil {
+		t.Fatal("morshu does not blink or talk")
+	}
+}
+
+func TestVNExpressionEditorSavesPresets(t *testing.T) {
+	path := writeTestLayeredImage(t, JSONLayeredImage{
+		Width:  4,
+		Height: 4,
+		Layers: []JSONLayeredImageEntry{{Name: "body.png"}, {Name: "smile.png"}, {Name: "frown.png"}},
+		Groups: []JSONLayerGroup{
+			{Name: "body", Layers: []string{"body.png"}},
+			{Name: "facial", Layers: []string{"smile.png", "frown.png"}},
+		},
+	}, "body.png", "smile.png", "frown.png")
+
+	ed := &VNExpressionEditor{char: NewVNCharacterFromJSON(path, "Test")}
+	ed.MoveGroup(1)
+	ed.MoveLayer(1)
+	ed.StorePreset("grumpy")
+
+	if ed.GetPreset() != "grumpy" || !strings.Contains(ed.status, "grumpy") {
+		t.Fatalf("selected preset %q, status %q", ed.GetPreset(), ed.status)
+	}
+
+	char := NewVNCharacterFromJSON(path, "Test")
+	if char == nil || len(char.sprites) != 2 {
+		t.Fatal("saving presets broke the layered image")
+	}
+
+	if !char.ApplyPreset("grumpy") || char.sprites[1].current.name != "frown.png" || char.sprites[0].current.name != "body.png" {
+		t.Fatalf("saved presets: %v", char.presets)
+	}
+
+	ed.RemovePreset()
+	if char = NewVNCharacterFromJSON(path, "Test"); len(char.GetPresetNames()) != 0 {
+		t.Fatalf("removed preset is still saved: %v", char.GetPresetNames())
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+	"unicode"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Size of a layer thumbnail in the expression editor
+const vnEditTileSize float64 = 48
+
+// Thumbnails per row of the expression editor panel
+const vnEditColumns = 5
+
+// Editor for the expression presets of a VN character, drawn over the
+// character as a panel on the right
+//
+// Up/Down pick a group and Left/Right its layer, previewing the combination
+// on the character. Page Up/Page Down browse the saved presets, N names the
+// shown combination as a new preset, S overwrites the selected one, Delete
+// removes it and C copies its name. Presets are saved into the character
+// JSON right away. Escape closes the editor.
+type VNExpressionEditor struct {
+	Screen
+	char    *VNCharacter
+	font    *Font
+	group   int
+	preset  int
+	naming  bool
+	name    string
+	status  string
+	onClose func()
+}
+
+func (ed *VNExpressionEditor) GetGroup() *VNSpriteListCategory {
+	return ed.char.sprites[ed.group]
+}
+
+// Returns the selected preset name, "" if there are none
+func (ed *VNExpressionEditor) GetPreset() string {
+	names := ed.char.GetPresetNames()
+	if ed.preset >= len(names) {
+		return ""
+	}
+
+	return names[ed.preset]
+}
+
+func (ed *VNExpressionEditor) MoveGroup(step int) {
+	count := len(ed.char.sprites)
+	ed.group = (ed.group + step%count + count) % count
+}
+
+// Shows another layer of the selected group, wrapping around
+func (ed *VNExpressionEditor) MoveLayer(step int) {
+	list := ed.GetGroup()
+	count := len(list.sprites)
+
+	var index int
+	for i, sprite := range list.sprites {
+		if sprite == list.current {
+			index = i
+		}
+	}
+
+	list.current = list.sprites[(index+step%count+count)%count]
+}
+
+// Selects another preset and shows it
+func (ed *VNExpressionEditor) MovePreset(step int) {
+	count := len(ed.char.GetPresetNames())
+	if count == 0 {
+		return
+	}
+
+	ed.preset = (ed.preset + step%count + count) % count
+	ed.char.ApplyPreset(ed.GetPreset())
+}
+
+func (ed *VNExpressionEditor) selectPreset(name string) {
+	for i, preset := range ed.char.GetPresetNames() {
+		if preset == name {
+			ed.preset = i
+		}
+	}
+}
+
+func (ed *VNExpressionEditor) save(message string) {
+	if err := ed.char.SavePresets(); err != nil {
+		log.Println("[VN] Failed to save presets: " + err.Error())
+		ed.status = err.Error()
+		return
+	}
+
+	ed.status = message
+}
+
+// Saves the shown combination under a preset name
+func (ed *VNExpressionEditor) StorePreset(name string) {
+	if name == "" {
+		return
+	}
+
+	ed.char.SetPreset(name, ed.char.CapturePreset())
+	ed.selectPreset(name)
+	ed.save(fmt.Sprintf(I18n("string_vn_editor_saved", "Saved %s"), name))
+}
+
+func (ed *VNExpressionEditor) RemovePreset() {
+	name := ed.GetPreset()
+	if name == "" {
+		return
+	}
+
+	ed.char.RemovePreset(name)
+	if ed.preset > 0 {
+		ed.preset--
+	}
+
+	ed.save(fmt.Sprintf(I18n("string_vn_editor_removed", "Removed %s"), name))
+}
+
+func (ed *VNExpressionEditor) CopyPreset() {
+	name := ed.GetPreset()
+	if name == "" {
+		return
+	}
+
+	if err := WriteClipboard(name); err != nil {
+		log.Println("[VN] Failed to copy " + name + ": " + err.Error())
+		ed.status = err.Error()
+		return
+	}
+
+	ed.status = fmt.Sprintf(I18n("string_vn_editor_copied", "Copied %s"), name)
+}
+
+// Preset names are used as keys in dialogue scripts, so they are kept simple
+func isPresetNameChar(ch rune) bool {
+	return unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '_' || ch == '-'
+}
+
+func (ed *VNExpressionEditor) processNamingKeys() {
+	for _, ch := range ebiten.InputChars() {
+		if isPresetNameChar(ch) {
+			ed.name += string(ch)
+		}
+	}
+
+	switch {
+	case repeatingKeyPressed(ebiten.KeyBackspace):
+		ed.name = trimLastChar(ed.name)
+
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		ed.naming = false
+		ed.StorePreset(ed.name)
+
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		ed.naming = false
+	}
+}
+
+func (ed *VNExpressionEditor) ProcessKeyEvents() bool {
+	if ed.naming {
+		ed.processNamingKeys()
+		return false
+	}
+
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		ed.onClose()
+
+	case repeatingKeyPressed(ebiten.KeyUp):
+		ed.MoveGroup(-1)
+
+	case repeatingKeyPressed(ebiten.KeyDown):
+		ed.MoveGroup(1)
+
+	case repeatingKeyPressed(ebiten.KeyLeft):
+		ed.MoveLayer(-1)
+
+	case repeatingKeyPressed(ebiten.KeyRight):
+		ed.MoveLayer(1)
+
+	case repeatingKeyPressed(ebiten.KeyPageUp):
+		ed.MovePreset(-1)
+
+	case repeatingKeyPressed(ebiten.KeyPageDown):
+		ed.MovePreset(1)
+
+	case inpututil.IsKeyJustPressed(ebiten.KeyN):
+		ed.naming = true
+		ed.name = ""
+
+	case inpututil.IsKeyJustPressed(ebiten.KeyS):
+		ed.StorePreset(ed.GetPreset())
+
+	case inpututil.IsKeyJustPressed(ebiten.KeyDelete):
+		ed.RemovePreset()
+
+	case inpututil.IsKeyJustPressed(ebiten.KeyC):
+		ed.CopyPreset()
+	}
+
+	return false
+}
+
+func (ed *VNExpressionEditor) drawLayers(screen *ebiten.Image, left, top float64) {
+	list := ed.GetGroup()
+
+	for i, sprite := range list.sprites {
+		op := &ebiten.DrawImageOptions{}
+
+		imgWidth, imgHeight := sprite.image.Size()
+		op.GeoM.Translate(-(float64(imgWidth) / 2), -(float64(imgHeight) / 2))
+
+		scale := vnEditTileSize / math.Max(float64(imgWidth), float64(imgHeight))
+		op.GeoM.Scale(scale, scale)
+
+		x := left + float64(i%vnEditColumns)*vnEditTileSize
+		y := top + float64(i/vnEditColumns)*vnEditTileSize
+		op.GeoM.Translate(x+vnEditTileSize/2, y+vnEditTileSize/2)
+
+		if list.current == sprite {
+			ebitenutil.DrawRect(screen, x, y, vnEditTileSize, vnEditTileSize, color.RGBA{0, 255, 0, 128})
+		}
+
+		DrawImage(screen, sprite.image, op)
+	}
+}
+
+func (ed *VNExpressionEditor) Draw(screen *ebiten.Image) {
+	width := vnEditTileSize * vnEditColumns
+	left := screenWidth - width
+	ed.game.DrawHerbGUIFrame(screen, left, 0, width, screenHeight)
+
+	fontRenderer := ed.game.fontRenderer
+	fontRenderer.PushState()
+
+	fontRenderer.SetFont(ed.font)
+	fontRenderer.SetScale(2.0)
+	fontRenderer.EnableShadow(true)
+
+	lineHeight := fontRenderer.GetGlyphSize().Y + 4
+	pos := Vec2f{left + 8, 8}
+
+	list := ed.GetGroup()
+	fontRenderer.SetTextColor(color.RGBA{255, 224, 64, 255})
+	fontRenderer.DrawTextAt(screen, fmt.Sprintf("< %s >", list.name), pos)
+	pos.Y += lineHeight
+
+	fontRenderer.SetTextColor(color.White)
+	fontRenderer.DrawTextAt(screen, list.current.name, pos)
+	pos.Y += lineHeight
+
+	rows := math.Ceil(float64(len(list.sprites)) / vnEditColumns)
+	ed.drawLayers(screen, left, pos.Y)
+	pos.Y += rows*vnEditTileSize + lineHeight/2
+
+	fontRenderer.SetTextColor(color.RGBA{255, 224, 64, 255})
+	fontRenderer.DrawTextAt(screen, I18n("string_vn_editor_presets", "Presets"), pos)
+	pos.Y += lineHeight
+
+	names := ed.char.GetPresetNames()
+	if len(names) == 0 {
+		fontRenderer.SetTextColor(color.RGBA{192, 192, 192, 255})
+		fontRenderer.DrawTextAt(screen, I18n("string_vn_editor_no_presets", "None yet"), pos)
+	}
+
+	for i, name := range names {
+		if i == ed.preset {
+			fontRenderer.SetTextColor(color.RGBA{0, 255, 0, 255})
+			name = "> " + name
+		} else {
+			fontRenderer.SetTextColor(color.RGBA{192, 192, 192, 255})
+			name = "  " + name
+		}
+
+		fontRenderer.DrawTextAt(screen, name, pos)
+		pos.Y += lineHeight
+	}
+
+	status := ed.status
+	if ed.naming {
+		status = fmt.Sprintf(I18n("string_vn_editor_name", "Name: %s_"), ed.name)
+	}
+
+	fontRenderer.SetTextColor(color.White)
+	fontRenderer.DrawTextAt(screen, status, Vec2f{left + 8, screenHeight - 8 - lineHeight})
+
+	fontRenderer.PopState()
+}
+
+func NewVNExpressionEditor(g *Game, char *VNCharacter, onClose func()) *VNExpressionEditor {
+	ed := new(VNExpressionEditor)
+	ed.IScreen = ed
+	ed.game = g
+	ed.char = char
+	ed.font = ResourceManager_GetInstance().LoadFontJSON("font/font_fantasy.json")
+	ed.onClose = onClose
+	return ed
+}
@@ -13,7 +13,7 @@ func (e *WanderingNPC) Update() {
 
 		switch e.walkerState {
 		case 0:
-			e.StartWalk(LookDirection(r.Int() % 4))
+			e.StartWalk(LookDirection(e.game.rng.Int() % 4))
 		default:
 			e.EndWalk()
 		}
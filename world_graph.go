@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"image/color"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+const defaultWorldGraphPath = "level/world.json"
+
+// Link from a door, elevator or pit tile to a spawn tile in another level
+type JSONLevelLink struct {
+	Level  string `json:"level"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Target string `json:"target"`
+	SpawnX int    `json:"spawn_x"`
+	SpawnY int    `json:"spawn_y"`
+}
+
+type levelLinkKey struct {
+	level string
+	x, y  int
+}
+
+// Connections between levels, stored in a JSON file next to the levels
+type WorldGraph struct {
+	fileName string
+	links    map[levelLinkKey]*JSONLevelLink
+}
+
+func NewWorldGraph(fileName string) *WorldGraph {
+	wg := new(WorldGraph)
+	wg.fileName = fileName
+	wg.links = make(map[levelLinkKey]*JSONLevelLink)
+	return wg
+}
+
+// Loads a world graph; a missing file gives an empty graph saved to that path
+func LoadWorldGraph(path string) (*WorldGraph, error) {
+	wg := NewWorldGraph(path)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return wg, nil
+	} else if err != nil {
+		return wg, err
+	}
+
+	var jsonGraph struct {
+		Links []JSONLevelLink `json:"links"`
+	}
+
+	if err := json.Unmarshal(data, &jsonGraph); err != nil {
+		return wg, err
+	}
+
+	for _, link := range jsonGraph.Links {
+		wg.SetLink(link)
+	}
+
+	return wg, nil
+}
+
+func (wg *WorldGraph) Save() error {
+	var jsonGraph struct {
+		Links []JSONLevelLink `json:"links"`
+	}
+
+	jsonGraph.Links = wg.GetLinks()
+
+	data, err := json.MarshalIndent(jsonGraph, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(wg.fileName, data, 0644)
+}
+
+// Returns the link placed on the tile, or nil
+func (wg *WorldGraph) GetLink(level string, x, y int) *JSONLevelLink {
+	if wg == nil {
+		return nil
+	}
+
+	return wg.links[levelLinkKey{level, x, y}]
+}
+
+func (wg *WorldGraph) SetLink(link JSONLevelLink) {
+	wg.links[levelLinkKey{link.Level, link.X, link.Y}] = &link
+}
+
+func (wg *WorldGraph) RemoveLink(level string, x, y int) {
+	delete(wg.links, levelLinkKey{level, x, y})
+}
+
+// Returns all links ordered by level and position
+func (wg *WorldGraph) GetLinks() []JSONLevelLink {
+	var links []JSONLevelLink
+	for _, link := range wg.links {
+		links = append(links, *link)
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		a, b := links[i], links[j]
+		if a.Level != b.Level {
+			return a.Level < b.Level
+		}
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		return a.X < b.X
+	})
+
+	return links
+}
+
+func IsLinkTile(tile Tile) bool {
+	return tile == tileIDDoor || tile == tileIDElevator || tile == tileIDPit
+}
+
+// Returns the link of a door, elevator or pit at the tile position, or nil.
+// A wired door or elevator without power is closed.
+func (g *Game) GetLevelLinkAt(tilePos int) *JSONLevelLink {
+	if g.worldGraph == nil || !g.IsReceiverPowered(tilePos) {
+		return nil
+	}
+
+	link := g.worldGraph.GetLink(g.level.fileName, tilePos%g.level.width, tilePos/g.level.width)
+	if link == nil {
+		return nil
+	}
+
+	for i := 0; i < g.level.GetLayerCount(); i++ {
+		if IsLinkTile(g.level.GetTile(i, tilePos)) {
+			return link
+		}
+	}
+
+	return nil
+}
+
+// Level with the entities that were in it when the player left
+type LevelState struct {
+	level    *Level
+	entities []ILivingEntity
+}
+
+// Moves the player to a spawn tile of another level
+//
+// The current level and its entities are kept in memory, so coming back
+// finds them as they were left.
+func (g *Game) TravelTo(target string, spawnX, spawnY int) error {
+	if g.levelStates == nil {
+		g.levelStates = make(map[string]*LevelState)
+	}
+
+	state, visited := g.levelStates[target]
+	if target == g.level.fileName {
+		state = &LevelState{level: g.level}
+		for _, entity := range g.entities {
+			if entity != g.char {
+				state.entities = append(state.entities, entity)
+			}
+		}
+	} else if !visited {
+		level, err := ReadLevel(target)
+		if err != nil {
+			return err
+		}
+
+		state = &LevelState{level: level, entities: g.CreateLevelEntities(level)}
+	}
+
+	if !state.level.ContainsTile(spawnX, spawnY) {
+		return errors.New("spawn point is outside the target level")
+	}
+
+	g.entityListMutex.Lock()
+	current := &LevelState{level: g.level}
+	for _, entity := range g.entities {
+		if entity != g.char {
+			current.entities = append(current.entities, entity)
+		}
+	}
+	g.levelStates[g.level.fileName] = current
+
+	g.level = state.level
+	g.entities = append([]ILivingEntity{}, state.entities...)
+	if g.char != nil {
+		g.entities = append(g.entities, g.char)
+	}
+	g.entityListMutex.Unlock()
+
+	delete(g.levelStates, target)
+
+	if g.char != nil {
+		e := g.char.GetLivingEntity()
+		e.worldPos = Vec2f{float64(spawnX*tileSize + tileSize/2), float64(spawnY*tileSize + tileSize/2)}
+		e.walking = false
+		e.prevTilePos, _ = e.GetTilePos()
+
+		g.camera.currentWorldPos = e.worldPos
+	}
+
+	g.StreamChunks()
+	g.ResetSokoban()
+
+	log.Println("[World] Entered level " + target)
+	return nil
+}
+
+const levelTransitionTicks = 30
+
+// Fade out, level change and fade in after the player used a link tile
+type LevelTransition struct {
+	link     JSONLevelLink
+	tile     Tile
+	tick     int
+	switched bool
+}
+
+func (g *Game) StartLevelTransition(link *JSONLevelLink, tile Tile) {
+	if g.transition == nil {
+		g.transition = &LevelTransition{link: *link, tile: tile}
+	}
+}
+
+func (g *Game) IsInTransition() bool {
+	return g.transition != nil
+}
+
+func (g *Game) UpdateTransition() {
+	t := g.transition
+	t.tick++
+
+	if t.tick == levelTransitionTicks && !t.switched {
+		t.switched = true
+
+		if err := g.TravelTo(t.link.Target, t.link.SpawnX, t.link.SpawnY); err != nil {
+			log.Println("[World] Failed to travel to " + t.link.Target + ": " + err.Error())
+		}
+	}
+
+	if t.tick >= 2*levelTransitionTicks {
+		g.transition = nil
+	}
+}
+
+// Darkens the screen; a pit fades through red instead of black
+func (t *LevelTransition) Draw(screen *ebiten.Image) {
+	progress := float64(t.tick) / levelTransitionTicks
+	if progress > 1 {
+		progress = 2 - progress
+	}
+
+	c := color.RGBA{0, 0, 0, uint8(255 * progress)}
+	if t.tile == tileIDPit {
+		c.R = uint8(64 * progress)
+	}
+
+	ebitenutil.DrawRect(screen, 0, 0, screenWidth, screenHeight, c)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func writeTestLevel(t *testing.T, path string, level *Level) {
+	t.Helper()
+
+	g := &Game{level: level}
+	if err := g.SaveLevel(path); err != nil {
+		t.Fatalf("SaveLevel: %v", err)
+	}
+}
+
+func TestWorldGraphSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "world.json")
+
+	wg := NewWorldGraph(path)
+	wg.SetLink(JSONLevelLink{Level: "a.lvl", X: 3, Y: 4, Target: "b.lvl", SpawnX: 1, SpawnY: 2})
+	wg.SetLink(JSONLevelLink{Level: "b.lvl", X: 1, Y: 1, Target: "a.lvl", SpawnX: 3, SpawnY: 5})
+	wg.RemoveLink("b.lvl", 1, 1)
+
+	if err := wg.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := LoadWorldGraph(path)
+	if err != nil {
+		t.Fatalf("LoadWorldGraph: %v", err)
+	}
+
+	link := loaded.GetLink("a.lvl", 3, 4)
+	if len(loaded.GetLinks()) != 1 || link == nil || link.Target != "b.lvl" || link.SpawnY != 2 {
+		t.Fatalf("loaded links = %v", loaded.GetLinks())
+	}
+}
+
+func TestDoorTransitionKeepsLevelState(t *testing.T) {
+	dir := t.TempDir()
+	pathA := filepath.Join(dir, "a.lvl")
+	pathB := filepath.Join(dir, "b.lvl")
+
+	levelA := newTestLevel()
+	levelA.fileName = pathA
+	levelA.SetTile(testObjectLayer, tileIndex(levelA, 4, 2), tileIDDoor)
+	writeTestLevel(t, pathB, newTestLevel())
+
+	sim, input := newTestSimulation(t, levelA)
+	g := sim.GetGame()
+	g.worldGraph.SetLink(JSONLevelLink{Level: pathA, X: 4, Y: 2, Target: pathB, SpawnX: 7, SpawnY: 7})
+
+	sim.Spawn(g.char, 2, 2)
+	npc := CreateMichael(g)
+	sim.Spawn(npc, 10, 10)
+	npc.GetLivingEntity().health = 30
+
+	input.Hold(0, 30, kbPlayerMoveRight)
+	sim.Step(30)
+
+	if !g.IsInTransition() || g.level != levelA {
+		t.Fatal("stepping on the linked door did not start a transition")
+	}
+
+	sim.Step(2 * levelTransitionTicks)
+
+	if g.IsInTransition() || g.level.fileName != pathB || len(g.entities) != 1 {
+		t.Fatalf("player is in %q with %d entities after the transition", g.level.fileName, len(g.entities))
+	}
+
+	if tilePos, _ := g.char.GetTilePos(); tilePos != tileIndex(g.level, 7, 7) {
+		t.Fatalf("player tile = %d, want the spawn point", tilePos)
+	}
+
+	if err := g.TravelTo(pathA, 1, 1); err != nil {
+		t.Fatalf("TravelTo: %v", err)
+	}
+
+	if g.level != levelA || len(g.entities) != 2 || g.entities[0] != npc || npc.GetHealth() != 30 {
+		t.Fatal("returning did not restore the level and its entities")
+	}
+}
+
+func TestUnlinkedDoorStaysSolid(t *testing.T) {
+	level := newTestLevel()
+	level.SetTile(testObjectLayer, tileIndex(level, 4, 2), tileIDDoor)
+
+	sim, _ := newTestSimulation(t, level)
+	g := sim.GetGame()
+
+	if !g.IsTileSolidAt(4*tileSize+1, 2*tileSize+1) {
+		t.Fatal("a door without a destination is walkable")
+	}
+
+	g.worldGraph.SetLink(JSONLevelLink{Level: level.fileName, X: 4, Y: 2, Target: "elsewhere.lvl"})
+
+	if g.IsTileSolidAt(4*tileSize+1, 2*tileSize+1) {
+		t.Fatal("a linked door blocks the player")
+	}
+}
@@ -28,7 +28,7 @@ func (s *XPBootScreen) Draw(screen *ebiten.Image) {
 	op.GeoM.Translate(-(float64(imgWidth) / 2), -(float64(imgHeight) / 2))
 	op.GeoM.Translate(float64(screenWidth)/2, float64(screenHeight)/2)
 
-	screen.DrawImage(img, op)
+	DrawImage(screen, img, op)
 }
 
 func NewXPBootScreen(g *Game, parentManager *ScreenManager) *XPBootScreen {
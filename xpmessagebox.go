@@ -27,7 +27,7 @@ func (wnd *XPMessageBox) Draw(screen *ebiten.Image) {
 
 	op := &ebiten.DrawImageOptions{}
 	op.GeoM.Translate(wnd.posX+4+12, wnd.posY+4+12+30)
-	screen.DrawImage(xpIconError, op)
+	DrawImage(screen, xpIconError, op)
 
 	XPDrawButton(wnd.game, screen, "Кратко", 4, wnd.posX+4+11, wnd.posY+90, 75, 23)
 	XPDrawButton(wnd.game, screen, "ДА", 0, wnd.posX+96, wnd.posY+90, 75, 23)
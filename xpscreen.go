@@ -19,6 +19,9 @@ var xpCloseGlyph *ebiten.Image
 var xpIconError *ebiten.Image
 var xpButton *ebiten.Image
 
+// Time shown by the taskbar clock
+var xpClockNow = time.Now
+
 type WinXPScreen struct {
 	Screen
 	windows      []IXPWindow
@@ -129,7 +132,7 @@ func (s *WinXPScreen) LoadResources() {
 func (s *WinXPScreen) Draw(screen *ebiten.Image) {
 	op := &ebiten.DrawImageOptions{}
 	op.GeoM.Scale(float64(screenWidth)/800, float64(screenHeight)/600)
-	screen.DrawImage(s.wallpaperImage, op)
+	DrawImage(screen, s.wallpaperImage, op)
 
 	for _, window := range s.windows {
 		window.Draw(screen)
@@ -153,7 +156,7 @@ func (s *WinXPScreen) Draw(screen *ebiten.Image) {
 
 	fontRenderer.PopState()
 
-	hour, min, _ := time.Now().Clock()
+	hour, min, _ := xpClockNow().Clock()
 	time := fmt.Sprintf("%d:%d", hour, min)
 	strDim2 := fontRenderer.GetStringDimensions(time)
 	fontRenderer.DrawTextAt(screen, time, Vec2f{float64(screenWidth - 64), float64(screenHeight - (30+strDim2.Y)/2)})
@@ -203,5 +206,5 @@ func (g *Game) DrawWinXPCloseButton(screen *ebiten.Image, inactive bool, state i
 
 	op := &ebiten.DrawImageOptions{}
 	op.GeoM.Translate(x+4, y+4)
-	screen.DrawImage(xpCloseGlyph.SubImage(image.Rect(0, 0, 13, 13)).(*ebiten.Image), op)
+	DrawImage(screen, xpCloseGlyph.SubImage(image.Rect(0, 0, 13, 13)).(*ebiten.Image), op)
 }